@@ -4,6 +4,8 @@ import (
 	"admin-service/models"
 	"admin-service/utils"
 	"encoding/json"
+	"fmt"
+	"regexp"
 
 	"github.com/beego/beego/v2/server/web"
 )
@@ -250,13 +252,14 @@ func (c *ApplicationController) UpdateApplication() {
 
 	// 解析JSON请求体
 	var request struct {
-		AppId         string `json:"appId"` // 支持appId参数
-		AppName       string `json:"appName"`
-		Platform      string `json:"platform"`
-		ChannelAppId  string `json:"channelAppId"`  // 添加渠道相关字段
-		ChannelAppKey string `json:"channelAppKey"` // 添加渠道相关字段
-		Description   string `json:"description"`
-		Status        string `json:"status"` // 状态
+		AppId             string `json:"appId"` // 支持appId参数
+		AppName           string `json:"appName"`
+		Platform          string `json:"platform"`
+		ChannelAppId      string `json:"channelAppId"`  // 添加渠道相关字段
+		ChannelAppKey     string `json:"channelAppKey"` // 添加渠道相关字段
+		Description       string `json:"description"`
+		Status            string `json:"status"`            // 状态
+		SingleDeviceLogin *bool  `json:"singleDeviceLogin"` // 是否限制玩家只能在一台设备登录（不传则不修改）
 	}
 
 	// 解析JSON请求体
@@ -309,6 +312,10 @@ func (c *ApplicationController) UpdateApplication() {
 		application.Status = request.Status
 		fieldsToUpdate = append(fieldsToUpdate, "status")
 	}
+	if request.SingleDeviceLogin != nil {
+		application.SingleDeviceLogin = *request.SingleDeviceLogin
+		fieldsToUpdate = append(fieldsToUpdate, "single_device_login")
+	}
 
 	// 执行更新
 	err = application.Update(fieldsToUpdate...)
@@ -323,6 +330,145 @@ func (c *ApplicationController) UpdateApplication() {
 	utils.SuccessResponse(&c.Controller, "更新成功", nil)
 }
 
+// GetDataSchema 获取应用的玩家存档校验规则
+func (c *ApplicationController) GetDataSchema() {
+	claims := utils.ValidateJWT(c.Ctx)
+	if claims == nil {
+		return
+	}
+
+	var request struct {
+		AppId string `json:"appId"`
+	}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &request); err != nil {
+		utils.ErrorResponse(&c.Controller, 4001, "参数解析失败: "+err.Error(), nil)
+		return
+	}
+	if request.AppId == "" {
+		utils.ErrorResponse(&c.Controller, 1002, "应用ID不能为空", nil)
+		return
+	}
+
+	application := &models.Application{}
+	if err := application.GetByAppId(request.AppId); err != nil {
+		utils.ErrorResponse(&c.Controller, 1003, "应用不存在: "+err.Error(), nil)
+		return
+	}
+
+	var schema interface{}
+	if application.DataSchema != "" {
+		schema = json.RawMessage(application.DataSchema)
+	}
+
+	utils.SuccessResponse(&c.Controller, "获取成功", map[string]interface{}{
+		"appId":    application.AppId,
+		"schema":   schema,
+		"maxSize":  application.DataMaxSize,
+		"maxDepth": application.DataMaxDepth,
+	})
+}
+
+// SetDataSchema 设置应用的玩家存档校验规则，schema为空表示不校验结构，maxSize/maxDepth为0表示使用默认值
+func (c *ApplicationController) SetDataSchema() {
+	claims := utils.ValidateJWT(c.Ctx)
+	if claims == nil {
+		return
+	}
+
+	var request struct {
+		AppId    string          `json:"appId"`
+		Schema   json.RawMessage `json:"schema"`
+		MaxSize  int             `json:"maxSize"`
+		MaxDepth int             `json:"maxDepth"`
+	}
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &request); err != nil {
+		utils.ErrorResponse(&c.Controller, 4001, "参数解析失败: "+err.Error(), nil)
+		return
+	}
+	if request.AppId == "" {
+		utils.ErrorResponse(&c.Controller, 1002, "应用ID不能为空", nil)
+		return
+	}
+	if request.MaxSize < 0 || request.MaxDepth < 0 {
+		utils.ErrorResponse(&c.Controller, 1002, "maxSize和maxDepth不能为负数", nil)
+		return
+	}
+
+	// schema允许传JSON对象或JSON字符串，null或空字符串表示清除
+	schemaText := ""
+	if len(request.Schema) > 0 && string(request.Schema) != "null" {
+		raw := []byte(request.Schema)
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil {
+			raw = []byte(text)
+		}
+		if len(raw) > 0 {
+			var schema map[string]interface{}
+			if err := json.Unmarshal(raw, &schema); err != nil || schema == nil {
+				utils.ErrorResponse(&c.Controller, 1002, "schema必须是JSON对象", nil)
+				return
+			}
+			if err := checkSchemaPatterns(schema, "schema"); err != nil {
+				utils.ErrorResponse(&c.Controller, 1002, err.Error(), nil)
+				return
+			}
+			schemaText = string(raw)
+		}
+	}
+
+	application := &models.Application{}
+	if err := application.GetByAppId(request.AppId); err != nil {
+		utils.ErrorResponse(&c.Controller, 1003, "应用不存在: "+err.Error(), nil)
+		return
+	}
+
+	application.DataSchema = schemaText
+	application.DataMaxSize = request.MaxSize
+	application.DataMaxDepth = request.MaxDepth
+	if err := application.Update("data_schema", "data_max_size", "data_max_depth"); err != nil {
+		utils.ErrorResponse(&c.Controller, 1003, "更新存档校验规则失败: "+err.Error(), nil)
+		return
+	}
+
+	utils.LogOperation(claims.UserID, "更新存档校验规则", "更新存档校验规则: "+request.AppId)
+
+	utils.SuccessResponse(&c.Controller, "更新成功", nil)
+}
+
+// checkSchemaPatterns 检查schema及游戏服会校验的子模式（properties、items、additionalProperties）中的pattern是否为有效正则
+func checkSchemaPatterns(schema map[string]interface{}, path string) error {
+	if pattern, ok := schema["pattern"]; ok {
+		text, isString := pattern.(string)
+		if !isString {
+			return fmt.Errorf("%s.pattern必须是字符串", path)
+		}
+		if _, err := regexp.Compile(text); err != nil {
+			return fmt.Errorf("%s.pattern不是有效的正则表达式: %v", path, err)
+		}
+	}
+
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		for key, child := range properties {
+			if childSchema, ok := child.(map[string]interface{}); ok {
+				if err := checkSchemaPatterns(childSchema, path+".properties."+key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		if err := checkSchemaPatterns(items, path+".items"); err != nil {
+			return err
+		}
+	}
+	if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+		if err := checkSchemaPatterns(additional, path+".additionalProperties"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // DeleteApplication 删除应用（对齐云函数deleteApp接口）
 func (c *ApplicationController) DeleteApplication() {
 	// JWT验证并获取用户信息
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
 	"github.com/beego/beego/v2/server/web"
 )
 
@@ -18,11 +19,14 @@ type CounterController struct {
 
 // CreateCounterRequest 创建计数器请求结构
 type CreateCounterRequest struct {
-	AppId       string `json:"appId"`
-	Key         string `json:"key"`
-	ResetType   string `json:"resetType"`
-	ResetValue  int    `json:"resetValue"`
-	Description string `json:"description"`
+	AppId       string                    `json:"appId"`
+	Key         string                    `json:"key"`
+	ResetType   string                    `json:"resetType"`
+	ResetValue  int                       `json:"resetValue"`
+	Description string                    `json:"description"`
+	Scope       string                    `json:"scope"`      // global/player，默认global
+	MaxValue    int64                     `json:"maxValue"`   // 上限，0表示不限
+	Thresholds  []models.CounterThreshold `json:"thresholds"` // 阈值触发配置，仅全局计数器支持
 }
 
 // CreateCounter 创建计数器（对齐云函数createCounter接口）
@@ -87,6 +91,46 @@ func (c *CounterController) CreateCounter() {
 		return
 	}
 
+	// 验证作用域和上限
+	if req.Scope == "" {
+		req.Scope = models.CounterScopeGlobal
+	}
+	if req.Scope != models.CounterScopeGlobal && req.Scope != models.CounterScopePlayer {
+		c.Data["json"] = map[string]interface{}{
+			"code":      4001,
+			"msg":       "无效的计数器作用域",
+			"timestamp": utils.UnixMilli(),
+			"data":      nil,
+		}
+		c.ServeJSON()
+		return
+	}
+	if req.MaxValue < 0 {
+		c.Data["json"] = map[string]interface{}{
+			"code":      4001,
+			"msg":       "参数[maxValue]错误",
+			"timestamp": utils.UnixMilli(),
+			"data":      nil,
+		}
+		c.ServeJSON()
+		return
+	}
+
+	thresholds, err := models.EncodeCounterThresholds(req.Thresholds)
+	if err == nil && thresholds != "" && req.Scope != models.CounterScopeGlobal {
+		err = fmt.Errorf("只有全局计数器支持阈值触发")
+	}
+	if err != nil {
+		c.Data["json"] = map[string]interface{}{
+			"code":      4001,
+			"msg":       "阈值配置错误: " + err.Error(),
+			"timestamp": utils.UnixMilli(),
+			"data":      nil,
+		}
+		c.ServeJSON()
+		return
+	}
+
 	// 创建计数器配置
 	counter := &models.CounterConfig{
 		AppId:       req.AppId,
@@ -95,6 +139,9 @@ func (c *CounterController) CreateCounter() {
 		ResetValue:  req.ResetValue,
 		Description: req.Description,
 		IsActive:    true,
+		Scope:       req.Scope,
+		MaxValue:    req.MaxValue,
+		Thresholds:  thresholds,
 	}
 
 	if err := models.CreateCounterConfig(counter); err != nil {
@@ -120,6 +167,8 @@ func (c *CounterController) CreateCounter() {
 			"resetType":   req.ResetType,
 			"resetValue":  req.ResetValue,
 			"description": req.Description,
+			"scope":       req.Scope,
+			"maxValue":    req.MaxValue,
 			"createdAt":   counter.CreatedAt,
 		},
 	}
@@ -202,8 +251,14 @@ func (c *CounterController) GetCounter() {
 		if now.After(counterConfig.NextResetTime) {
 			shouldReset = true
 
-			// 重新计算下次重置时间
+			// 重新计算下次重置时间（自定义周期从上次重置时间顺延，与游戏服的周期边界保持一致）
 			nextResetTime := calculateNextResetTime(counterConfig.ResetType, counterConfig.ResetValue)
+			if counterConfig.ResetType == "custom" && counterConfig.ResetValue > 0 {
+				nextResetTime = counterConfig.NextResetTime
+				for !nextResetTime.After(now) {
+					nextResetTime = nextResetTime.Add(time.Duration(counterConfig.ResetValue) * time.Hour)
+				}
+			}
 			if !nextResetTime.IsZero() {
 				// 更新配置中的重置时间
 				err = models.UpdateCounterConfig(req.AppId, req.Key, map[string]interface{}{
@@ -214,6 +269,11 @@ func (c *CounterController) GetCounter() {
 					timeToReset = nextResetTime.Sub(now).Milliseconds()
 				}
 
+				// 重置前保存各点位的值
+				if err := models.ArchiveCounterValues(req.AppId, req.Key); err != nil {
+					logs.Warning("保存计数器重置前的值失败: appId=%s, key=%s, err=%v", req.AppId, req.Key, err)
+				}
+
 				// 重置所有点位的值
 				for locationKey := range locations {
 					models.UpdateCounterValue(req.AppId, req.Key, locationKey, 0)
@@ -454,6 +514,8 @@ type UpdateCounterRequest struct {
 	Description string                            `json:"description"`
 	Value       int64                             `json:"value"`
 	Locations   map[string]map[string]interface{} `json:"locations"`
+	MaxValue    *int64                            `json:"maxValue"`   // 不传则不修改，作用域创建后不能修改
+	Thresholds  *[]models.CounterThreshold        `json:"thresholds"` // 不传则不修改，传空数组清除
 }
 
 // UpdateCounter 更新计数器配置（对齐云函数updateCounter接口）
@@ -493,12 +555,46 @@ func (c *CounterController) UpdateCounter() {
 		return
 	}
 
-	// 更新计数器配置
-	err := models.UpdateCounterConfig(req.AppId, req.Key, map[string]interface{}{
+	fields := map[string]interface{}{
 		"reset_type":  req.ResetType,
 		"reset_value": req.ResetValue,
 		"description": req.Description,
-	})
+	}
+	if req.MaxValue != nil {
+		if *req.MaxValue < 0 {
+			c.Data["json"] = map[string]interface{}{
+				"code":      4001,
+				"msg":       "参数[maxValue]错误",
+				"timestamp": utils.UnixMilli(),
+				"data":      nil,
+			}
+			c.ServeJSON()
+			return
+		}
+		fields["max_value"] = *req.MaxValue
+	}
+	if req.Thresholds != nil {
+		thresholds, err := models.EncodeCounterThresholds(*req.Thresholds)
+		if err == nil && thresholds != "" {
+			if counterConfig, getErr := models.GetCounterConfig(req.AppId, req.Key); getErr == nil && counterConfig.Scope == models.CounterScopePlayer {
+				err = fmt.Errorf("只有全局计数器支持阈值触发")
+			}
+		}
+		if err != nil {
+			c.Data["json"] = map[string]interface{}{
+				"code":      4001,
+				"msg":       "阈值配置错误: " + err.Error(),
+				"timestamp": utils.UnixMilli(),
+				"data":      nil,
+			}
+			c.ServeJSON()
+			return
+		}
+		fields["thresholds"] = thresholds
+	}
+
+	// 更新计数器配置
+	err := models.UpdateCounterConfig(req.AppId, req.Key, fields)
 	if err != nil {
 		c.Data["json"] = map[string]interface{}{
 			"code":      5001,
@@ -975,3 +1071,77 @@ func calculateNextResetTime(resetType string, resetValue int) time.Time {
 		return time.Time{} // permanent类型不设置重置时间
 	}
 }
+
+// GetCounterHistoryRequest 获取计数器历史请求
+type GetCounterHistoryRequest struct {
+	AppId     string `json:"appId"`
+	Key       string `json:"key"`
+	Location  string `json:"location"`  // 可选，不传返回所有点位
+	StartTime string `json:"startTime"` // 可选，默认7天前，格式2006-01-02 15:04:05
+	EndTime   string `json:"endTime"`   // 可选，默认当前时间
+	Interval  string `json:"interval"`  // hour（默认，每次采样）/day（每天最后一次采样）
+}
+
+// GetCounterHistory 获取计数器历史数据（用于绘制趋势图）
+func (c *CounterController) GetCounterHistory() {
+	// JWT验证
+	if utils.ValidateJWT(c.Ctx) == nil {
+		return
+	}
+
+	var req GetCounterHistoryRequest
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		utils.ErrorResponse(&c.Controller, 1001, "参数解析失败: "+err.Error(), nil)
+		return
+	}
+
+	if req.AppId == "" || req.Key == "" {
+		utils.ErrorResponse(&c.Controller, 1002, "应用ID和计数器key不能为空", nil)
+		return
+	}
+	if req.Interval == "" {
+		req.Interval = "hour"
+	}
+	if req.Interval != "hour" && req.Interval != "day" {
+		utils.ErrorResponse(&c.Controller, 1002, "interval只能是hour或day", nil)
+		return
+	}
+
+	endTime := time.Now()
+	startTime := endTime.AddDate(0, 0, -7)
+	if req.StartTime != "" {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04:05", req.StartTime, time.Local)
+		if err != nil {
+			utils.ErrorResponse(&c.Controller, 1002, "startTime格式错误", nil)
+			return
+		}
+		startTime = parsed
+	}
+	if req.EndTime != "" {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04:05", req.EndTime, time.Local)
+		if err != nil {
+			utils.ErrorResponse(&c.Controller, 1002, "endTime格式错误", nil)
+			return
+		}
+		endTime = parsed
+	}
+	if endTime.Before(startTime) {
+		utils.ErrorResponse(&c.Controller, 1002, "endTime不能早于startTime", nil)
+		return
+	}
+
+	series, resets, err := models.GetCounterHistory(req.AppId, req.Key, req.Location, startTime, endTime, req.Interval)
+	if err != nil {
+		utils.ErrorResponse(&c.Controller, 1003, "获取计数器历史失败: "+err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(&c.Controller, "获取成功", map[string]interface{}{
+		"key":       req.Key,
+		"interval":  req.Interval,
+		"startTime": startTime.Format("2006-01-02 15:04:05"),
+		"endTime":   endTime.Format("2006-01-02 15:04:05"),
+		"series":    series,
+		"resets":    resets,
+	})
+}
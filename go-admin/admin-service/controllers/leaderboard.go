@@ -79,18 +79,21 @@ func (c *LeaderboardController) GetAllLeaderboards() {
 // CreateLeaderboard 创建排行榜（对齐云函数createLeaderboard接口）
 func (c *LeaderboardController) CreateLeaderboard() {
 	var req struct {
-		AppId           string `json:"appId"`
-		LeaderboardType string `json:"leaderboardType"`
-		Name            string `json:"name"`
-		Description     string `json:"description"`
-		ScoreType       string `json:"scoreType"`
-		MaxRank         int    `json:"maxRank"`
-		Category        string `json:"category"`
-		ResetType       string `json:"resetType"`
-		ResetValue      int    `json:"resetValue"`
-		Enabled         bool   `json:"enabled"`
-		UpdateStrategy  int    `json:"updateStrategy"`
-		Sort            int    `json:"sort"`
+		AppId           string                             `json:"appId"`
+		LeaderboardType string                             `json:"leaderboardType"`
+		Name            string                             `json:"name"`
+		Description     string                             `json:"description"`
+		ScoreType       string                             `json:"scoreType"`
+		MaxRank         int                                `json:"maxRank"`
+		Category        string                             `json:"category"`
+		ResetType       string                             `json:"resetType"`
+		ResetValue      int                                `json:"resetValue"`
+		Enabled         bool                               `json:"enabled"`
+		UpdateStrategy  int                                `json:"updateStrategy"`
+		Sort            int                                `json:"sort"`
+		RewardTiers     []models.LeaderboardRewardTier     `json:"rewardTiers"`
+		ValidationRules *models.LeaderboardValidationRules `json:"validationRules"`
+		LeagueConfig    *models.LeaderboardLeagueConfig    `json:"leagueConfig"`
 	}
 
 	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
@@ -116,6 +119,52 @@ func (c *LeaderboardController) CreateLeaderboard() {
 		return
 	}
 
+	rewardTiers, err := models.EncodeRewardTiers(req.RewardTiers)
+	if err != nil {
+		c.Data["json"] = map[string]interface{}{
+			"code":      4001,
+			"msg":       err.Error(),
+			"timestamp": utils.UnixMilli(),
+			"data":      nil,
+		}
+		c.ServeJSON()
+		return
+	}
+
+	validationRules, err := models.EncodeValidationRules(req.ValidationRules)
+	if err != nil {
+		c.Data["json"] = map[string]interface{}{
+			"code":      4001,
+			"msg":       err.Error(),
+			"timestamp": utils.UnixMilli(),
+			"data":      nil,
+		}
+		c.ServeJSON()
+		return
+	}
+
+	leagueConfig, err := models.EncodeLeagueConfig(req.LeagueConfig)
+	if err != nil {
+		c.Data["json"] = map[string]interface{}{
+			"code":      4001,
+			"msg":       err.Error(),
+			"timestamp": utils.UnixMilli(),
+			"data":      nil,
+		}
+		c.ServeJSON()
+		return
+	}
+	if leagueConfig != "" && (req.ResetType == "" || req.ResetType == "permanent") {
+		c.Data["json"] = map[string]interface{}{
+			"code":      4001,
+			"msg":       "联赛模式需要设置赛季重置周期",
+			"timestamp": utils.UnixMilli(),
+			"data":      nil,
+		}
+		c.ServeJSON()
+		return
+	}
+
 	leaderboard := &models.LeaderboardConfig{
 		AppId:           req.AppId,
 		LeaderboardType: req.LeaderboardType,
@@ -129,6 +178,9 @@ func (c *LeaderboardController) CreateLeaderboard() {
 		Enabled:         req.Enabled,
 		UpdateStrategy:  req.UpdateStrategy,
 		Sort:            req.Sort,
+		RewardTiers:     rewardTiers,
+		ValidationRules: validationRules,
+		LeagueConfig:    leagueConfig,
 	}
 
 	if err := models.CreateLeaderboardConfig(leaderboard); err != nil {
@@ -170,18 +222,21 @@ func (c *LeaderboardController) CreateLeaderboard() {
 // UpdateLeaderboard 更新排行榜配置（对齐云函数updateLeaderboard接口）
 func (c *LeaderboardController) UpdateLeaderboard() {
 	var req struct {
-		AppId           string `json:"appId"`
-		LeaderboardType string `json:"leaderboardType"`
-		Name            string `json:"name"`
-		Description     string `json:"description"`
-		ScoreType       string `json:"scoreType"`
-		MaxRank         int    `json:"maxRank"`
-		Category        string `json:"category"`
-		ResetType       string `json:"resetType"`
-		ResetValue      int    `json:"resetValue"`
-		Enabled         bool   `json:"enabled"`
-		UpdateStrategy  int    `json:"updateStrategy"`
-		Sort            int    `json:"sort"`
+		AppId           string                             `json:"appId"`
+		LeaderboardType string                             `json:"leaderboardType"`
+		Name            string                             `json:"name"`
+		Description     string                             `json:"description"`
+		ScoreType       string                             `json:"scoreType"`
+		MaxRank         int                                `json:"maxRank"`
+		Category        string                             `json:"category"`
+		ResetType       string                             `json:"resetType"`
+		ResetValue      int                                `json:"resetValue"`
+		Enabled         bool                               `json:"enabled"`
+		UpdateStrategy  int                                `json:"updateStrategy"`
+		Sort            int                                `json:"sort"`
+		RewardTiers     *[]models.LeaderboardRewardTier    `json:"rewardTiers"`     // 不传表示不修改，传空数组表示清空
+		ValidationRules *models.LeaderboardValidationRules `json:"validationRules"` // 不传表示不修改
+		LeagueConfig    *models.LeaderboardLeagueConfig    `json:"leagueConfig"`    // 不传表示不修改，bucketSize为0表示关闭联赛模式
 	}
 
 	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
@@ -224,6 +279,48 @@ func (c *LeaderboardController) UpdateLeaderboard() {
 	if req.Sort >= 0 {
 		fields["sort"] = req.Sort
 	}
+	if req.RewardTiers != nil {
+		rewardTiers, err := models.EncodeRewardTiers(*req.RewardTiers)
+		if err != nil {
+			c.Data["json"] = map[string]interface{}{
+				"code":      4001,
+				"msg":       err.Error(),
+				"timestamp": utils.UnixMilli(),
+				"data":      nil,
+			}
+			c.ServeJSON()
+			return
+		}
+		fields["reward_tiers"] = rewardTiers
+	}
+	if req.ValidationRules != nil {
+		validationRules, err := models.EncodeValidationRules(req.ValidationRules)
+		if err != nil {
+			c.Data["json"] = map[string]interface{}{
+				"code":      4001,
+				"msg":       err.Error(),
+				"timestamp": utils.UnixMilli(),
+				"data":      nil,
+			}
+			c.ServeJSON()
+			return
+		}
+		fields["validation_rules"] = validationRules
+	}
+	if req.LeagueConfig != nil {
+		leagueConfig, err := models.EncodeLeagueConfig(req.LeagueConfig)
+		if err != nil {
+			c.Data["json"] = map[string]interface{}{
+				"code":      4001,
+				"msg":       err.Error(),
+				"timestamp": utils.UnixMilli(),
+				"data":      nil,
+			}
+			c.ServeJSON()
+			return
+		}
+		fields["league_config"] = leagueConfig
+	}
 
 	if err := models.UpdateLeaderboard(req.AppId, req.LeaderboardType, fields); err != nil {
 		c.Data["json"] = map[string]interface{}{
@@ -441,3 +538,227 @@ func (c *LeaderboardController) QueryLeaderboardScore() {
 
 	utils.SuccessResponse(&c.Controller, "success", data)
 }
+
+// GetLeaderboardSeasons 获取排行榜历史赛季列表
+func (c *LeaderboardController) GetLeaderboardSeasons() {
+	var req struct {
+		AppId           string `json:"appId"`
+		LeaderboardType string `json:"leaderboardType"`
+		Page            int    `json:"page"`
+		PageSize        int    `json:"pageSize"`
+	}
+
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+		return
+	}
+
+	if req.AppId == "" || req.LeaderboardType == "" {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "appId和leaderboardType不能为空", nil)
+		return
+	}
+
+	// 设置默认值
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+
+	seasons, total, err := models.GetLeaderboardSeasons(req.AppId, req.LeaderboardType, req.Page, req.PageSize)
+	if err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "获取赛季列表失败: "+err.Error(), nil)
+		return
+	}
+
+	result := map[string]interface{}{
+		"list":       seasons,
+		"total":      total,
+		"page":       req.Page,
+		"pageSize":   req.PageSize,
+		"totalPages": (total + int64(req.PageSize) - 1) / int64(req.PageSize),
+	}
+
+	utils.SuccessResponse(&c.Controller, "success", result)
+}
+
+// GetLeaderboardSeasonData 获取历史赛季的排名数据（可按玩家筛选，用于争议处理和奖励发放）
+func (c *LeaderboardController) GetLeaderboardSeasonData() {
+	var req struct {
+		AppId           string `json:"appId"`
+		LeaderboardType string `json:"leaderboardType"`
+		Season          int    `json:"season"`
+		PlayerId        string `json:"playerId"`
+		Page            int    `json:"page"`
+		PageSize        int    `json:"pageSize"`
+	}
+
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+		return
+	}
+
+	if req.AppId == "" || req.LeaderboardType == "" || req.Season <= 0 {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "appId、leaderboardType和season不能为空", nil)
+		return
+	}
+
+	// 设置默认值
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+
+	data, total, err := models.GetLeaderboardSeasonData(req.AppId, req.LeaderboardType, req.Season, req.PlayerId, req.Page, req.PageSize)
+	if err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "获取赛季数据失败: "+err.Error(), nil)
+		return
+	}
+
+	result := map[string]interface{}{
+		"season":     req.Season,
+		"list":       data,
+		"total":      total,
+		"page":       req.Page,
+		"pageSize":   req.PageSize,
+		"totalPages": (total + int64(req.PageSize) - 1) / int64(req.PageSize),
+	}
+
+	utils.SuccessResponse(&c.Controller, "success", result)
+}
+
+// GetSuspiciousScores 获取被校验规则拒绝的可疑分数列表
+func (c *LeaderboardController) GetSuspiciousScores() {
+	var req struct {
+		AppId           string `json:"appId"`
+		LeaderboardType string `json:"leaderboardType"`
+		PlayerId        string `json:"playerId"`
+		Status          *int   `json:"status"` // 0=待审核 1=确认作弊 2=已忽略，不传表示全部
+		Page            int    `json:"page"`
+		PageSize        int    `json:"pageSize"`
+	}
+
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+		return
+	}
+
+	if req.AppId == "" {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "appId不能为空", nil)
+		return
+	}
+
+	// 设置默认值
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 20
+	}
+	status := -1
+	if req.Status != nil {
+		status = *req.Status
+	}
+
+	list, total, err := models.GetSuspiciousScores(req.AppId, req.LeaderboardType, req.PlayerId, status, req.Page, req.PageSize)
+	if err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "获取可疑分数失败: "+err.Error(), nil)
+		return
+	}
+
+	result := map[string]interface{}{
+		"list":       list,
+		"total":      total,
+		"page":       req.Page,
+		"pageSize":   req.PageSize,
+		"totalPages": (total + int64(req.PageSize) - 1) / int64(req.PageSize),
+	}
+
+	utils.SuccessResponse(&c.Controller, "success", result)
+}
+
+// ReviewSuspiciousScore 审核可疑分数
+func (c *LeaderboardController) ReviewSuspiciousScore() {
+	var req struct {
+		AppId       string `json:"appId"`
+		Id          int64  `json:"id"`
+		Status      int    `json:"status"` // 1=确认作弊 2=已忽略
+		Remark      string `json:"remark"`
+		RemoveScore bool   `json:"removeScore"` // 确认作弊时是否移除玩家当前排行榜成绩
+	}
+
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+		return
+	}
+
+	if req.AppId == "" || req.Id <= 0 {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "appId和id不能为空", nil)
+		return
+	}
+	if req.Status != 1 && req.Status != 2 {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "status只能为1（确认作弊）或2（忽略）", nil)
+		return
+	}
+
+	reviewer, _ := c.Ctx.Input.GetData("username").(string)
+	if err := models.ReviewSuspiciousScore(req.AppId, req.Id, req.Status, req.Remark, reviewer, req.RemoveScore); err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "审核失败: "+err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(&c.Controller, "success", nil)
+}
+
+// ReconcileLeaderboard 触发排行榜Redis与数据库对账（由游戏服异步执行，通过getReconcileReport查询结果）
+func (c *LeaderboardController) ReconcileLeaderboard() {
+	var req struct {
+		AppId string `json:"appId"` // 为空表示所有应用
+	}
+
+	if len(c.Ctx.Input.RequestBody) > 0 {
+		if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+			utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+			return
+		}
+	}
+
+	operator, _ := c.Ctx.Input.GetData("username").(string)
+	requestId, err := models.RequestLeaderboardReconcile(req.AppId, operator)
+	if err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "提交对账请求失败: "+err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(&c.Controller, "对账请求已提交", map[string]interface{}{
+		"requestId": requestId,
+	})
+}
+
+// GetReconcileReport 获取排行榜对账报告（不传requestId返回最近一次定时对账报告）
+func (c *LeaderboardController) GetReconcileReport() {
+	var req struct {
+		RequestId string `json:"requestId"`
+	}
+
+	if len(c.Ctx.Input.RequestBody) > 0 {
+		if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+			utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+			return
+		}
+	}
+
+	report, err := models.GetLeaderboardReconcileReport(req.RequestId)
+	if err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "获取对账报告失败: "+err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(&c.Controller, "success", map[string]interface{}{
+		"finished": report != nil,
+		"report":   report,
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"admin-service/models"
 	"admin-service/utils"
 	"encoding/json"
+	"errors"
 
 	"github.com/beego/beego/v2/server/web"
 )
@@ -216,6 +217,76 @@ func (c *UserController) DeleteUser() {
 	c.ServeJSON()
 }
 
+// GetUserSessions 获取玩家当前有效的登录会话（设备、IP、最后心跳时间）
+func (c *UserController) GetUserSessions() {
+	var requestData struct {
+		AppId    string `json:"appId"`
+		PlayerId string `json:"playerId"`
+	}
+
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &requestData); err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+		return
+	}
+
+	if requestData.AppId == "" || requestData.PlayerId == "" {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "应用ID和玩家ID不能为空", nil)
+		return
+	}
+
+	sessions, err := models.ListGameUserSessions(requestData.AppId, requestData.PlayerId)
+	if err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "获取登录会话失败: "+err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(&c.Controller, "获取成功", map[string]interface{}{
+		"list": sessions,
+	})
+}
+
+// KickUser 踢玩家下线，sessionId为空时踢下线玩家的所有登录会话，被踢的设备需要重新登录
+func (c *UserController) KickUser() {
+	claims := utils.ValidateJWT(c.Ctx)
+	if claims == nil {
+		return
+	}
+
+	var requestData struct {
+		AppId     string `json:"appId"`
+		PlayerId  string `json:"playerId"`
+		SessionId string `json:"sessionId"`
+	}
+
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &requestData); err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+		return
+	}
+
+	if requestData.AppId == "" || requestData.PlayerId == "" {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "应用ID和玩家ID不能为空", nil)
+		return
+	}
+
+	count, err := models.TerminateGameUserSessions(requestData.AppId, requestData.PlayerId, requestData.SessionId, models.SessionKickAdmin)
+	if err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "踢下线失败: "+err.Error(), nil)
+		return
+	}
+
+	// 记录操作日志
+	models.LogAdminOperation(claims.UserID, claims.Username, "KICK", "USER", map[string]interface{}{
+		"appId":     requestData.AppId,
+		"playerId":  requestData.PlayerId,
+		"sessionId": requestData.SessionId,
+		"count":     count,
+	})
+
+	utils.SuccessResponse(&c.Controller, "已踢下线", map[string]interface{}{
+		"count": count,
+	})
+}
+
 // GetUserDetail 获取用户详情
 func (c *UserController) GetUserDetail() {
 	var requestData struct {
@@ -250,7 +321,7 @@ func (c *UserController) SetUserDetail() {
 		return
 	}
 
-	if err := models.SetUserDetail(requestData.AppId, requestData.PlayerId, requestData.UserData); err != nil {
+	if err := models.SetUserDetail(requestData.AppId, requestData.PlayerId, requestData.UserData, utils.GetClientIP(&c.Controller)); err != nil {
 		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "设置用户详情失败", nil)
 		return
 	}
@@ -258,6 +329,236 @@ func (c *UserController) SetUserDetail() {
 	utils.SuccessResponse(&c.Controller, "success", nil)
 }
 
+// GetUserSlots 获取玩家的命名存档
+func (c *UserController) GetUserSlots() {
+	var requestData struct {
+		AppId    string `json:"appId"`
+		PlayerId string `json:"playerId"`
+	}
+
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &requestData); err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+		return
+	}
+
+	if requestData.AppId == "" || requestData.PlayerId == "" {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "应用ID和玩家ID不能为空", nil)
+		return
+	}
+
+	slots, err := models.GetGameUserSlots(requestData.AppId, requestData.PlayerId)
+	if err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "获取玩家存档失败", nil)
+		return
+	}
+
+	utils.SuccessResponse(&c.Controller, "success", map[string]interface{}{
+		"list": slots,
+	})
+}
+
+// GetUserDataHistory 获取玩家存档历史版本列表
+func (c *UserController) GetUserDataHistory() {
+	var requestData struct {
+		AppId    string `json:"appId"`
+		PlayerId string `json:"playerId"`
+	}
+
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &requestData); err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+		return
+	}
+
+	if requestData.AppId == "" || requestData.PlayerId == "" {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "应用ID和玩家ID不能为空", nil)
+		return
+	}
+
+	versions, err := models.GetUserDataHistory(requestData.AppId, requestData.PlayerId)
+	if err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "获取存档历史失败", nil)
+		return
+	}
+
+	utils.SuccessResponse(&c.Controller, "success", map[string]interface{}{
+		"list": versions,
+	})
+}
+
+// DiffUserData 对比玩家存档的两个版本（toVersion为0时与当前存档对比）
+func (c *UserController) DiffUserData() {
+	var requestData struct {
+		AppId       string `json:"appId"`
+		PlayerId    string `json:"playerId"`
+		FromVersion int64  `json:"fromVersion"`
+		ToVersion   int64  `json:"toVersion"`
+	}
+
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &requestData); err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+		return
+	}
+
+	if requestData.AppId == "" || requestData.PlayerId == "" || requestData.FromVersion <= 0 {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "应用ID、玩家ID和对比版本不能为空", nil)
+		return
+	}
+
+	diffs, err := models.DiffUserDataVersions(requestData.AppId, requestData.PlayerId, requestData.FromVersion, requestData.ToVersion)
+	if err != nil {
+		if errors.Is(err, models.ErrUserDataVersionNotFound) {
+			utils.ErrorResponse(&c.Controller, utils.CodeNotFound, err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "对比存档失败: "+err.Error(), nil)
+		return
+	}
+
+	utils.SuccessResponse(&c.Controller, "success", map[string]interface{}{
+		"fromVersion": requestData.FromVersion,
+		"toVersion":   requestData.ToVersion,
+		"diffs":       diffs,
+	})
+}
+
+// RestoreUserData 将玩家存档回滚到指定历史版本
+func (c *UserController) RestoreUserData() {
+	claims := utils.ValidateJWT(c.Ctx)
+	if claims == nil {
+		return
+	}
+
+	var requestData struct {
+		AppId    string `json:"appId"`
+		PlayerId string `json:"playerId"`
+		Version  int64  `json:"version"`
+	}
+
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &requestData); err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+		return
+	}
+
+	if requestData.AppId == "" || requestData.PlayerId == "" || requestData.Version <= 0 {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "应用ID、玩家ID和版本号不能为空", nil)
+		return
+	}
+
+	newVersion, err := models.RestoreUserDataVersion(requestData.AppId, requestData.PlayerId, requestData.Version, utils.GetClientIP(&c.Controller))
+	if err != nil {
+		if errors.Is(err, models.ErrUserDataVersionNotFound) {
+			utils.ErrorResponse(&c.Controller, utils.CodeNotFound, err.Error(), nil)
+			return
+		}
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "回滚存档失败: "+err.Error(), nil)
+		return
+	}
+
+	// 记录操作日志
+	models.LogAdminOperation(claims.UserID, claims.Username, "RESTORE", "USER_DATA", map[string]interface{}{
+		"appId":       requestData.AppId,
+		"playerId":    requestData.PlayerId,
+		"fromVersion": requestData.Version,
+		"newVersion":  newVersion,
+	})
+
+	utils.SuccessResponse(&c.Controller, "回滚成功", map[string]interface{}{
+		"version": newVersion,
+	})
+}
+
+// GetUserIdentities 获取玩家绑定的登录身份
+func (c *UserController) GetUserIdentities() {
+	var requestData struct {
+		AppId    string `json:"appId"`
+		PlayerId string `json:"playerId"`
+	}
+
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &requestData); err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+		return
+	}
+
+	if requestData.AppId == "" || requestData.PlayerId == "" {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "应用ID和玩家ID不能为空", nil)
+		return
+	}
+
+	identities, err := models.GetGameUserIdentities(requestData.AppId, requestData.PlayerId)
+	if err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, "获取登录身份失败", nil)
+		return
+	}
+
+	utils.SuccessResponse(&c.Controller, "success", map[string]interface{}{
+		"list": identities,
+	})
+}
+
+// MergeUsers 合并玩家账号，把源玩家的登录身份、存档、排行榜、邮件和计数器迁移到目标玩家后删除源玩家
+func (c *UserController) MergeUsers() {
+	claims := utils.ValidateJWT(c.Ctx)
+	if claims == nil {
+		return
+	}
+
+	var requestData struct {
+		AppId          string `json:"appId"`
+		SourcePlayerId string `json:"sourcePlayerId"`
+		TargetPlayerId string `json:"targetPlayerId"`
+		UseSourceData  bool   `json:"useSourceData"` // 主存档和同名存档槽位使用源玩家的数据
+		KeepIdentity   string `json:"keepIdentity"`  // 同一渠道两个玩家都有登录身份时保留哪一方：target/source，为空时返回冲突
+	}
+
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &requestData); err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "参数错误", nil)
+		return
+	}
+
+	if requestData.AppId == "" || requestData.SourcePlayerId == "" || requestData.TargetPlayerId == "" {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "应用ID、源玩家ID和目标玩家ID不能为空", nil)
+		return
+	}
+
+	if requestData.KeepIdentity != "" && requestData.KeepIdentity != models.MergeKeepTargetIdentity && requestData.KeepIdentity != models.MergeKeepSourceIdentity {
+		utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, "keepIdentity只能为target或source", nil)
+		return
+	}
+
+	result, err := models.MergeGameUsers(requestData.AppId, requestData.SourcePlayerId, requestData.TargetPlayerId, requestData.UseSourceData, requestData.KeepIdentity, utils.GetClientIP(&c.Controller))
+	if err != nil && result == nil {
+		switch {
+		case errors.Is(err, models.ErrMergeSamePlayer):
+			utils.ErrorResponse(&c.Controller, utils.CodeBadRequest, err.Error(), nil)
+		case errors.Is(err, models.ErrMergePlayerNotFound):
+			utils.ErrorResponse(&c.Controller, utils.CodeNotFound, err.Error(), nil)
+		case errors.Is(err, models.ErrMergeInProgress):
+			utils.ErrorResponse(&c.Controller, utils.CodeConflict, err.Error(), nil)
+		case errors.Is(err, models.ErrMergeIdentityConflict):
+			utils.ErrorResponse(&c.Controller, utils.CodeConflict, err.Error()+"，请通过keepIdentity指定保留哪一方", nil)
+		default:
+			utils.ErrorResponse(&c.Controller, utils.CodeServerError, "合并玩家失败: "+err.Error(), nil)
+		}
+		return
+	}
+
+	// 记录操作日志
+	models.LogAdminOperation(claims.UserID, claims.Username, "MERGE", "USER", map[string]interface{}{
+		"appId":          requestData.AppId,
+		"sourcePlayerId": requestData.SourcePlayerId,
+		"targetPlayerId": requestData.TargetPlayerId,
+		"useSourceData":  requestData.UseSourceData,
+		"keepIdentity":   requestData.KeepIdentity,
+		"result":         result,
+	})
+
+	if err != nil {
+		utils.ErrorResponse(&c.Controller, utils.CodeServerError, err.Error(), result)
+		return
+	}
+	utils.SuccessResponse(&c.Controller, "合并成功", result)
+}
+
 // GetUserStats 获取用户统计（应用级别统计，对齐云函数 getUserStats）
 func (c *UserController) GetUserStats() {
 	var requestData struct {
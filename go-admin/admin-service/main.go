@@ -1,6 +1,7 @@
 package main
 
 import (
+	"admin-service/models"
 	_ "admin-service/routers"
 	"admin-service/utils"
 	"flag"
@@ -226,6 +227,11 @@ func main() {
 		} else {
 			fmt.Printf(" ✅ 完成\n")
 		}
+
+		// 为已有应用补建新增的数据表
+		if err := models.EnsureAppTables(); err != nil {
+			log.Printf("补建应用数据表失败: %v", err)
+		}
 	}
 
 	// 读取配置
@@ -108,31 +108,47 @@ func getRequiredPermission(path, method string) string {
 		"/admin/resetPwd": "admin_manage",
 
 		// 应用管理
-		"/app/getAll":    "app_manage",
-		"/app/create":    "app_manage",
-		"/app/update":    "app_manage",
-		"/app/delete":    "app_manage",
-		"/app/init":      "app_manage",
-		"/app/query":     "app_manage",
-		"/app/getDetail": "app_manage",
+		"/app/getAll":        "app_manage",
+		"/app/create":        "app_manage",
+		"/app/update":        "app_manage",
+		"/app/delete":        "app_manage",
+		"/app/init":          "app_manage",
+		"/app/query":         "app_manage",
+		"/app/getDetail":     "app_manage",
+		"/app/getDataSchema": "app_manage",
+		"/app/setDataSchema": "app_manage",
 
 		// 用户管理
-		"/user/getAll":    "user_manage",
-		"/user/ban":       "user_manage",
-		"/user/unban":     "user_manage",
-		"/user/delete":    "user_manage",
-		"/user/getDetail": "user_manage",
-		"/user/setDetail": "user_manage",
-		"/user/getStats":  "user_manage",
+		"/user/getAll":         "user_manage",
+		"/user/ban":            "user_manage",
+		"/user/unban":          "user_manage",
+		"/user/delete":         "user_manage",
+		"/user/getSessions":    "user_manage",
+		"/user/kick":           "user_manage",
+		"/user/getDetail":      "user_manage",
+		"/user/setDetail":      "user_manage",
+		"/user/getSlots":       "user_manage",
+		"/user/getDataHistory": "user_manage",
+		"/user/diffData":       "user_manage",
+		"/user/restoreData":    "user_manage",
+		"/user/getIdentities":  "user_manage",
+		"/user/merge":          "user_manage",
+		"/user/getStats":       "user_manage",
 
 		// 排行榜管理
-		"/leaderboard/getAll":      "leaderboard_manage",
-		"/leaderboard/create":      "leaderboard_manage",
-		"/leaderboard/update":      "leaderboard_manage",
-		"/leaderboard/delete":      "leaderboard_manage",
-		"/leaderboard/getData":     "leaderboard_manage",
-		"/leaderboard/updateScore": "leaderboard_manage",
-		"/leaderboard/deleteScore": "leaderboard_manage",
+		"/leaderboard/getAll":             "leaderboard_manage",
+		"/leaderboard/create":             "leaderboard_manage",
+		"/leaderboard/update":             "leaderboard_manage",
+		"/leaderboard/delete":             "leaderboard_manage",
+		"/leaderboard/getData":            "leaderboard_manage",
+		"/leaderboard/updateScore":        "leaderboard_manage",
+		"/leaderboard/deleteScore":        "leaderboard_manage",
+		"/leaderboard/getSeasons":         "leaderboard_manage",
+		"/leaderboard/getSeasonData":      "leaderboard_manage",
+		"/leaderboard/getSuspicious":      "leaderboard_manage",
+		"/leaderboard/reviewSuspicious":   "leaderboard_manage",
+		"/leaderboard/reconcile":          "leaderboard_manage",
+		"/leaderboard/getReconcileReport": "leaderboard_manage",
 
 		// 计数器管理
 		"/counter/getList":     "leaderboard_manage",
@@ -140,6 +156,7 @@ func getRequiredPermission(path, method string) string {
 		"/counter/update":      "leaderboard_manage",
 		"/counter/delete":      "leaderboard_manage",
 		"/counter/getAllStats": "leaderboard_manage",
+		"/counter/getHistory":  "leaderboard_manage",
 
 		// 统计查看
 		"/stat/dashboard":           "stats_view",
@@ -11,22 +11,26 @@ import (
 // Application 应用模型
 type Application struct {
 	BaseModel
-	AppId         string `orm:"unique;size(50);column(app_id)" json:"appId"`               // 应用ID（唯一）
-	AppName       string `orm:"size(100);column(app_name)" json:"appName"`                 // 应用名称
-	Description   string `orm:"type(text);column(description)" json:"description"`         // 应用描述
-	ChannelAppId  string `orm:"size(100);column(channel_app_id)" json:"channelAppId"`      // 渠道应用ID
-	ChannelAppKey string `orm:"size(100);column(channel_app_key)" json:"channelAppKey"`    // 渠道应用密钥
-	Category      string `orm:"size(50);default('game');column(category)" json:"category"` // 应用分类: game/tool/social
-	Platform      string `orm:"size(50);column(platform)" json:"platform"`                 // 平台: alipay/wechat/baidu
-	Status        string `orm:"size(20);default('active');column(status)" json:"status"`   // 状态: active/inactive/pending
-	Version       string `orm:"size(50);column(version)" json:"version"`                   // 当前版本
-	MinVersion    string `orm:"size(50);column(min_version)" json:"minVersion"`            // 最低支持版本
-	Settings      string `orm:"type(text);column(settings)" json:"settings"`               // 应用设置(JSON格式)
-	UserCount     int64  `orm:"default(0);column(user_count)" json:"userCount"`            // 用户数量
-	ScoreCount    int64  `orm:"default(0);column(score_count)" json:"scoreCount"`          // 分数记录数
-	DailyActive   int64  `orm:"default(0);column(daily_active)" json:"dailyActive"`        // 日活跃用户
-	MonthlyActive int64  `orm:"default(0);column(monthly_active)" json:"monthlyActive"`    // 月活跃用户
-	CreatedBy     string `orm:"size(50);column(created_by)" json:"createdBy"`              // 创建者
+	AppId             string `orm:"unique;size(50);column(app_id)" json:"appId"`                         // 应用ID（唯一）
+	AppName           string `orm:"size(100);column(app_name)" json:"appName"`                           // 应用名称
+	Description       string `orm:"type(text);column(description)" json:"description"`                   // 应用描述
+	ChannelAppId      string `orm:"size(100);column(channel_app_id)" json:"channelAppId"`                // 渠道应用ID
+	ChannelAppKey     string `orm:"size(100);column(channel_app_key)" json:"channelAppKey"`              // 渠道应用密钥
+	Category          string `orm:"size(50);default('game');column(category)" json:"category"`           // 应用分类: game/tool/social
+	Platform          string `orm:"size(50);column(platform)" json:"platform"`                           // 平台: alipay/wechat/baidu
+	Status            string `orm:"size(20);default('active');column(status)" json:"status"`             // 状态: active/inactive/pending
+	Version           string `orm:"size(50);column(version)" json:"version"`                             // 当前版本
+	MinVersion        string `orm:"size(50);column(min_version)" json:"minVersion"`                      // 最低支持版本
+	Settings          string `orm:"type(text);column(settings)" json:"settings"`                         // 应用设置(JSON格式)
+	UserCount         int64  `orm:"default(0);column(user_count)" json:"userCount"`                      // 用户数量
+	ScoreCount        int64  `orm:"default(0);column(score_count)" json:"scoreCount"`                    // 分数记录数
+	DailyActive       int64  `orm:"default(0);column(daily_active)" json:"dailyActive"`                  // 日活跃用户
+	MonthlyActive     int64  `orm:"default(0);column(monthly_active)" json:"monthlyActive"`              // 月活跃用户
+	CreatedBy         string `orm:"size(50);column(created_by)" json:"createdBy"`                        // 创建者
+	DataSchema        string `orm:"type(text);null;column(data_schema)" json:"dataSchema"`               // 玩家存档JSON Schema
+	DataMaxSize       int    `orm:"default(0);column(data_max_size)" json:"dataMaxSize"`                 // 玩家存档最大字节数，0表示使用默认值
+	DataMaxDepth      int    `orm:"default(0);column(data_max_depth)" json:"dataMaxDepth"`               // 玩家存档最大嵌套层数，0表示使用默认值
+	SingleDeviceLogin bool   `orm:"default(false);column(single_device_login)" json:"singleDeviceLogin"` // 是否限制玩家只能在一台设备登录
 }
 
 func (a *Application) TableName() string {
@@ -94,6 +98,7 @@ CREATE TABLE IF NOT EXISTS user_%s (
   nickname varchar(100) COMMENT '昵称',
   avatar varchar(500) COMMENT '头像URL',
   data longtext COMMENT '游戏数据（JSON格式）',
+  data_version bigint(20) NOT NULL DEFAULT 0 COMMENT '游戏数据版本号（每次保存加1）',
   level int(11) NOT NULL DEFAULT 1 COMMENT '等级',
   exp bigint(20) NOT NULL DEFAULT 0 COMMENT '经验值',
   coin bigint(20) NOT NULL DEFAULT 0 COMMENT '金币',
@@ -117,37 +122,175 @@ CREATE TABLE IF NOT EXISTS user_%s (
   KEY idx_banned_ban_expire (banned, ban_expire)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户数据表_%s'`, cleanAppId, cleanAppId)
 
+	// 创建玩家命名存档表（每个玩家可以有多个槽位，如slot1、settings）
+	userSlotSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS user_data_%s (
+  id bigint(20) NOT NULL AUTO_INCREMENT,
+  player_id varchar(100) NOT NULL COMMENT '玩家ID',
+  slot varchar(50) NOT NULL COMMENT '槽位名称',
+  data longtext COMMENT '存档内容（JSON格式）',
+  version bigint(20) NOT NULL DEFAULT 0 COMMENT '存档版本号（每次保存加1）',
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '修改时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uk_player_slot (player_id, slot)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='玩家存档表_%s'`, cleanAppId, cleanAppId)
+
+	// 创建玩家存档历史表（保留最近若干个版本，用于对比和回滚）
+	userDataHistorySQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS user_data_history_%s (
+  id bigint(20) NOT NULL AUTO_INCREMENT,
+  player_id varchar(100) NOT NULL COMMENT '玩家ID',
+  version bigint(20) NOT NULL COMMENT '存档版本号',
+  data longtext COMMENT '存档内容（JSON格式）',
+  source varchar(20) NOT NULL DEFAULT 'player' COMMENT '来源: initial/player/patch/admin/restore/merge',
+  ip varchar(50) NOT NULL DEFAULT '' COMMENT '保存来源IP',
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '保存时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uk_player_version (player_id, version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='玩家存档历史表_%s'`, cleanAppId, cleanAppId)
+
+	// 创建玩家登录身份表（一个玩家可以绑定多个渠道的账号）
+	userIdentitySQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS user_identity_%s (
+  id bigint(20) NOT NULL AUTO_INCREMENT,
+  player_id varchar(100) NOT NULL COMMENT '玩家ID',
+  provider varchar(20) NOT NULL COMMENT '登录渠道: common/wx/qq/alipay/douyin/yalla',
+  open_id varchar(100) NOT NULL COMMENT '渠道用户唯一标识',
+  union_id varchar(100) NOT NULL DEFAULT '' COMMENT '开放平台统一标识',
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '绑定时间',
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '修改时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uk_provider_open_id (provider, open_id),
+  KEY idx_player_id (player_id),
+  KEY idx_union_id (union_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='玩家登录身份表_%s'`, cleanAppId, cleanAppId)
+
 	// 创建排行榜统计表
 	leaderboardStatsSQL := fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS leaderboard_%s (
   id bigint(20) NOT NULL AUTO_INCREMENT,
   type varchar(50) NOT NULL COMMENT '排行榜类型',
+  partition_key varchar(100) NOT NULL DEFAULT '' COMMENT '分区键（如区服、地区）',
   player_id varchar(100) NOT NULL COMMENT '玩家ID',
   score bigint(20) NOT NULL DEFAULT 0 COMMENT '分数',
   extra_data text COMMENT '额外数据（JSON格式）',
   created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
   PRIMARY KEY (id),
-  UNIQUE KEY uk_leaderboard_user (type, player_id),
-  KEY idx_leaderboard_score (type, score DESC),
+  UNIQUE KEY uk_leaderboard_user (type, partition_key, player_id),
+  KEY idx_leaderboard_score (type, partition_key, score DESC),
   KEY idx_leaderboard_type (type),
   KEY idx_updated_at (updated_at)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='排行榜数据表_%s'`, cleanAppId, cleanAppId)
 
+	// 创建排行榜赛季历史表（排行榜重置时归档最终排名）
+	leaderboardHistorySQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS leaderboard_history_%s (
+  id bigint(20) NOT NULL AUTO_INCREMENT,
+  type varchar(50) NOT NULL COMMENT '排行榜类型',
+  season int(11) NOT NULL COMMENT '赛季号',
+  partition_key varchar(100) NOT NULL DEFAULT '' COMMENT '分区键（如区服、地区）',
+  player_id varchar(100) NOT NULL COMMENT '玩家ID',
+  score bigint(20) NOT NULL DEFAULT 0 COMMENT '最终分数',
+  ranking int(11) NOT NULL COMMENT '最终排名',
+  extra_data text COMMENT '额外数据（JSON格式）',
+  season_start datetime COMMENT '赛季开始时间',
+  season_end datetime NOT NULL COMMENT '赛季结束时间',
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '归档时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uk_season_player (type, season, partition_key, player_id),
+  KEY idx_season_ranking (type, season, partition_key, ranking),
+  KEY idx_player_id (player_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='排行榜赛季历史表_%s'`, cleanAppId, cleanAppId)
+
+	// 创建排行榜可疑分数记录表（未通过校验规则的提交）
+	leaderboardSuspiciousSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS leaderboard_suspicious_%s (
+  id bigint(20) NOT NULL AUTO_INCREMENT,
+  type varchar(50) NOT NULL COMMENT '排行榜类型',
+  player_id varchar(100) NOT NULL COMMENT '玩家ID',
+  score bigint(20) NOT NULL DEFAULT 0 COMMENT '提交的分数',
+  old_score bigint(20) NOT NULL DEFAULT 0 COMMENT '提交前的分数',
+  extra_data text COMMENT '提交的额外数据',
+  rule varchar(50) NOT NULL COMMENT '触发的校验规则',
+  reason varchar(500) COMMENT '拒绝原因',
+  status tinyint(4) NOT NULL DEFAULT 0 COMMENT '审核状态: 0=待审核 1=确认作弊 2=已忽略',
+  reviewed_by varchar(100) DEFAULT NULL COMMENT '审核人',
+  reviewed_at datetime DEFAULT NULL COMMENT '审核时间',
+  remark varchar(500) DEFAULT NULL COMMENT '审核备注',
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '提交时间',
+  PRIMARY KEY (id),
+  KEY idx_type_status (type, status),
+  KEY idx_player_id (player_id),
+  KEY idx_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='排行榜可疑分数表_%s'`, cleanAppId, cleanAppId)
+
+	// 创建排行榜联赛分组表（联赛模式下玩家的段位和本赛季分组）
+	leaderboardLeagueSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS leaderboard_league_%s (
+  id bigint(20) NOT NULL AUTO_INCREMENT,
+  type varchar(50) NOT NULL COMMENT '排行榜类型',
+  player_id varchar(100) NOT NULL COMMENT '玩家ID',
+  tier int(11) NOT NULL DEFAULT 1 COMMENT '当前段位（1为最低段位）',
+  bucket int(11) NOT NULL DEFAULT 0 COMMENT '本赛季分组号（0表示本赛季尚未分组）',
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uk_league_player (type, player_id),
+  KEY idx_league_bucket (type, tier, bucket)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='排行榜联赛分组表_%s'`, cleanAppId, cleanAppId)
+
 	// 创建计数器表（简化结构，对齐JS功能）
 	counterSQL := fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS counter_%s (
   id bigint(20) NOT NULL AUTO_INCREMENT,
   counter_key varchar(100) NOT NULL COMMENT '计数器名称',
+  player_id varchar(100) NOT NULL DEFAULT '' COMMENT '玩家ID（全局计数器为空）',
   location varchar(100) NOT NULL DEFAULT 'default' COMMENT '点位标识',
   value bigint(20) NOT NULL DEFAULT 0 COMMENT '计数值',
   created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
   updated_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   PRIMARY KEY (id),
-  UNIQUE KEY uk_location (counter_key, location),
-  KEY idx_location (location)
+  UNIQUE KEY uk_location (counter_key, player_id, location),
+  KEY idx_location (location),
+  KEY idx_player (player_id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='计数器数据表_%s'`, cleanAppId, cleanAppId)
 
+	// 创建计数器历史表（定时采样和周期重置前的最终值）
+	counterHistorySQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS counter_history_%s (
+  id bigint(20) NOT NULL AUTO_INCREMENT,
+  counter_key varchar(100) NOT NULL COMMENT '计数器名称',
+  location varchar(100) NOT NULL DEFAULT 'default' COMMENT '点位标识',
+  period varchar(50) NOT NULL DEFAULT '' COMMENT '重置周期标识',
+  value bigint(20) NOT NULL DEFAULT 0 COMMENT '计数值',
+  sample_type varchar(20) NOT NULL DEFAULT 'sample' COMMENT '类型: sample定时采样/reset重置前的值',
+  sampled_at datetime NOT NULL COMMENT '采样时间',
+  created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY uk_history_sample (counter_key, location, sample_type, sampled_at),
+  KEY idx_history_time (counter_key, sampled_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='计数器历史表_%s'`, cleanAppId, cleanAppId)
+
+	// 创建计数器阈值触发记录表（唯一键保证每个阈值每个重置周期只触发一次）
+	counterThresholdLogSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS counter_threshold_log_%s (
+  id bigint(20) NOT NULL AUTO_INCREMENT,
+  counter_key varchar(100) NOT NULL COMMENT '计数器名称',
+  threshold_id varchar(50) NOT NULL COMMENT '阈值标识',
+  period varchar(50) NOT NULL COMMENT '重置周期标识',
+  location varchar(100) NOT NULL DEFAULT 'default' COMMENT '点位标识',
+  value bigint(20) NOT NULL DEFAULT 0 COMMENT '触发时的计数值',
+  action_type varchar(20) NOT NULL COMMENT '动作类型: mail/config/webhook',
+  status varchar(20) NOT NULL DEFAULT 'pending' COMMENT '状态: pending/success/failed',
+  error_msg text COMMENT '失败原因',
+  fired_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '触发时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uk_threshold_period (counter_key, threshold_id, period),
+  KEY idx_fired_at (fired_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='计数器阈值触发记录表_%s'`, cleanAppId, cleanAppId)
+
 	// 创建邮件表（存储邮件内容）
 	mailSQL := fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS mail_%s (
@@ -222,7 +365,7 @@ CREATE TABLE IF NOT EXISTS game_config_%s (
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='游戏配置表_%s'`, cleanAppId, cleanAppId)
 
 	// 执行创建表的SQL
-	sqls := []string{userDataSQL, leaderboardStatsSQL, counterSQL, mailSQL, mailPlayerRelationSQL, gameConfigSQL}
+	sqls := []string{userDataSQL, userSlotSQL, userDataHistorySQL, userIdentitySQL, leaderboardStatsSQL, leaderboardHistorySQL, leaderboardSuspiciousSQL, leaderboardLeagueSQL, counterSQL, counterHistorySQL, counterThresholdLogSQL, mailSQL, mailPlayerRelationSQL, gameConfigSQL}
 	for _, sql := range sqls {
 		_, err := o.Raw(sql).Exec()
 		if err != nil {
@@ -233,6 +376,25 @@ CREATE TABLE IF NOT EXISTS game_config_%s (
 	return nil
 }
 
+// EnsureAppTables 为所有应用补建缺失的数据表（升级后新增的表不会在旧应用中自动创建）
+func EnsureAppTables() error {
+	o := orm.NewOrm()
+
+	var apps []Application
+	_, err := o.QueryTable("apps").All(&apps, "Id", "AppId")
+	if err != nil {
+		return fmt.Errorf("query apps failed: %v", err)
+	}
+
+	for i := range apps {
+		if err := apps[i].createAppTables(); err != nil {
+			return fmt.Errorf("app %s: %v", apps[i].AppId, err)
+		}
+	}
+
+	return nil
+}
+
 // Update 更新应用
 func (a *Application) Update(fields ...string) error {
 	o := orm.NewOrm()
@@ -343,8 +505,16 @@ func (a *Application) dropAppTables() error {
 
 	tables := []string{
 		fmt.Sprintf("user_%s", cleanAppId),
+		fmt.Sprintf("user_data_%s", cleanAppId),
+		fmt.Sprintf("user_data_history_%s", cleanAppId),
+		fmt.Sprintf("user_identity_%s", cleanAppId),
 		fmt.Sprintf("leaderboard_%s", cleanAppId),
+		fmt.Sprintf("leaderboard_history_%s", cleanAppId),
+		fmt.Sprintf("leaderboard_suspicious_%s", cleanAppId),
+		fmt.Sprintf("leaderboard_league_%s", cleanAppId),
 		fmt.Sprintf("counter_%s", cleanAppId),
+		fmt.Sprintf("counter_history_%s", cleanAppId),
+		fmt.Sprintf("counter_threshold_log_%s", cleanAppId),
 		fmt.Sprintf("mail_%s", cleanAppId),
 		fmt.Sprintf("mail_player_relation_%s", cleanAppId),
 		fmt.Sprintf("game_config_%s", cleanAppId),
@@ -1,11 +1,105 @@
 package models
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"admin-service/utils"
+
 	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/go-redis/redis/v8"
+)
+
+// 计数器Redis键（与游戏服约定一致）
+// 游戏服按重置周期把计数值缓存在counter:{appId}:{period}:{key}（hash，字段为点位），定期写回数据库
+// 玩家计数器每个玩家单独一个键：counter_player:{appId}:{period}:{playerId}:{key}
+const (
+	counterRedisKeyPrefix        = "counter:"
+	counterPlayerRedisKeyPrefix  = "counter_player:"
+	counterLoadedKeyPrefix       = "counter_loaded:"
+	counterPlayerLoadedKeyPrefix = "counter_player_loaded:"
+	counterDirtyKey              = "counter_dirty"
+)
+
+// counterCacheSetScript 缓存存在时更新点位值并标记待落库，缓存不存在时由游戏服从数据库加载
+var counterCacheSetScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+	redis.call('SADD', KEYS[2], ARGV[3])
+end
+return 0
+`)
+
+// counterPermanentPeriod 永久计数器的周期标识（与游戏服约定一致）
+const counterPermanentPeriod = "all"
+
+// counterDirtyMember 游戏服计数器待落库标记（与游戏服约定一致），End为周期结束时间，永久计数器为0
+type counterDirtyMember struct {
+	AppId      string `json:"a"`
+	CounterKey string `json:"k"`
+	PlayerId   string `json:"u,omitempty"`
+	Period     string `json:"p"`
+	End        int64  `json:"e,omitempty"`
+}
+
+// counterPeriod 计数器重置周期
+type counterPeriod struct {
+	Id    string    // 周期标识
+	Start time.Time // 周期开始时间
+	End   time.Time // 周期结束时间
+}
+
+// endUnix 周期结束时间戳，永久计数器返回0
+func (p counterPeriod) endUnix() int64 {
+	if p.Id == counterPermanentPeriod {
+		return 0
+	}
+	return p.End.Unix()
+}
+
+// getCounterPeriod 根据重置类型计算计数器所在周期（与游戏服的周期划分保持一致）
+func getCounterPeriod(config *CounterConfig, now time.Time) counterPeriod {
+	year, month, day := now.Date()
+	switch config.ResetType {
+	case "daily":
+		start := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+		return counterPeriod{Id: start.Format("20060102"), Start: start, End: start.AddDate(0, 0, 1)}
+	case "weekly":
+		// 周一为一周的开始
+		offset := (int(now.Weekday()) + 6) % 7
+		start := time.Date(year, month, day-offset, 0, 0, 0, 0, now.Location())
+		return counterPeriod{Id: "w" + start.Format("20060102"), Start: start, End: start.AddDate(0, 0, 7)}
+	case "monthly":
+		start := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+		return counterPeriod{Id: start.Format("200601"), Start: start, End: start.AddDate(0, 1, 0)}
+	case "custom":
+		// 自定义周期以配置的下次重置时间为基准，每resetValue小时重置一次
+		if config.ResetValue > 0 {
+			length := int64(config.ResetValue) * 3600
+			var end int64
+			if !config.NextResetTime.IsZero() {
+				end = config.NextResetTime.Unix()
+			}
+			if diff := now.Unix() - end; diff >= 0 {
+				end += (diff/length + 1) * length
+			} else {
+				end -= ((-diff - 1) / length) * length
+			}
+			return counterPeriod{Id: "c" + strconv.FormatInt(end, 10), Start: time.Unix(end-length, 0), End: time.Unix(end, 0)}
+		}
+	}
+	return counterPeriod{Id: counterPermanentPeriod}
+}
+
+// 计数器作用域
+const (
+	CounterScopeGlobal = "global" // 全局计数器，所有玩家共享
+	CounterScopePlayer = "player" // 玩家计数器，每个玩家独立计数（如每日免费次数）
 )
 
 // CounterConfig 计数器配置模型
@@ -18,6 +112,85 @@ type CounterConfig struct {
 	NextResetTime time.Time `orm:"type(datetime);null;column(next_reset_time)" json:"nextResetTime"`
 	Description   string    `orm:"type(text);null;column(description)" json:"description"`
 	IsActive      bool      `orm:"default(true);column(is_active)" json:"isActive"`
+	Scope         string    `orm:"size(20);default(global);column(scope)" json:"scope"`  // global, player
+	MaxValue      int64     `orm:"default(0);column(max_value)" json:"maxValue"`         // 上限，0表示不限
+	Thresholds    string    `orm:"type(text);null;column(thresholds)" json:"thresholds"` // 阈值触发配置（JSON数组）
+}
+
+// 计数器阈值动作类型
+const (
+	CounterActionMail    = "mail"    // 发送全服邮件
+	CounterActionConfig  = "config"  // 修改游戏配置
+	CounterActionWebhook = "webhook" // 调用外部接口
+)
+
+// CounterThreshold 计数器阈值，计数值达到阈值时执行动作，每个重置周期只执行一次
+type CounterThreshold struct {
+	Id       string                 `json:"id"`                 // 阈值标识，同一计数器内唯一
+	Location string                 `json:"location,omitempty"` // 点位，默认default
+	Operator string                 `json:"operator"`           // gte: 达到或超过, lte: 降到或低于
+	Value    int64                  `json:"value"`
+	Action   CounterThresholdAction `json:"action"`
+}
+
+// CounterThresholdAction 阈值触发的动作
+type CounterThresholdAction struct {
+	Type        string          `json:"type"`                  // mail/config/webhook
+	Title       string          `json:"title,omitempty"`       // mail: 邮件标题
+	Content     string          `json:"content,omitempty"`     // mail: 邮件内容
+	Rewards     json.RawMessage `json:"rewards,omitempty"`     // mail: 奖励列表（JSON数组）
+	ConfigKey   string          `json:"configKey,omitempty"`   // config: 游戏配置键
+	ConfigValue string          `json:"configValue,omitempty"` // config: 写入的配置值
+	URL         string          `json:"url,omitempty"`         // webhook: 回调地址（POST JSON）
+}
+
+// EncodeCounterThresholds 校验计数器阈值配置并序列化为存储格式
+func EncodeCounterThresholds(thresholds []CounterThreshold) (string, error) {
+	if len(thresholds) == 0 {
+		return "", nil
+	}
+
+	ids := make(map[string]bool, len(thresholds))
+	for _, threshold := range thresholds {
+		if threshold.Id == "" || len(threshold.Id) > 50 {
+			return "", fmt.Errorf("阈值id不能为空且不能超过50个字符")
+		}
+		if ids[threshold.Id] {
+			return "", fmt.Errorf("阈值id[%s]重复", threshold.Id)
+		}
+		ids[threshold.Id] = true
+
+		if threshold.Operator != "gte" && threshold.Operator != "lte" {
+			return "", fmt.Errorf("阈值[%s]的operator只能是gte或lte", threshold.Id)
+		}
+
+		action := threshold.Action
+		switch action.Type {
+		case CounterActionMail:
+			if action.Title == "" {
+				return "", fmt.Errorf("阈值[%s]的邮件标题不能为空", threshold.Id)
+			}
+			if len(action.Rewards) > 0 && !json.Valid(action.Rewards) {
+				return "", fmt.Errorf("阈值[%s]的邮件奖励格式错误", threshold.Id)
+			}
+		case CounterActionConfig:
+			if action.ConfigKey == "" {
+				return "", fmt.Errorf("阈值[%s]的configKey不能为空", threshold.Id)
+			}
+		case CounterActionWebhook:
+			if !strings.HasPrefix(action.URL, "http://") && !strings.HasPrefix(action.URL, "https://") {
+				return "", fmt.Errorf("阈值[%s]的url格式错误", threshold.Id)
+			}
+		default:
+			return "", fmt.Errorf("阈值[%s]的动作类型无效", threshold.Id)
+		}
+	}
+
+	data, err := json.Marshal(thresholds)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
 }
 
 // TableName 指定表名
@@ -29,6 +202,7 @@ func (c *CounterConfig) TableName() string {
 type CounterData struct {
 	Id         int64  `orm:"auto" json:"id"`
 	CounterKey string `orm:"size(100);column(counter_key)" json:"counter_key"`
+	PlayerId   string `orm:"size(100);column(player_id)" json:"player_id"`
 	Location   string `orm:"size(100);default(default);column(location)" json:"location"`
 	Value      int64  `orm:"default(0)" json:"value"`
 	created_at string `orm:"auto_now_add;type(datetime);column(created_at)" json:"created_at"`
@@ -225,6 +399,7 @@ func DeleteCounterConfig(appId, key string) error {
 		}
 
 		fmt.Printf("硬删除配置成功，影响行数: %d\n", configResult)
+		clearCounterCache(appId, key)
 
 		// 删除计数器数据表中对应的数据
 		counterData := &CounterData{}
@@ -266,11 +441,27 @@ func DeleteCounterLocation(appId, key, location string) error {
 	rowsAffected, _ := result.RowsAffected()
 	fmt.Printf("删除点位成功，影响行数: %d\n", rowsAffected)
 
+	ctx := context.Background()
+	for _, cacheKey := range findCounterCacheKeys(ctx, appId, key) {
+		RedisClient.HDel(ctx, cacheKey, location)
+	}
+
 	return nil
 }
 
 // UpdateCounterValue 更新计数器值
+// 只能修改全局计数器的点位，玩家计数器的值由各玩家独立计数，不支持在后台直接修改
 func UpdateCounterValue(appId, key, location string, value int64) error {
+	config, err := GetCounterConfig(appId, key)
+	if err == orm.ErrNoRows {
+		config = nil // 计数器未启用，游戏服不会缓存该计数器
+	} else if err != nil {
+		return err
+	}
+	if config != nil && config.Scope == CounterScopePlayer {
+		return fmt.Errorf("计数器[%s]为玩家计数器，不支持修改点位值", key)
+	}
+
 	o := orm.NewOrm()
 
 	counterData := &CounterData{}
@@ -278,8 +469,8 @@ func UpdateCounterValue(appId, key, location string, value int64) error {
 
 	// 检查记录是否存在
 	var existingId int64
-	checkSQL := fmt.Sprintf("SELECT id FROM %s WHERE counter_key = ? AND location = ?", tableName)
-	err := o.Raw(checkSQL, key, location).QueryRow(&existingId)
+	checkSQL := fmt.Sprintf("SELECT id FROM %s WHERE counter_key = ? AND player_id = '' AND location = ?", tableName)
+	err = o.Raw(checkSQL, key, location).QueryRow(&existingId)
 
 	if err == orm.ErrNoRows {
 		// 插入新记录
@@ -292,12 +483,26 @@ func UpdateCounterValue(appId, key, location string, value int64) error {
 		// 更新现有记录
 		updateSQL := fmt.Sprintf(`
 			UPDATE %s SET value = ?, updated_at = NOW() 
-			WHERE counter_key = ? AND location = ?
+			WHERE counter_key = ? AND player_id = '' AND location = ?
 		`, tableName)
 		_, err = o.Raw(updateSQL, value, key, location).Exec()
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	// 同步游戏服当前周期的计数器缓存，避免被缓存中的旧值覆盖（已结束周期的缓存保留周期最终值，不能修改）
+	if RedisClient != nil && config != nil {
+		ctx := context.Background()
+		period := getCounterPeriod(config, time.Now())
+		cacheKey := counterRedisKeyPrefix + appId + ":" + period.Id + ":" + key
+		member, _ := json.Marshal(counterDirtyMember{AppId: appId, CounterKey: key, Period: period.Id, End: period.endUnix()})
+		if err := counterCacheSetScript.Run(ctx, RedisClient, []string{cacheKey, counterDirtyKey}, location, value, string(member)).Err(); err != nil {
+			logs.Warning("同步计数器缓存失败: key=%s, err=%v", cacheKey, err)
+		}
+	}
+
+	return nil
 }
 
 // GetCounterValue 获取计数器值
@@ -308,7 +513,7 @@ func GetCounterValue(appId, key, location string) (int64, error) {
 	tableName := counterData.GetTableName(appId)
 
 	var value int64
-	querySQL := fmt.Sprintf("SELECT value FROM %s WHERE counter_key = ? AND location = ?", tableName)
+	querySQL := fmt.Sprintf("SELECT value FROM %s WHERE counter_key = ? AND player_id = '' AND location = ?", tableName)
 	err := o.Raw(querySQL, key, location).QueryRow(&value)
 
 	if err == orm.ErrNoRows {
@@ -326,7 +531,7 @@ func GetCounterAllLocations(appId, key string) (map[string]interface{}, error) {
 	tableName := counterData.GetTableName(appId)
 
 	var results []orm.Params
-	querySQL := fmt.Sprintf("SELECT location, value FROM %s WHERE counter_key = ?", tableName)
+	querySQL := fmt.Sprintf("SELECT location, value FROM %s WHERE counter_key = ? AND player_id = ''", tableName)
 	_, err := o.Raw(querySQL, key).Values(&results)
 
 	if err != nil {
@@ -363,12 +568,14 @@ func createCounterTable(appId string) error {
 			CREATE TABLE %s (
 				id BIGINT AUTO_INCREMENT PRIMARY KEY,
 				counter_key VARCHAR(100) NOT NULL,
+				player_id VARCHAR(100) NOT NULL DEFAULT '',
 				location VARCHAR(100) DEFAULT 'default',
 				value BIGINT DEFAULT 0,
 				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-				UNIQUE KEY uk_key_location (counter_key, location),
-				INDEX idx_counter_key (counter_key)
+				UNIQUE KEY uk_key_location (counter_key, player_id, location),
+				INDEX idx_counter_key (counter_key),
+				INDEX idx_player (player_id)
 			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4
 		`, tableName)
 
@@ -379,6 +586,51 @@ func createCounterTable(appId string) error {
 	return nil
 }
 
+// findCounterCacheKeys 查找计数器在Redis中各个周期的缓存键
+func findCounterCacheKeys(ctx context.Context, appId, key string) []string {
+	if RedisClient == nil {
+		return nil
+	}
+
+	prefix := counterRedisKeyPrefix + appId + ":"
+	var keys []string
+	iter := RedisClient.Scan(ctx, 0, prefix+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		// 键格式为{prefix}{period}:{key}，周期标识中不含冒号
+		parts := strings.SplitN(strings.TrimPrefix(iter.Val(), prefix), ":", 2)
+		if len(parts) == 2 && parts[1] == key {
+			keys = append(keys, iter.Val())
+		}
+	}
+	if err := iter.Err(); err != nil {
+		logs.Warning("查询计数器缓存失败: appId=%s, key=%s, err=%v", appId, key, err)
+	}
+	return keys
+}
+
+// clearCounterCache 删除计数器在Redis中的所有缓存（包括玩家计数器）
+func clearCounterCache(appId, key string) {
+	ctx := context.Background()
+	for _, cacheKey := range findCounterCacheKeys(ctx, appId, key) {
+		loadedKey := counterLoadedKeyPrefix + strings.TrimPrefix(cacheKey, counterRedisKeyPrefix)
+		RedisClient.Del(ctx, cacheKey, loadedKey)
+	}
+
+	if RedisClient == nil {
+		return
+	}
+	// 玩家计数器键格式为counter_player:{appId}:{period}:{playerId}:{key}
+	prefix := counterPlayerRedisKeyPrefix + appId + ":"
+	iter := RedisClient.Scan(ctx, 0, prefix+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		parts := strings.SplitN(strings.TrimPrefix(iter.Val(), prefix), ":", 3)
+		if len(parts) == 3 && parts[2] == key {
+			loadedKey := counterPlayerLoadedKeyPrefix + strings.TrimPrefix(iter.Val(), counterPlayerRedisKeyPrefix)
+			RedisClient.Del(ctx, iter.Val(), loadedKey)
+		}
+	}
+}
+
 // calculateNextResetTime 计算下次重置时间
 func calculateNextResetTime(resetType string, resetValue int) time.Time {
 	now := time.Now()
@@ -408,6 +660,96 @@ func calculateNextResetTime(resetType string, resetValue int) time.Time {
 	}
 }
 
+// 计数器历史类型
+const (
+	CounterHistorySample = "sample" // 定时采样
+	CounterHistoryReset  = "reset"  // 重置前的值
+)
+
+// counterHistoryMaxRows 单次查询历史数据的最大行数
+const counterHistoryMaxRows = 10000
+
+// CounterHistoryPoint 计数器历史数据点
+type CounterHistoryPoint struct {
+	Time  string `json:"time"`
+	Value int64  `json:"value"`
+}
+
+// CounterResetRecord 计数器重置前的值
+type CounterResetRecord struct {
+	Location string `json:"location"`
+	Period   string `json:"period"`
+	Value    int64  `json:"value"`
+	Time     string `json:"time"`
+}
+
+// getCounterHistoryTableName 获取计数器历史表名
+func getCounterHistoryTableName(appId string) string {
+	return fmt.Sprintf("counter_history_%s", utils.CleanAppId(appId))
+}
+
+// GetCounterHistory 获取计数器在时间区间内的历史数据
+// interval为day时每个点位每天取最后一次采样，否则返回每次采样；重置前的值单独返回
+func GetCounterHistory(appId, key, location string, startTime, endTime time.Time, interval string) (map[string][]CounterHistoryPoint, []CounterResetRecord, error) {
+	o := orm.NewOrm()
+
+	sql := fmt.Sprintf(`SELECT location, period, value, sample_type, sampled_at FROM %s
+		WHERE counter_key = ? AND sampled_at >= ? AND sampled_at <= ?`, getCounterHistoryTableName(appId))
+	params := []interface{}{key, startTime, endTime}
+	if location != "" {
+		sql += ` AND location = ?`
+		params = append(params, location)
+	}
+	sql += fmt.Sprintf(` ORDER BY sampled_at ASC, id ASC LIMIT %d`, counterHistoryMaxRows)
+
+	var rows []orm.Params
+	if _, err := o.Raw(sql, params...).Values(&rows); err != nil {
+		return nil, nil, err
+	}
+
+	series := make(map[string][]CounterHistoryPoint)
+	resets := make([]CounterResetRecord, 0)
+	for _, row := range rows {
+		rowLocation, _ := row["location"].(string)
+		sampledAt, _ := row["sampled_at"].(string)
+		value, _ := strconv.ParseInt(fmt.Sprint(row["value"]), 10, 64)
+
+		if row["sample_type"] == CounterHistoryReset {
+			period, _ := row["period"].(string)
+			resets = append(resets, CounterResetRecord{Location: rowLocation, Period: period, Value: value, Time: sampledAt})
+			continue
+		}
+
+		points := series[rowLocation]
+		if interval == "day" && len(sampledAt) >= 10 {
+			// 同一天内后面的采样覆盖前面的采样
+			day := sampledAt[:10]
+			if n := len(points); n > 0 && points[n-1].Time == day {
+				points[n-1].Value = value
+				continue
+			}
+			sampledAt = day
+		}
+		series[rowLocation] = append(points, CounterHistoryPoint{Time: sampledAt, Value: value})
+	}
+
+	return series, resets, nil
+}
+
+// ArchiveCounterValues 重置前把计数器当前各点位的值写入历史表
+func ArchiveCounterValues(appId, key string) error {
+	o := orm.NewOrm()
+	counterData := &CounterData{}
+
+	sql := fmt.Sprintf(`
+		INSERT IGNORE INTO %s (counter_key, location, period, value, sample_type, sampled_at)
+		SELECT counter_key, location, '', value, ?, NOW() FROM %s WHERE counter_key = ? AND player_id = ''
+	`, getCounterHistoryTableName(appId), counterData.GetTableName(appId))
+
+	_, err := o.Raw(sql, CounterHistoryReset, key).Exec()
+	return err
+}
+
 // GetCounterCount 获取计数器数量统计
 func GetCounterCount(appId string) (int64, error) {
 	o := orm.NewOrm()
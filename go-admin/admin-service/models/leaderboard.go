@@ -28,8 +28,11 @@ type LeaderboardConfig struct {
 	ResetType        string    `orm:"size(50);default(permanent)" json:"resetType"` // permanent, daily, weekly, monthly, custom
 	ResetValue       int       `orm:"default(0);column(reset_value)" json:"resetValue"`
 	ResetTime        time.Time `orm:"null;type(datetime);column(reset_time)" json:"resetTime"`
-	UpdateStrategy   int       `orm:"default(0);column(update_strategy)" json:"updateStrategy"` // 0=最高分, 1=最新分, 2=累计分
-	Sort             int       `orm:"default(1)" json:"sort"`                                   // 0=升序, 1=降序
+	UpdateStrategy   int       `orm:"default(0);column(update_strategy)" json:"updateStrategy"`        // 0=最高分, 1=最新分, 2=累计分
+	Sort             int       `orm:"default(1)" json:"sort"`                                          // 0=升序, 1=降序
+	RewardTiers      string    `orm:"type(text);null;column(reward_tiers)" json:"rewardTiers"`         // 赛季奖励档位（JSON数组）
+	ValidationRules  string    `orm:"type(text);null;column(validation_rules)" json:"validationRules"` // 分数校验规则（JSON对象）
+	LeagueConfig     string    `orm:"type(text);null;column(league_config)" json:"leagueConfig"`       // 联赛分组配置（JSON对象），为空表示普通排行榜
 	ScoreCount       int       `orm:"default(0);column(score_count)" json:"scoreCount"`
 	ParticipantCount int       `orm:"default(0);column(participant_count)" json:"participantCount"`
 	LastResetTime    time.Time `orm:"null;type(datetime);column(last_reset_time)" json:"lastResetTime"`
@@ -47,6 +50,113 @@ type Leaderboard struct {
 	UpdatedAt string `orm:"auto_now;type(datetime);column(updated_at)" json:"updatedAt"`
 }
 
+// LeaderboardRewardTier 排行榜赛季奖励档位（重置时按名次自动发放奖励邮件）
+// 标题和内容支持占位符: {name} 排行榜名称, {season} 赛季号, {rank} 名次
+type LeaderboardRewardTier struct {
+	MinRank int             `json:"minRank"`
+	MaxRank int             `json:"maxRank"`
+	Rewards json.RawMessage `json:"rewards"` // 奖励列表（JSON数组）
+	Title   string          `json:"title,omitempty"`
+	Content string          `json:"content,omitempty"`
+}
+
+// EncodeRewardTiers 校验奖励档位并序列化为存储格式
+func EncodeRewardTiers(tiers []LeaderboardRewardTier) (string, error) {
+	if len(tiers) == 0 {
+		return "", nil
+	}
+
+	for i, tier := range tiers {
+		if tier.MinRank < 1 || tier.MaxRank < tier.MinRank {
+			return "", fmt.Errorf("第%d个奖励档位名次范围无效", i+1)
+		}
+		var rewards []interface{}
+		if err := json.Unmarshal(tier.Rewards, &rewards); err != nil || len(rewards) == 0 {
+			return "", fmt.Errorf("第%d个奖励档位rewards必须为非空JSON数组", i+1)
+		}
+		for j := 0; j < i; j++ {
+			if tier.MinRank <= tiers[j].MaxRank && tier.MaxRank >= tiers[j].MinRank {
+				return "", fmt.Errorf("第%d个奖励档位与第%d个档位名次重叠", i+1, j+1)
+			}
+		}
+	}
+
+	data, err := json.Marshal(tiers)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// LeaderboardValidationRules 排行榜分数校验规则（游戏服提交分数时强制执行）
+type LeaderboardValidationRules struct {
+	MinScore            *int64 `json:"minScore,omitempty"`            // 允许的最小分数
+	MaxScore            *int64 `json:"maxScore,omitempty"`            // 允许的最大分数
+	MaxIncrease         int64  `json:"maxIncrease,omitempty"`         // 单次提交最大提升幅度（累计分排行榜为单次最大增量），0表示不限制
+	MaxSubmitsPerMinute int    `json:"maxSubmitsPerMinute,omitempty"` // 每个玩家每分钟最大提交次数，0表示不限制
+	RequireProof        bool   `json:"requireProof,omitempty"`        // 是否要求extraData中携带签名proof
+	ProofSecret         string `json:"proofSecret,omitempty"`         // proof签名密钥（HMAC-SHA256）
+	ProofMaxAge         int64  `json:"proofMaxAge,omitempty"`         // proof有效期（秒），0表示使用默认值300
+}
+
+// EncodeValidationRules 校验分数规则并序列化为存储格式
+func EncodeValidationRules(rules *LeaderboardValidationRules) (string, error) {
+	if rules == nil {
+		return "", nil
+	}
+
+	if rules.MinScore != nil && rules.MaxScore != nil && *rules.MinScore > *rules.MaxScore {
+		return "", fmt.Errorf("minScore不能大于maxScore")
+	}
+	if rules.MaxIncrease < 0 || rules.MaxSubmitsPerMinute < 0 || rules.ProofMaxAge < 0 {
+		return "", fmt.Errorf("maxIncrease、maxSubmitsPerMinute和proofMaxAge不能为负数")
+	}
+	if rules.RequireProof && rules.ProofSecret == "" {
+		return "", fmt.Errorf("启用requireProof时必须设置proofSecret")
+	}
+
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// LeaderboardLeagueConfig 联赛分组配置（玩家按段位分组排名，赛季结束时晋级/降级）
+type LeaderboardLeagueConfig struct {
+	BucketSize    int      `json:"bucketSize"`          // 每个分组的人数
+	TierCount     int      `json:"tierCount"`           // 段位数量（1为最低段位）
+	PromoteCount  int      `json:"promoteCount"`        // 每组晋级人数
+	RelegateCount int      `json:"relegateCount"`       // 每组降级人数
+	TierNames     []string `json:"tierNames,omitempty"` // 段位名称（按段位从低到高）
+}
+
+// EncodeLeagueConfig 校验联赛分组配置并序列化为存储格式
+func EncodeLeagueConfig(league *LeaderboardLeagueConfig) (string, error) {
+	if league == nil || league.BucketSize == 0 {
+		return "", nil
+	}
+
+	if league.BucketSize < 2 || league.TierCount < 1 {
+		return "", fmt.Errorf("bucketSize不能小于2，tierCount不能小于1")
+	}
+	if league.PromoteCount < 0 || league.RelegateCount < 0 {
+		return "", fmt.Errorf("promoteCount和relegateCount不能为负数")
+	}
+	if league.PromoteCount+league.RelegateCount > league.BucketSize {
+		return "", fmt.Errorf("晋级和降级人数之和不能超过bucketSize")
+	}
+	if len(league.TierNames) > 0 && len(league.TierNames) != league.TierCount {
+		return "", fmt.Errorf("tierNames数量必须与tierCount一致")
+	}
+
+	data, err := json.Marshal(league)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 // GetLeaderboardCount 获取排行榜数量统计
 func GetLeaderboardCount(appId string) (int64, error) {
 	o := orm.NewOrm()
@@ -211,7 +321,7 @@ func GetLeaderboardData(appId, leaderboardType string, page, pageSize int) ([]ma
 	// 获取分页数据
 	offset := (page - 1) * pageSize
 	querySQL := fmt.Sprintf(`
-		SELECT id, type, player_id, score, extra_data as extraData, created_at as createdAt, updated_at as updatedAt 
+		SELECT id, type, partition_key as partitionKey, player_id, score, extra_data as extraData, created_at as createdAt, updated_at as updatedAt 
 		FROM %s 
 		WHERE type = ? 
 		ORDER BY score DESC, created_at ASC 
@@ -313,8 +423,8 @@ func GetLeaderboardData(appId, leaderboardType string, page, pageSize int) ([]ma
 	return result, total, nil
 }
 
-// UpdateLeaderboardScore 更新排行榜分数
-func UpdateLeaderboardScore(appId, leaderboardType, playerId string, score int64) error {
+// UpdateLeaderboardScore 更新玩家在指定分区的排行榜分数（partitionKey为空表示默认分区）
+func UpdateLeaderboardScore(appId, leaderboardType, partitionKey, playerId string, score int64) error {
 	o := orm.NewOrm()
 
 	// 使用动态表
@@ -323,45 +433,55 @@ func UpdateLeaderboardScore(appId, leaderboardType, playerId string, score int64
 
 	// 检查记录是否存在
 	var existingId int64
-	checkSQL := fmt.Sprintf("SELECT id FROM %s WHERE type = ? AND player_id = ?", tableName)
-	err := o.Raw(checkSQL, leaderboardType, playerId).QueryRow(&existingId)
+	checkSQL := fmt.Sprintf("SELECT id FROM %s WHERE type = ? AND partition_key = ? AND player_id = ?", tableName)
+	err := o.Raw(checkSQL, leaderboardType, partitionKey, playerId).QueryRow(&existingId)
 
 	if err == orm.ErrNoRows {
 		// 插入新记录
 		insertSQL := fmt.Sprintf(`
-			INSERT INTO %s (type, player_id, score, created_at, updated_at) 
-			VALUES (?, ?, ?, NOW(), NOW())
+			INSERT INTO %s (type, partition_key, player_id, score, created_at, updated_at) 
+			VALUES (?, ?, ?, ?, NOW(), NOW())
 		`, tableName)
-		_, err = o.Raw(insertSQL, leaderboardType, playerId, score).Exec()
+		_, err = o.Raw(insertSQL, leaderboardType, partitionKey, playerId, score).Exec()
 	} else if err == nil {
 		// 更新现有记录
 		updateSQL := fmt.Sprintf(`
 			UPDATE %s SET score = ?, updated_at = NOW() 
-			WHERE type = ? AND player_id = ?
+			WHERE id = ?
 		`, tableName)
-		_, err = o.Raw(updateSQL, score, leaderboardType, playerId).Exec()
+		_, err = o.Raw(updateSQL, score, existingId).Exec()
 	}
 
-	// 如果数据库操作成功，同步到Redis
-	if err == nil {
-		syncLeaderboardToRedis(appId, leaderboardType, playerId, score, "")
+	if err != nil {
+		return err
 	}
 
-	return err
+	// 同步到Redis，按记录的更新时间计算同分排名用的复合分数
+	var updatedAt string
+	timeSQL := fmt.Sprintf("SELECT updated_at FROM %s WHERE type = ? AND partition_key = ? AND player_id = ?", tableName)
+	if err := o.Raw(timeSQL, leaderboardType, partitionKey, playerId).QueryRow(&updatedAt); err != nil {
+		return err
+	}
+	ascending := isLeaderboardAscending(appId, leaderboardType)
+	syncLeaderboardToRedis(appId, leaderboardType, partitionKey, playerId, score, parseLeaderboardTime(updatedAt), ascending, "")
+
+	return nil
 }
 
-// DeleteLeaderboardScore 删除排行榜分数
-func DeleteLeaderboardScore(appId, leaderboardType, playerId string) error {
+// DeleteLeaderboardScore 删除玩家在指定分区的排行榜分数（partitionKey为空表示默认分区），同时移除Redis中的记录
+func DeleteLeaderboardScore(appId, leaderboardType, partitionKey, playerId string) error {
 	o := orm.NewOrm()
 
 	// 使用动态表
 	leaderboardData := &Leaderboard{}
 	tableName := leaderboardData.GetTableName(appId)
 
-	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE type = ? AND player_id = ?", tableName)
-	_, err := o.Raw(deleteSQL, leaderboardType, playerId).Exec()
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE type = ? AND partition_key = ? AND player_id = ?", tableName)
+	if _, err := o.Raw(deleteSQL, leaderboardType, partitionKey, playerId).Exec(); err != nil {
+		return err
+	}
 
-	return err
+	return removeLeaderboardFromRedis(appId, leaderboardType, partitionKey, playerId)
 }
 
 // CommitLeaderboardScore 提交排行榜分数
@@ -476,6 +596,174 @@ func QueryLeaderboardScore(appId, leaderboardType, playerId string) (int64, int,
 	return userScore, int(rank) + 1, nil
 }
 
+// getLeaderboardHistoryTableName 获取排行榜赛季历史表名
+func getLeaderboardHistoryTableName(appId string) string {
+	return fmt.Sprintf("leaderboard_history_%s", utils.CleanAppId(appId))
+}
+
+// GetLeaderboardSeasons 获取排行榜已归档的赛季列表
+func GetLeaderboardSeasons(appId, leaderboardType string, page, pageSize int) ([]orm.Params, int64, error) {
+	o := orm.NewOrm()
+	tableName := getLeaderboardHistoryTableName(appId)
+
+	var total int64
+	countSQL := fmt.Sprintf("SELECT COUNT(DISTINCT season) FROM %s WHERE type = ?", tableName)
+	err := o.Raw(countSQL, leaderboardType).QueryRow(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	querySQL := fmt.Sprintf(`
+		SELECT season, MIN(season_start) as seasonStart, MAX(season_end) as seasonEnd,
+			COUNT(*) as playerCount, MAX(score) as topScore, MIN(created_at) as archivedAt
+		FROM %s
+		WHERE type = ?
+		GROUP BY season
+		ORDER BY season DESC
+		LIMIT ? OFFSET ?
+	`, tableName)
+
+	var seasons []orm.Params
+	_, err = o.Raw(querySQL, leaderboardType, pageSize, offset).Values(&seasons)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return seasons, total, nil
+}
+
+// GetLeaderboardSeasonData 获取指定赛季的归档排名（可按玩家ID筛选）
+func GetLeaderboardSeasonData(appId, leaderboardType string, season int, playerId string, page, pageSize int) ([]orm.Params, int64, error) {
+	o := orm.NewOrm()
+	tableName := getLeaderboardHistoryTableName(appId)
+	userTableName := fmt.Sprintf("user_%s", utils.CleanAppId(appId))
+
+	whereClause := "WHERE h.type = ? AND h.season = ?"
+	args := []interface{}{leaderboardType, season}
+	if playerId != "" {
+		whereClause += " AND h.player_id = ?"
+		args = append(args, playerId)
+	}
+
+	var total int64
+	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s h %s", tableName, whereClause)
+	err := o.Raw(countSQL, args...).QueryRow(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	querySQL := fmt.Sprintf(`
+		SELECT h.season, h.partition_key as partitionKey, h.ranking as `+"`rank`"+`, h.player_id as playerId, h.score, h.extra_data as extraData,
+			h.season_start as seasonStart, h.season_end as seasonEnd, u.nickname, u.avatar
+		FROM %s h
+		LEFT JOIN %s u ON u.player_id = h.player_id
+		%s
+		ORDER BY h.partition_key ASC, h.ranking ASC
+		LIMIT ? OFFSET ?
+	`, tableName, userTableName, whereClause)
+
+	var results []orm.Params
+	_, err = o.Raw(querySQL, append(args, pageSize, offset)...).Values(&results)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
+// getLeaderboardSuspiciousTableName 获取排行榜可疑分数表名
+func getLeaderboardSuspiciousTableName(appId string) string {
+	return fmt.Sprintf("leaderboard_suspicious_%s", utils.CleanAppId(appId))
+}
+
+// GetSuspiciousScores 获取可疑分数记录（status<0表示不筛选状态）
+func GetSuspiciousScores(appId, leaderboardType, playerId string, status, page, pageSize int) ([]orm.Params, int64, error) {
+	o := orm.NewOrm()
+	tableName := getLeaderboardSuspiciousTableName(appId)
+
+	whereClause := "WHERE 1=1"
+	args := []interface{}{}
+	if leaderboardType != "" {
+		whereClause += " AND type = ?"
+		args = append(args, leaderboardType)
+	}
+	if playerId != "" {
+		whereClause += " AND player_id = ?"
+		args = append(args, playerId)
+	}
+	if status >= 0 {
+		whereClause += " AND status = ?"
+		args = append(args, status)
+	}
+
+	var total int64
+	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s %s", tableName, whereClause)
+	err := o.Raw(countSQL, args...).QueryRow(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	querySQL := fmt.Sprintf(`
+		SELECT id, type, player_id as playerId, score, old_score as oldScore, extra_data as extraData,
+			rule, reason, status, reviewed_by as reviewedBy, reviewed_at as reviewedAt, remark, created_at as createdAt
+		FROM %s %s
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, tableName, whereClause)
+
+	var results []orm.Params
+	_, err = o.Raw(querySQL, append(args, pageSize, offset)...).Values(&results)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
+// ReviewSuspiciousScore 审核可疑分数，确认作弊且removeScore为true时同时移除玩家当前排行榜成绩
+func ReviewSuspiciousScore(appId string, id int64, status int, remark, reviewer string, removeScore bool) error {
+	o := orm.NewOrm()
+	tableName := getLeaderboardSuspiciousTableName(appId)
+
+	var records []orm.Params
+	_, err := o.Raw(fmt.Sprintf("SELECT type, player_id FROM %s WHERE id = ?", tableName), id).Values(&records)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("可疑分数记录不存在")
+	}
+
+	updateSQL := fmt.Sprintf("UPDATE %s SET status = ?, remark = ?, reviewed_by = ?, reviewed_at = NOW() WHERE id = ?", tableName)
+	_, err = o.Raw(updateSQL, status, remark, reviewer, id).Exec()
+	if err != nil {
+		return err
+	}
+
+	if status == 1 && removeScore {
+		leaderboardType, _ := records[0]["type"].(string)
+		playerId, _ := records[0]["player_id"].(string)
+
+		// 可疑记录不区分分区，移除玩家在该排行榜所有分区（含联赛分组）的成绩
+		var partitions []orm.Params
+		partitionSQL := fmt.Sprintf("SELECT partition_key FROM %s WHERE type = ? AND player_id = ?", (&Leaderboard{}).GetTableName(appId))
+		if _, err := o.Raw(partitionSQL, leaderboardType, playerId).Values(&partitions); err != nil {
+			return fmt.Errorf("查询玩家成绩失败: %v", err)
+		}
+		for _, row := range partitions {
+			partitionKey, _ := row["partition_key"].(string)
+			if err := DeleteLeaderboardScore(appId, leaderboardType, partitionKey, playerId); err != nil {
+				return fmt.Errorf("移除玩家成绩失败: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
 // FixLeaderboardUserInfo 修复排行榜用户信息（暂时保留兼容性）
 func FixLeaderboardUserInfo(appId, leaderboardType string) (int64, error) {
 	logs.Info("排行榜已迁移到动态表，用户信息修复功能已不需要")
@@ -678,13 +966,67 @@ func UpdateLeaderboardScoreWithExtra(appId, leaderboardType, playerId string, sc
 // Redis缓存同步功能 - 与game-service保持一致
 // =============================================================================
 
-// getLeaderboardRedisKey 获取排行榜Redis键名（与game-service保持一致）
-func getLeaderboardRedisKey(appId, leaderboardType string) string {
-	return fmt.Sprintf("leaderboard:%s:%s", appId, leaderboardType)
+// getLeaderboardRedisKey 获取排行榜Redis键名（与game-service保持一致，partition为空时使用默认分区）
+func getLeaderboardRedisKey(appId, leaderboardType, partition string) string {
+	if partition == "" {
+		return fmt.Sprintf("leaderboard:%s:%s", appId, leaderboardType)
+	}
+	return fmt.Sprintf("leaderboard:%s:%s:p:%s", appId, leaderboardType, partition)
+}
+
+// leaderboardTiebreakEpoch 同分排名的时间基准（与game-service保持一致）
+var leaderboardTiebreakEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	// tiebreakScale 复合分数中时间部分的缩放系数（秒级精度）
+	tiebreakScale = 1e9
+	// maxTiebreakScore 分数绝对值达到该值后float64无法再保留时间精度，不再附加时间部分
+	maxTiebreakScore = 1 << 21
+)
+
+// encodeRedisScore 计算Redis有序集合中的复合分数（与game-service保持一致）
+// 整数部分为真实分数，小数部分为达成时间，同分时先达成的玩家排名靠前
+func encodeRedisScore(score int64, reachedAt time.Time, ascending bool) float64 {
+	if score >= maxTiebreakScore || score <= -maxTiebreakScore {
+		return float64(score)
+	}
+
+	elapsed := int64(reachedAt.Sub(leaderboardTiebreakEpoch) / time.Second)
+	if elapsed < 0 {
+		elapsed = 0
+	} else if elapsed > tiebreakScale-1 {
+		elapsed = tiebreakScale - 1
+	}
+
+	if ascending {
+		return float64(score) + float64(elapsed)/tiebreakScale
+	}
+	return float64(score) + float64(tiebreakScale-1-elapsed)/tiebreakScale
+}
+
+// isLeaderboardAscending 排行榜是否为升序（分数越低排名越靠前），配置不存在时按降序处理
+func isLeaderboardAscending(appId, leaderboardType string) bool {
+	var config LeaderboardConfig
+	err := orm.NewOrm().QueryTable("leaderboard_config").
+		Filter("app_id", appId).
+		Filter("leaderboard_type", leaderboardType).
+		One(&config, "ScoreType")
+	return err == nil && config.ScoreType == "lower_better"
 }
 
-// syncLeaderboardToRedis 同步单个排行榜记录到Redis
-func syncLeaderboardToRedis(appId, leaderboardType, playerId string, score int64, extraData string) error {
+// parseLeaderboardTime 解析数据库返回的时间字符串（与game-service的paramToTime一致，解析失败时返回零值）
+func parseLeaderboardTime(value string) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// syncLeaderboardToRedis 同步单个排行榜记录到Redis，reachedAt为分数达成时间（用于同分排名）
+func syncLeaderboardToRedis(appId, leaderboardType, partition, playerId string, score int64, reachedAt time.Time, ascending bool, extraData string) error {
 	if RedisClient == nil {
 		logs.Warning("Redis客户端未初始化，跳过缓存同步")
 		return nil
@@ -693,11 +1035,11 @@ func syncLeaderboardToRedis(appId, leaderboardType, playerId string, score int64
 	ctx := context.Background()
 
 	// 排行榜有序集合的key（用于存储分数排名）
-	scoreKey := getLeaderboardRedisKey(appId, leaderboardType)
+	scoreKey := getLeaderboardRedisKey(appId, leaderboardType, partition)
 
-	// 1. 更新有序集合中的分数（使用用户ID作为member，分数作为score）
+	// 1. 更新有序集合中的分数（使用用户ID作为member，复合分数作为score）
 	member := &redis.Z{
-		Score:  float64(score),
+		Score:  encodeRedisScore(score, reachedAt, ascending),
 		Member: playerId,
 	}
 
@@ -722,7 +1064,7 @@ func syncLeaderboardToRedis(appId, leaderboardType, playerId string, score int64
 		}
 
 		// 用户详情哈希表的key（用于存储额外数据）
-		detailKey := getLeaderboardRedisKey(appId, leaderboardType) + ":details"
+		detailKey := scoreKey + ":details"
 
 		err = RedisClient.HSet(ctx, detailKey, playerId, string(detailsJSON)).Err()
 		if err != nil {
@@ -735,8 +1077,8 @@ func syncLeaderboardToRedis(appId, leaderboardType, playerId string, score int64
 	return nil
 }
 
-// removeLeaderboardFromRedis 从Redis中删除排行榜记录
-func removeLeaderboardFromRedis(appId, leaderboardType, playerId string) error {
+// removeLeaderboardFromRedis 从Redis中删除玩家在指定分区的排行榜记录
+func removeLeaderboardFromRedis(appId, leaderboardType, partition, playerId string) error {
 	if RedisClient == nil {
 		logs.Warning("Redis客户端未初始化，跳过缓存清理")
 		return nil
@@ -745,9 +1087,9 @@ func removeLeaderboardFromRedis(appId, leaderboardType, playerId string) error {
 	ctx := context.Background()
 
 	// 排行榜有序集合的key
-	scoreKey := getLeaderboardRedisKey(appId, leaderboardType)
+	scoreKey := getLeaderboardRedisKey(appId, leaderboardType, partition)
 	// 用户详情哈希表的key
-	detailKey := getLeaderboardRedisKey(appId, leaderboardType) + ":details"
+	detailKey := scoreKey + ":details"
 
 	// 1. 从有序集合中移除用户
 	err := RedisClient.ZRem(ctx, scoreKey, playerId).Err()
@@ -763,7 +1105,7 @@ func removeLeaderboardFromRedis(appId, leaderboardType, playerId string) error {
 		return err
 	}
 
-	logs.Info("从Redis中删除排行榜数据: %s:%s:%s", appId, leaderboardType, playerId)
+	logs.Info("从Redis中删除排行榜数据: %s:%s:%s:%s", appId, leaderboardType, partition, playerId)
 	return nil
 }
 
@@ -777,9 +1119,9 @@ func clearLeaderboardFromRedis(appId, leaderboardType string) error {
 	ctx := context.Background()
 
 	// 排行榜有序集合的key
-	scoreKey := getLeaderboardRedisKey(appId, leaderboardType)
+	scoreKey := getLeaderboardRedisKey(appId, leaderboardType, "")
 	// 用户详情哈希表的key
-	detailKey := getLeaderboardRedisKey(appId, leaderboardType) + ":details"
+	detailKey := scoreKey + ":details"
 
 	// 删除排行榜有序集合
 	err := RedisClient.Del(ctx, scoreKey).Err()
@@ -795,6 +1137,19 @@ func clearLeaderboardFromRedis(appId, leaderboardType string) error {
 		return err
 	}
 
+	// 删除各分区（含联赛分组）的有序集合和详情哈希表
+	iter := RedisClient.Scan(ctx, 0, scoreKey+":p:*", 200).Iterator()
+	for iter.Next(ctx) {
+		if err := RedisClient.Del(ctx, iter.Val()).Err(); err != nil {
+			logs.Error("Redis Del分区缓存失败: %v", err)
+			return err
+		}
+	}
+	if err := iter.Err(); err != nil {
+		logs.Error("Redis Scan分区缓存失败: %v", err)
+		return err
+	}
+
 	logs.Info("清空Redis排行榜缓存: %s:%s", appId, leaderboardType)
 	return nil
 }
@@ -819,7 +1174,7 @@ func refreshLeaderboardToRedis(appId, leaderboardType string) error {
 
 	// 查询所有排行榜数据
 	querySQL := fmt.Sprintf(`
-		SELECT player_id, score, extra_data 
+		SELECT partition_key, player_id, score, extra_data, updated_at 
 		FROM %s 
 		WHERE type = ? 
 		ORDER BY score DESC
@@ -833,6 +1188,7 @@ func refreshLeaderboardToRedis(appId, leaderboardType string) error {
 	}
 
 	// 批量同步到Redis
+	ascending := isLeaderboardAscending(appId, leaderboardType)
 	for _, row := range results {
 		playerId := fmt.Sprintf("%v", row["player_id"])
 		score := int64(0)
@@ -849,7 +1205,9 @@ func refreshLeaderboardToRedis(appId, leaderboardType string) error {
 			extraData = ed
 		}
 
-		err = syncLeaderboardToRedis(appId, leaderboardType, playerId, score, extraData)
+		partitionKey, _ := row["partition_key"].(string)
+		updatedAt, _ := row["updated_at"].(string)
+		err = syncLeaderboardToRedis(appId, leaderboardType, partitionKey, playerId, score, parseLeaderboardTime(updatedAt), ascending, extraData)
 		if err != nil {
 			logs.Error("同步排行榜记录到Redis失败: %v", err)
 			// 继续处理其他记录，不中断整个过程
@@ -859,3 +1217,59 @@ func refreshLeaderboardToRedis(appId, leaderboardType string) error {
 	logs.Info("刷新排行榜到Redis完成: %s:%s (%d条记录)", appId, leaderboardType, len(results))
 	return nil
 }
+
+// 排行榜对账相关Redis键（与游戏服约定一致）
+const (
+	leaderboardReconcileQueueKey  = "leaderboard_reconcile:queue"
+	leaderboardReconcileReportKey = "leaderboard_reconcile:report:"
+	leaderboardReconcileLastKey   = "leaderboard_reconcile:last"
+)
+
+// RequestLeaderboardReconcile 提交排行榜Redis/MySQL对账请求，由游戏服后台任务执行，返回请求ID
+func RequestLeaderboardReconcile(appId, operator string) (string, error) {
+	if RedisClient == nil {
+		return "", fmt.Errorf("Redis未启用")
+	}
+
+	requestId := fmt.Sprintf("%d%s", time.Now().UnixNano()/int64(time.Millisecond), utils.GenerateRandomString(6))
+	payload, err := json.Marshal(map[string]interface{}{
+		"requestId": requestId,
+		"appId":     appId,
+		"operator":  operator,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := RedisClient.LPush(context.Background(), leaderboardReconcileQueueKey, string(payload)).Err(); err != nil {
+		return "", err
+	}
+
+	return requestId, nil
+}
+
+// GetLeaderboardReconcileReport 获取对账报告，requestId为空时返回最近一次定时对账报告，报告未生成时返回nil
+func GetLeaderboardReconcileReport(requestId string) (map[string]interface{}, error) {
+	if RedisClient == nil {
+		return nil, fmt.Errorf("Redis未启用")
+	}
+
+	key := leaderboardReconcileLastKey
+	if requestId != "" {
+		key = leaderboardReconcileReportKey + requestId
+	}
+
+	data, err := RedisClient.Get(context.Background(), key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var report map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,261 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"admin-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// 存档历史来源（与游戏服务约定一致）
+const (
+	UserDataSourceAdmin   = "admin"   // 管理后台修改
+	UserDataSourceRestore = "restore" // 管理后台回滚
+	UserDataSourceMerge   = "merge"   // 合并账号时使用被合并玩家的存档
+)
+
+// ErrUserDataVersionNotFound 存档历史版本不存在
+var ErrUserDataVersionNotFound = errors.New("存档历史版本不存在")
+
+// UserDataVersion 玩家存档历史版本
+type UserDataVersion struct {
+	Version   int64  `json:"version"`
+	Data      string `json:"data,omitempty"`
+	Source    string `json:"source"`
+	Ip        string `json:"ip"`
+	Size      int64  `json:"size"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// UserDataDiff 两个存档版本之间的差异
+type UserDataDiff struct {
+	Path string      `json:"path"`
+	Type string      `json:"type"` // added/removed/changed
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// getUserDataHistoryTableName 获取玩家存档历史表名
+func getUserDataHistoryTableName(appId string) string {
+	return fmt.Sprintf("user_data_history_%s", utils.CleanAppId(appId))
+}
+
+// SaveGameUserData 在事务中保存玩家存档、增加版本号并记录历史，返回新的版本号
+func SaveGameUserData(appId, playerId, data, source, ip string) (int64, error) {
+	o := orm.NewOrm()
+	tableName := fmt.Sprintf("user_%s", appId)
+	historyTable := getUserDataHistoryTableName(appId)
+	keep := int64(web.AppConfig.DefaultInt("user_data_history_keep", 20))
+
+	tx, err := o.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	var rows []orm.Params
+	_, err = tx.Raw(fmt.Sprintf("SELECT data_version FROM %s WHERE player_id = ? FOR UPDATE", tableName), playerId).Values(&rows)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if len(rows) == 0 {
+		tx.Rollback()
+		return 0, fmt.Errorf("用户不存在")
+	}
+	version, _ := strconv.ParseInt(fmt.Sprint(rows[0]["data_version"]), 10, 64)
+
+	// 修改前的存档如果还没有记录过也写入历史，历史记录失败不影响保存
+	if keep > 0 {
+		sql := fmt.Sprintf(`
+			INSERT IGNORE INTO %s (player_id, version, data, source, ip, created_at)
+			SELECT player_id, data_version, data, 'initial', '', updated_at FROM %s WHERE player_id = ? AND data IS NOT NULL AND data <> '' AND data <> '{}'
+		`, historyTable, tableName)
+		if _, err := tx.Raw(sql, playerId).Exec(); err != nil {
+			logs.Warning("记录存档历史失败: appId=%s, playerId=%s, err=%v", appId, playerId, err)
+		}
+	}
+
+	sql := fmt.Sprintf("UPDATE %s SET data = ?, data_version = data_version + 1, updated_at = NOW() WHERE player_id = ?", tableName)
+	if _, err := tx.Raw(sql, data, playerId).Exec(); err != nil {
+		tx.Rollback()
+		logs.Error("更新用户数据失败:", err)
+		return 0, err
+	}
+
+	if keep > 0 {
+		sql = fmt.Sprintf(`INSERT IGNORE INTO %s (player_id, version, data, source, ip, created_at) VALUES (?, ?, ?, ?, ?, NOW())`, historyTable)
+		if _, err := tx.Raw(sql, playerId, version+1, data, source, ip).Exec(); err != nil {
+			logs.Warning("记录存档历史失败: appId=%s, playerId=%s, err=%v", appId, playerId, err)
+		} else {
+			tx.Raw(fmt.Sprintf(`DELETE FROM %s WHERE player_id = ? AND version <= ?`, historyTable), playerId, version+1-keep).Exec()
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return version + 1, nil
+}
+
+// GetUserDataHistory 获取玩家存档历史版本列表（不含存档内容，按版本号倒序）
+func GetUserDataHistory(appId, playerId string) ([]UserDataVersion, error) {
+	o := orm.NewOrm()
+	tableName := getUserDataHistoryTableName(appId)
+
+	exists, err := checkTableExists(tableName)
+	if err != nil || !exists {
+		return []UserDataVersion{}, err
+	}
+
+	var rows []orm.Params
+	sql := fmt.Sprintf("SELECT version, source, ip, LENGTH(data) as size, created_at FROM %s WHERE player_id = ? ORDER BY version DESC", tableName)
+	if _, err := o.Raw(sql, playerId).Values(&rows); err != nil {
+		logs.Error("获取存档历史失败:", err)
+		return nil, err
+	}
+
+	versions := make([]UserDataVersion, 0, len(rows))
+	for _, row := range rows {
+		versions = append(versions, convertUserDataVersion(row))
+	}
+	return versions, nil
+}
+
+// GetUserDataVersion 获取玩家存档指定版本，version为0时返回当前存档
+func GetUserDataVersion(appId, playerId string, version int64) (*UserDataVersion, error) {
+	if version == 0 {
+		user, err := GetGameUserDetail(appId, playerId)
+		if err != nil {
+			return nil, err
+		}
+		return &UserDataVersion{Version: user.DataVersion, Data: user.Data, Size: int64(len(user.Data))}, nil
+	}
+
+	o := orm.NewOrm()
+	var rows []orm.Params
+	sql := fmt.Sprintf("SELECT version, data, source, ip, LENGTH(data) as size, created_at FROM %s WHERE player_id = ? AND version = ?",
+		getUserDataHistoryTableName(appId))
+	if _, err := o.Raw(sql, playerId, version).Values(&rows); err != nil {
+		logs.Error("获取存档历史失败:", err)
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrUserDataVersionNotFound
+	}
+
+	result := convertUserDataVersion(rows[0])
+	return &result, nil
+}
+
+// DiffUserDataVersions 对比玩家存档的两个版本，toVersion为0时与当前存档对比
+func DiffUserDataVersions(appId, playerId string, fromVersion, toVersion int64) ([]UserDataDiff, error) {
+	from, err := GetUserDataVersion(appId, playerId, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := GetUserDataVersion(appId, playerId, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffUserData(from.Data, to.Data), nil
+}
+
+// RestoreUserDataVersion 将玩家存档回滚到指定历史版本（作为新版本保存），返回新的版本号
+func RestoreUserDataVersion(appId, playerId string, version int64, ip string) (int64, error) {
+	if version <= 0 {
+		return 0, ErrUserDataVersionNotFound
+	}
+	target, err := GetUserDataVersion(appId, playerId, version)
+	if err != nil {
+		return 0, err
+	}
+	return SaveGameUserData(appId, playerId, target.Data, UserDataSourceRestore, ip)
+}
+
+// diffUserData 按JSON路径对比两份存档，无法解析为JSON时整体对比
+func diffUserData(oldData, newData string) []UserDataDiff {
+	oldValues := make(map[string]interface{})
+	newValues := make(map[string]interface{})
+	flattenUserData("", decodeUserDataValue(oldData), oldValues)
+	flattenUserData("", decodeUserDataValue(newData), newValues)
+
+	diffs := make([]UserDataDiff, 0)
+	for path, oldValue := range oldValues {
+		newValue, ok := newValues[path]
+		if !ok {
+			diffs = append(diffs, UserDataDiff{Path: path, Type: "removed", Old: oldValue})
+		} else if !reflect.DeepEqual(oldValue, newValue) {
+			diffs = append(diffs, UserDataDiff{Path: path, Type: "changed", Old: oldValue, New: newValue})
+		}
+	}
+	for path, newValue := range newValues {
+		if _, ok := oldValues[path]; !ok {
+			diffs = append(diffs, UserDataDiff{Path: path, Type: "added", New: newValue})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
+
+// decodeUserDataValue 解析存档内容，不是合法JSON时按字符串处理
+func decodeUserDataValue(data string) interface{} {
+	if data == "" {
+		return map[string]interface{}{}
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return data
+	}
+	return value
+}
+
+// flattenUserData 将JSON展开为路径到叶子值的映射，路径以.分隔，数组使用下标，空对象和空数组作为叶子值
+func flattenUserData(prefix string, value interface{}, result map[string]interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && prefix != "" {
+			result[prefix] = v
+		}
+		for key, child := range v {
+			flattenUserData(join(key), child, result)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			result[prefix] = v
+		}
+		for i, child := range v {
+			flattenUserData(join(strconv.Itoa(i)), child, result)
+		}
+	default:
+		result[prefix] = v
+	}
+}
+
+// convertUserDataVersion 将查询结果转换为存档历史版本
+func convertUserDataVersion(row orm.Params) UserDataVersion {
+	version := UserDataVersion{}
+	version.Version, _ = strconv.ParseInt(fmt.Sprint(row["version"]), 10, 64)
+	version.Size, _ = strconv.ParseInt(fmt.Sprint(row["size"]), 10, 64)
+	version.Data, _ = row["data"].(string)
+	version.Source, _ = row["source"].(string)
+	version.Ip, _ = row["ip"].(string)
+	version.CreatedAt, _ = row["created_at"].(string)
+	return version
+}
@@ -0,0 +1,544 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"admin-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// identityProviderLegacy 渠道未知的旧账号（与游戏服约定一致，玩家下次登录时改为实际渠道）
+const identityProviderLegacy = "legacy"
+
+var (
+	// ErrMergeSamePlayer 合并的源玩家和目标玩家相同
+	ErrMergeSamePlayer = errors.New("源玩家和目标玩家不能相同")
+	// ErrMergePlayerNotFound 合并的玩家不存在
+	ErrMergePlayerNotFound = errors.New("玩家不存在")
+	// ErrMergeInProgress 玩家正在被另一个合并操作处理
+	ErrMergeInProgress = errors.New("玩家正在合并中，请稍后再试")
+	// ErrMergeIdentityConflict 两个玩家绑定了同一渠道的登录身份，需要指定保留哪一方
+	ErrMergeIdentityConflict = errors.New("两个玩家绑定了同一渠道的登录身份")
+)
+
+// 合并时同一渠道登录身份冲突的处理方式
+const (
+	MergeKeepTargetIdentity = "target" // 保留目标玩家的身份，删除源玩家的
+	MergeKeepSourceIdentity = "source" // 保留源玩家的身份，删除目标玩家的
+)
+
+// counterPlayerLockKeyPrefix 合并玩家期间的玩家计数器锁（与游戏服约定一致），后接appId:playerId
+// 加锁期间游戏服不读写该玩家的计数器缓存，落库任务也不会写回该玩家的计数器
+const counterPlayerLockKeyPrefix = "counter_player_lock:"
+
+// mergeLockTTL 合并玩家锁的有效期
+const mergeLockTTL = 5 * time.Minute
+
+// GameUserIdentity 玩家登录身份
+type GameUserIdentity struct {
+	Provider  string `json:"provider"`
+	OpenId    string `json:"openId"`
+	UnionId   string `json:"unionId"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// UserMergeResult 合并账号结果，各字段为迁移到目标玩家的记录数
+type UserMergeResult struct {
+	SourcePlayerId    string `json:"sourcePlayerId"`
+	TargetPlayerId    string `json:"targetPlayerId"`
+	Identities        int64  `json:"identities"`
+	RemovedIdentities int64  `json:"removedIdentities"` // 同一渠道冲突时删除的登录身份数
+	Slots             int64  `json:"slots"`
+	Leaderboards      int64  `json:"leaderboards"`
+	MailRelations     int64  `json:"mailRelations"`
+	Counters          int64  `json:"counters"`
+	DataVersion       int64  `json:"dataVersion"` // 使用源玩家存档时目标玩家的新存档版本号
+}
+
+// getUserIdentityTableName 获取玩家登录身份表名
+func getUserIdentityTableName(appId string) string {
+	return fmt.Sprintf("user_identity_%s", utils.CleanAppId(appId))
+}
+
+// GetGameUserIdentities 获取玩家绑定的所有登录身份
+func GetGameUserIdentities(appId, playerId string) ([]GameUserIdentity, error) {
+	o := orm.NewOrm()
+	tableName := getUserIdentityTableName(appId)
+
+	exists, err := checkTableExists(tableName)
+	if err != nil || !exists {
+		return []GameUserIdentity{}, err
+	}
+
+	var rows []orm.Params
+	sql := fmt.Sprintf("SELECT provider, open_id, union_id, created_at FROM %s WHERE player_id = ? ORDER BY id ASC", tableName)
+	if _, err := o.Raw(sql, playerId).Values(&rows); err != nil {
+		logs.Error("获取玩家登录身份失败:", err)
+		return nil, err
+	}
+
+	identities := make([]GameUserIdentity, 0, len(rows))
+	for _, row := range rows {
+		identity := GameUserIdentity{}
+		identity.Provider, _ = row["provider"].(string)
+		identity.OpenId, _ = row["open_id"].(string)
+		identity.UnionId, _ = row["union_id"].(string)
+		identity.CreatedAt, _ = row["created_at"].(string)
+		identities = append(identities, identity)
+	}
+	return identities, nil
+}
+
+// MergeGameUsers 把源玩家合并到目标玩家：迁移登录身份、命名存档、排行榜、邮件和玩家计数器，然后删除源玩家
+// useSourceData为true时目标玩家的主存档和同名存档槽位使用源玩家的数据，否则保留目标玩家的数据
+// 两个玩家绑定了同一渠道的登录身份时按keepIdentity（target/source）保留一方，为空时不合并并返回ErrMergeIdentityConflict
+func MergeGameUsers(appId, sourcePlayerId, targetPlayerId string, useSourceData bool, keepIdentity, ip string) (*UserMergeResult, error) {
+	if sourcePlayerId == targetPlayerId {
+		return nil, ErrMergeSamePlayer
+	}
+
+	cleanAppId := utils.CleanAppId(appId)
+	userTable := fmt.Sprintf("user_%s", cleanAppId)
+
+	// 锁定两个玩家的计数器缓存，并把缓存中尚未落库的值写回数据库，合并期间游戏服不会再写入旧值
+	unlock, err := lockMergePlayers(appId, sourcePlayerId, targetPlayerId)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := detachPlayerCounterCache(appId, userTable, sourcePlayerId, targetPlayerId); err != nil {
+		return nil, fmt.Errorf("写回玩家计数器缓存失败: %v", err)
+	}
+
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	var users []orm.Params
+	_, err = tx.Raw(fmt.Sprintf("SELECT player_id, open_id, data FROM %s WHERE player_id IN (?, ?) FOR UPDATE", userTable), sourcePlayerId, targetPlayerId).Values(&users)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if len(users) != 2 {
+		tx.Rollback()
+		return nil, ErrMergePlayerNotFound
+	}
+
+	var sourceOpenId, sourceData string
+	for _, user := range users {
+		if user["player_id"] == sourcePlayerId {
+			sourceOpenId, _ = user["open_id"].(string)
+			sourceData, _ = user["data"].(string)
+		}
+	}
+
+	result := &UserMergeResult{SourcePlayerId: sourcePlayerId, TargetPlayerId: targetPlayerId}
+	result.Identities, result.RemovedIdentities, err = mergeUserIdentities(tx, appId, sourcePlayerId, targetPlayerId, sourceOpenId, keepIdentity)
+	if err == nil {
+		result.Slots, err = mergeUserSlots(tx, cleanAppId, sourcePlayerId, targetPlayerId, useSourceData)
+	}
+	if err == nil {
+		result.Leaderboards, err = mergeUserLeaderboards(tx, appId, sourcePlayerId, targetPlayerId)
+	}
+	if err == nil {
+		result.MailRelations, err = movePlayerRows(tx, fmt.Sprintf("mail_player_relation_%s", cleanAppId), sourcePlayerId, targetPlayerId)
+	}
+	if err == nil {
+		result.Counters, err = mergeUserCounters(tx, cleanAppId, sourcePlayerId, targetPlayerId)
+	}
+	if err != nil {
+		tx.Rollback()
+		logs.Error("合并玩家失败: appId=%s, source=%s, target=%s, err=%v", appId, sourcePlayerId, targetPlayerId, err)
+		return nil, err
+	}
+
+	// 源玩家的存档历史不再需要，最后删除源玩家
+	tx.Raw(fmt.Sprintf("DELETE FROM %s WHERE player_id = ?", getUserDataHistoryTableName(appId)), sourcePlayerId).Exec()
+	if _, err := tx.Raw(fmt.Sprintf("DELETE FROM %s WHERE player_id = ?", userTable), sourcePlayerId).Exec(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// 主存档通过SaveGameUserData保存，目标玩家原来的存档会记录到历史中，可以回滚
+	if useSourceData && sourceData != "" {
+		version, err := SaveGameUserData(appId, targetPlayerId, sourceData, UserDataSourceMerge, ip)
+		if err != nil {
+			logs.Error("合并玩家存档失败: appId=%s, target=%s, err=%v", appId, targetPlayerId, err)
+			return result, fmt.Errorf("合并完成但使用源玩家存档失败: %v", err)
+		}
+		result.DataVersion = version
+	}
+
+	clearMergedPlayerCache(appId, sourcePlayerId, targetPlayerId)
+	return result, nil
+}
+
+// mergeUserIdentities 迁移登录身份，源玩家还没有归属到渠道的open_id以legacy身份迁移，保证原账号仍能登录到目标玩家
+// 同一渠道两个玩家都有身份时按keepIdentity删除另一方的身份，返回迁移和删除的身份数
+func mergeUserIdentities(tx orm.TxOrmer, appId, sourcePlayerId, targetPlayerId, sourceOpenId, keepIdentity string) (int64, int64, error) {
+	tableName := getUserIdentityTableName(appId)
+
+	var rows []orm.Params
+	_, err := tx.Raw(fmt.Sprintf("SELECT id FROM %s WHERE player_id = ? AND open_id = ?", tableName), sourcePlayerId, sourceOpenId).Values(&rows)
+	if err != nil {
+		return 0, 0, err
+	}
+	// 带冒号的open_id是游戏服生成的占位值（渠道前缀或已解绑），不是真实账号
+	if len(rows) == 0 && sourceOpenId != "" && !strings.Contains(sourceOpenId, ":") {
+		sql := fmt.Sprintf("INSERT IGNORE INTO %s (player_id, provider, open_id, union_id, created_at, updated_at) VALUES (?, ?, ?, '', NOW(), NOW())", tableName)
+		if _, err := tx.Raw(sql, sourcePlayerId, identityProviderLegacy, sourceOpenId).Exec(); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// 检查同一渠道的身份冲突（一个玩家在每个渠道只能有一个身份）
+	var conflicts []orm.Params
+	_, err = tx.Raw(fmt.Sprintf(`
+		SELECT DISTINCT s.provider FROM %[1]s s JOIN %[1]s t ON t.provider = s.provider AND t.player_id = ?
+		WHERE s.player_id = ? ORDER BY s.provider
+	`, tableName), targetPlayerId, sourcePlayerId).Values(&conflicts)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var removed int64
+	if len(conflicts) > 0 {
+		providers := make([]interface{}, 0, len(conflicts))
+		names := make([]string, 0, len(conflicts))
+		for _, row := range conflicts {
+			provider, _ := row["provider"].(string)
+			providers = append(providers, provider)
+			names = append(names, provider)
+		}
+
+		var dropPlayerId string
+		switch keepIdentity {
+		case MergeKeepTargetIdentity:
+			dropPlayerId = sourcePlayerId
+		case MergeKeepSourceIdentity:
+			dropPlayerId = targetPlayerId
+		default:
+			return 0, 0, fmt.Errorf("%w: %s", ErrMergeIdentityConflict, strings.Join(names, ","))
+		}
+
+		args := append([]interface{}{dropPlayerId}, providers...)
+		res, err := tx.Raw(fmt.Sprintf("DELETE FROM %s WHERE player_id = ? AND provider IN (%s)", tableName, utils.BuildPlaceholders(len(providers))), args...).Exec()
+		if err != nil {
+			return 0, 0, err
+		}
+		removed, _ = res.RowsAffected()
+	}
+
+	res, err := tx.Raw(fmt.Sprintf("UPDATE %s SET player_id = ? WHERE player_id = ?", tableName), targetPlayerId, sourcePlayerId).Exec()
+	if err != nil {
+		return 0, 0, err
+	}
+	moved, _ := res.RowsAffected()
+	return moved, removed, nil
+}
+
+// mergeUserSlots 迁移命名存档，同名槽位按useSourceData决定保留哪一方
+func mergeUserSlots(tx orm.TxOrmer, cleanAppId, sourcePlayerId, targetPlayerId string, useSourceData bool) (int64, error) {
+	tableName := fmt.Sprintf("user_data_%s", cleanAppId)
+	if useSourceData {
+		sql := fmt.Sprintf(`
+			DELETE t FROM %s t JOIN %s s ON s.slot = t.slot AND s.player_id = ?
+			WHERE t.player_id = ?
+		`, tableName, tableName)
+		if _, err := tx.Raw(sql, sourcePlayerId, targetPlayerId).Exec(); err != nil {
+			return 0, err
+		}
+	}
+	return movePlayerRows(tx, tableName, sourcePlayerId, targetPlayerId)
+}
+
+// mergeUserLeaderboards 迁移排行榜数据，同一排行榜分区两个玩家都有成绩时按排行榜的更新策略合并
+func mergeUserLeaderboards(tx orm.TxOrmer, appId, sourcePlayerId, targetPlayerId string) (int64, error) {
+	cleanAppId := utils.CleanAppId(appId)
+	tableName := fmt.Sprintf("leaderboard_%s", cleanAppId)
+
+	var rows []orm.Params
+	sql := fmt.Sprintf(`
+		SELECT s.id, s.type, s.score, s.extra_data, s.updated_at, t.id AS target_id, t.score AS target_score, t.updated_at AS target_updated_at
+		FROM %s s LEFT JOIN %s t ON t.type = s.type AND t.partition_key = s.partition_key AND t.player_id = ?
+		WHERE s.player_id = ?
+	`, tableName, tableName)
+	if _, err := tx.Raw(sql, targetPlayerId, sourcePlayerId).Values(&rows); err != nil {
+		return 0, err
+	}
+
+	configs := make(map[string]*LeaderboardConfig)
+	var merged int64
+	for _, row := range rows {
+		id := row["id"]
+		if row["target_id"] == nil {
+			if _, err := tx.Raw(fmt.Sprintf("UPDATE %s SET player_id = ? WHERE id = ?", tableName), targetPlayerId, id).Exec(); err != nil {
+				return 0, err
+			}
+			merged++
+			continue
+		}
+
+		leaderboardType, _ := row["type"].(string)
+		config, ok := configs[leaderboardType]
+		if !ok {
+			config = &LeaderboardConfig{}
+			if err := tx.QueryTable("leaderboard_config").Filter("app_id", appId).Filter("leaderboard_type", leaderboardType).One(config); err != nil {
+				config = nil
+			}
+			configs[leaderboardType] = config
+		}
+
+		score, _ := strconv.ParseInt(fmt.Sprint(row["score"]), 10, 64)
+		targetScore, _ := strconv.ParseInt(fmt.Sprint(row["target_score"]), 10, 64)
+		sourceUpdatedAt, _ := row["updated_at"].(string)
+		targetUpdatedAt, _ := row["target_updated_at"].(string)
+
+		// 0=最高分（按排序方向取更好的成绩）, 1=最新分, 2=累计分
+		useSource := false
+		switch {
+		case config != nil && config.UpdateStrategy == 2:
+			sql := fmt.Sprintf("UPDATE %s SET score = score + ? WHERE id = ?", tableName)
+			if _, err := tx.Raw(sql, score, row["target_id"]).Exec(); err != nil {
+				return 0, err
+			}
+		case config != nil && config.UpdateStrategy == 1:
+			useSource = sourceUpdatedAt > targetUpdatedAt
+		case config != nil && config.ScoreType == "lower_better":
+			useSource = score < targetScore
+		default:
+			useSource = score > targetScore
+		}
+		if useSource {
+			sql := fmt.Sprintf("UPDATE %s SET score = ?, extra_data = ?, updated_at = ? WHERE id = ?", tableName)
+			if _, err := tx.Raw(sql, score, row["extra_data"], sourceUpdatedAt, row["target_id"]).Exec(); err != nil {
+				return 0, err
+			}
+		}
+		merged++
+	}
+
+	if _, err := tx.Raw(fmt.Sprintf("DELETE FROM %s WHERE player_id = ?", tableName), sourcePlayerId).Exec(); err != nil {
+		return 0, err
+	}
+
+	// 赛季历史、联赛分组和可疑分数记录直接迁移，目标玩家已有的记录保留目标玩家的
+	for _, table := range []string{"leaderboard_history_%s", "leaderboard_league_%s", "leaderboard_suspicious_%s"} {
+		if _, err := movePlayerRows(tx, fmt.Sprintf(table, cleanAppId), sourcePlayerId, targetPlayerId); err != nil {
+			return 0, err
+		}
+	}
+	return merged, nil
+}
+
+// mergeUserCounters 迁移玩家计数器，同一计数器点位两个玩家都有值时相加
+func mergeUserCounters(tx orm.TxOrmer, cleanAppId, sourcePlayerId, targetPlayerId string) (int64, error) {
+	tableName := fmt.Sprintf("counter_%s", cleanAppId)
+	sql := fmt.Sprintf(`
+		INSERT INTO %[1]s (counter_key, player_id, location, value, created_at, updated_at)
+		SELECT * FROM (SELECT counter_key, ? AS player_id, location, value, created_at, updated_at FROM %[1]s WHERE player_id = ?) AS s
+		ON DUPLICATE KEY UPDATE
+			value = %[1]s.value + VALUES(value),
+			updated_at = GREATEST(%[1]s.updated_at, VALUES(updated_at))
+	`, tableName)
+	if _, err := tx.Raw(sql, targetPlayerId, sourcePlayerId).Exec(); err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Raw(fmt.Sprintf("DELETE FROM %s WHERE player_id = ?", tableName), sourcePlayerId).Exec()
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// movePlayerRows 把源玩家的记录改为目标玩家，唯一键冲突的记录保留目标玩家的并删除源玩家的
+func movePlayerRows(tx orm.TxOrmer, tableName, sourcePlayerId, targetPlayerId string) (int64, error) {
+	res, err := tx.Raw(fmt.Sprintf("UPDATE IGNORE %s SET player_id = ? WHERE player_id = ?", tableName), targetPlayerId, sourcePlayerId).Exec()
+	if err != nil {
+		return 0, err
+	}
+	moved, _ := res.RowsAffected()
+
+	if _, err := tx.Raw(fmt.Sprintf("DELETE FROM %s WHERE player_id = ?", tableName), sourcePlayerId).Exec(); err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+// lockMergePlayers 合并期间锁定两个玩家的计数器，返回解锁函数；任一玩家已被锁定时返回ErrMergeInProgress
+func lockMergePlayers(appId string, playerIds ...string) (func(), error) {
+	if RedisClient == nil {
+		return func() {}, nil
+	}
+	ctx := context.Background()
+
+	keys := make([]string, 0, len(playerIds))
+	unlock := func() {
+		if len(keys) > 0 {
+			RedisClient.Del(ctx, keys...)
+		}
+	}
+	for _, playerId := range playerIds {
+		key := counterPlayerLockKeyPrefix + appId + ":" + playerId
+		locked, err := RedisClient.SetNX(ctx, key, time.Now().Unix(), mergeLockTTL).Result()
+		if err != nil || !locked {
+			unlock()
+			if err != nil {
+				return nil, err
+			}
+			return nil, ErrMergeInProgress
+		}
+		keys = append(keys, key)
+	}
+	return unlock, nil
+}
+
+// detachPlayerCounterCache 把游戏服缓存中玩家计数器当前周期的值写回数据库，然后删除缓存
+// 写回前对玩家加排他锁，等待游戏服正在进行的落库（对玩家加共享锁）完成，避免落库任务随后写入旧值
+func detachPlayerCounterCache(appId, userTable string, playerIds ...string) error {
+	if RedisClient == nil {
+		return nil
+	}
+	ctx := context.Background()
+
+	// 玩家计数器键格式为counter_player:{appId}:{period}:{playerId}:{key}
+	prefix := counterPlayerRedisKeyPrefix + appId + ":"
+	var cacheKeys []string
+	iter := RedisClient.Scan(ctx, 0, prefix+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		parts := strings.SplitN(strings.TrimPrefix(iter.Val(), prefix), ":", 3)
+		if len(parts) == 3 && containsString(playerIds, parts[1]) {
+			cacheKeys = append(cacheKeys, iter.Val())
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	tx, err := orm.NewOrm().Begin()
+	if err != nil {
+		return err
+	}
+	args := []interface{}{}
+	for _, playerId := range playerIds {
+		args = append(args, playerId)
+	}
+	var users []orm.Params
+	_, err = tx.Raw(fmt.Sprintf("SELECT player_id FROM %s WHERE player_id IN (%s) FOR UPDATE", userTable, utils.BuildPlaceholders(len(args))), args...).Values(&users)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	tableName := fmt.Sprintf("counter_%s", utils.CleanAppId(appId))
+	configs := make(map[string]*CounterConfig)
+	now := time.Now()
+	for _, cacheKey := range cacheKeys {
+		parts := strings.SplitN(strings.TrimPrefix(cacheKey, prefix), ":", 3)
+		period, playerId, counterKey := parts[0], parts[1], parts[2]
+
+		// 只写回当前周期，已结束周期的值由游戏服落库或已归档
+		config, ok := configs[counterKey]
+		if !ok {
+			config, err = GetCounterConfig(appId, counterKey)
+			if err != nil {
+				config = nil
+			}
+			configs[counterKey] = config
+		}
+		if config == nil || getCounterPeriod(config, now).Id != period {
+			continue
+		}
+
+		values, err := RedisClient.HGetAll(ctx, cacheKey).Result()
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		for location, value := range values {
+			sql := fmt.Sprintf(`
+				INSERT INTO %s (counter_key, player_id, location, value, created_at, updated_at)
+				VALUES (?, ?, ?, ?, NOW(), NOW())
+				ON DUPLICATE KEY UPDATE value = VALUES(value), updated_at = NOW()
+			`, tableName)
+			if _, err := tx.Raw(sql, counterKey, playerId, location, value).Exec(); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// 数据库已是最新值，删除缓存和待落库标记，解锁后游戏服重新从数据库加载
+	for _, cacheKey := range cacheKeys {
+		loadedKey := counterPlayerLoadedKeyPrefix + strings.TrimPrefix(cacheKey, counterPlayerRedisKeyPrefix)
+		RedisClient.Del(ctx, cacheKey, loadedKey)
+	}
+	members, err := RedisClient.SMembers(ctx, counterDirtyKey).Result()
+	if err != nil {
+		return err
+	}
+	for _, raw := range members {
+		var member counterDirtyMember
+		if json.Unmarshal([]byte(raw), &member) == nil && member.AppId == appId && containsString(playerIds, member.PlayerId) {
+			RedisClient.SRem(ctx, counterDirtyKey, raw)
+		}
+	}
+	return nil
+}
+
+// clearMergedPlayerCache 合并后清理两个玩家在游戏服的缓存：计数器缓存重新从数据库加载，源玩家的登录token失效，排行榜通过对账修复
+func clearMergedPlayerCache(appId, sourcePlayerId, targetPlayerId string) {
+	if RedisClient == nil {
+		return
+	}
+	ctx := context.Background()
+
+	// 玩家计数器键格式为counter_player:{appId}:{period}:{playerId}:{key}
+	prefix := counterPlayerRedisKeyPrefix + appId + ":"
+	iter := RedisClient.Scan(ctx, 0, prefix+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		parts := strings.SplitN(strings.TrimPrefix(iter.Val(), prefix), ":", 3)
+		if len(parts) == 3 && (parts[1] == sourcePlayerId || parts[1] == targetPlayerId) {
+			loadedKey := counterPlayerLoadedKeyPrefix + strings.TrimPrefix(iter.Val(), counterPlayerRedisKeyPrefix)
+			RedisClient.Del(ctx, iter.Val(), loadedKey)
+		}
+	}
+
+	if _, err := TerminateGameUserSessions(appId, sourcePlayerId, "", SessionKickAdmin); err != nil {
+		logs.Warning("撤销源玩家登录会话失败: appId=%s, playerId=%s, err=%v", appId, sourcePlayerId, err)
+	}
+
+	if _, err := RequestLeaderboardReconcile(appId, "merge:"+sourcePlayerId); err != nil {
+		logs.Warning("提交排行榜对账失败: appId=%s, err=%v", appId, err)
+	}
+}
+
+// containsString 判断字符串是否在列表中
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -3,22 +3,26 @@ package models
 import (
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 	"time"
 
+	"admin-service/utils"
+
 	"github.com/beego/beego/v2/client/orm"
 	"github.com/beego/beego/v2/core/logs"
 )
 
 // GameUser 游戏用户结构
 type GameUser struct {
-	ID        int64     `orm:"pk;auto" json:"id"`
-	OpenId    string    `orm:"size(100);unique" json:"openId"`
-	PlayerId  string    `orm:"size(100);unique" json:"playerId"`
-	Data      string    `orm:"type(longtext)" json:"data"`
-	Banned    bool      `orm:"default(false)" json:"banned"`
-	CreatedAt time.Time `orm:"auto_now_add;type(datetime)" json:"createdAt"`
-	UpdatedAt time.Time `orm:"auto_now;type(datetime)" json:"updatedAt"`
+	ID          int64     `orm:"pk;auto" json:"id"`
+	OpenId      string    `orm:"size(100);unique" json:"openId"`
+	PlayerId    string    `orm:"size(100);unique" json:"playerId"`
+	Data        string    `orm:"type(longtext)" json:"data"`
+	DataVersion int64     `orm:"default(0);column(data_version)" json:"dataVersion"`
+	Banned      bool      `orm:"default(false)" json:"banned"`
+	CreatedAt   time.Time `orm:"auto_now_add;type(datetime)" json:"createdAt"`
+	UpdatedAt   time.Time `orm:"auto_now;type(datetime)" json:"updatedAt"`
 	// 解析后的数据
 	PlayerInfo map[string]interface{} `orm:"-" json:"playerInfo"`
 }
@@ -151,19 +155,53 @@ func GetGameUserDetail(appId, playerId string) (*GameUser, error) {
 	return &user, nil
 }
 
-// UpdateGameUserData 更新游戏用户数据
-func UpdateGameUserData(appId, playerId, data string) error {
+// GameUserSlot 玩家命名存档
+type GameUserSlot struct {
+	Slot      string `json:"slot"`
+	Data      string `json:"data"`
+	Version   int64  `json:"version"`
+	Size      int64  `json:"size"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// GetGameUserSlots 获取玩家所有命名存档（含存档内容）
+func GetGameUserSlots(appId, playerId string) ([]GameUserSlot, error) {
 	o := orm.NewOrm()
-	tableName := fmt.Sprintf("user_%s", appId)
+	tableName := fmt.Sprintf("user_data_%s", utils.CleanAppId(appId))
 
-	sql := fmt.Sprintf("UPDATE %s SET data = ?, updated_at = NOW() WHERE player_id = ?", tableName)
-	_, err := o.Raw(sql, data, playerId).Exec()
-	if err != nil {
-		logs.Error("更新用户数据失败:", err)
-		return err
+	exists, err := checkTableExists(tableName)
+	if err != nil || !exists {
+		return []GameUserSlot{}, err
 	}
 
-	return nil
+	var rows []orm.Params
+	sql := fmt.Sprintf("SELECT slot, data, version, LENGTH(data) as size, created_at, updated_at FROM %s WHERE player_id = ? ORDER BY slot ASC", tableName)
+	if _, err := o.Raw(sql, playerId).Values(&rows); err != nil {
+		logs.Error("获取玩家存档失败:", err)
+		return nil, err
+	}
+
+	slots := make([]GameUserSlot, 0, len(rows))
+	for _, row := range rows {
+		slot := GameUserSlot{}
+		slot.Slot, _ = row["slot"].(string)
+		slot.Data, _ = row["data"].(string)
+		slot.CreatedAt, _ = row["created_at"].(string)
+		slot.UpdatedAt, _ = row["updated_at"].(string)
+		slot.Version, _ = strconv.ParseInt(fmt.Sprint(row["version"]), 10, 64)
+		slot.Size, _ = strconv.ParseInt(fmt.Sprint(row["size"]), 10, 64)
+		slots = append(slots, slot)
+	}
+
+	return slots, nil
+}
+
+// UpdateGameUserData 更新游戏用户数据
+// 同时增加数据版本号并记录存档历史，让客户端持有的旧版本存档无法覆盖后台的修改
+func UpdateGameUserData(appId, playerId, data, ip string) error {
+	_, err := SaveGameUserData(appId, playerId, data, UserDataSourceAdmin, ip)
+	return err
 }
 
 // BanGameUser 封禁游戏用户
@@ -193,6 +231,11 @@ func BanGameUser(appId, playerId string, adminId int64, banType, banReason strin
 		return err
 	}
 
+	// 踢下线玩家的所有登录会话，已登录的设备立即失效
+	if _, err := TerminateGameUserSessions(appId, playerId, "", SessionKickBanned); err != nil {
+		logs.Warning("撤销封禁用户登录会话失败: appId=%s, playerId=%s, err=%v", appId, playerId, err)
+	}
+
 	return nil
 }
 
@@ -356,6 +399,17 @@ func DeleteGameUser(appId, playerId string) error {
 		return err
 	}
 
+	// 删除玩家的命名存档和存档历史
+	slotTable := fmt.Sprintf("user_data_%s", appId)
+	sql = fmt.Sprintf("DELETE FROM %s WHERE player_id = ?", slotTable)
+	tx.Raw(sql, playerId).Exec()
+	sql = fmt.Sprintf("DELETE FROM %s WHERE player_id = ?", getUserDataHistoryTableName(appId))
+	tx.Raw(sql, playerId).Exec()
+
+	// 删除玩家的登录身份
+	sql = fmt.Sprintf("DELETE FROM %s WHERE player_id = ?", getUserIdentityTableName(appId))
+	tx.Raw(sql, playerId).Exec()
+
 	// 删除相关的排行榜数据
 	leaderboardTable := fmt.Sprintf("leaderboard_%s", appId)
 	sql = fmt.Sprintf("DELETE FROM %s WHERE player_id = ?", leaderboardTable)
@@ -366,7 +420,14 @@ func DeleteGameUser(appId, playerId string) error {
 	sql = fmt.Sprintf("DELETE FROM %s WHERE user_id = ?", mailTable)
 	tx.Raw(sql, playerId).Exec()
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if _, err := TerminateGameUserSessions(appId, playerId, "", SessionKickAdmin); err != nil {
+		logs.Warning("撤销已删除用户登录会话失败: appId=%s, playerId=%s, err=%v", appId, playerId, err)
+	}
+	return nil
 }
 
 // GetGameUserStats 获取游戏用户统计信息
@@ -470,8 +531,8 @@ func GetUserDetail(appId, playerId string) (*GameUser, error) {
 }
 
 // SetUserDetail 设置用户详情（别名）
-func SetUserDetail(appId, playerId, data string) error {
-	return UpdateGameUserData(appId, playerId, data)
+func SetUserDetail(appId, playerId, data, ip string) error {
+	return UpdateGameUserData(appId, playerId, data, ip)
 }
 
 // GetUserStats 获取用户统计（别名）
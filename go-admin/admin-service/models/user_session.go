@@ -0,0 +1,119 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 玩家登录会话Redis键（与游戏服约定一致）
+// 游戏服为每台登录设备创建会话user_session_{appId}_{playerId}_{sessionId}（hash），
+// 当前有效的会话ID保存在user_sessions_{appId}_{playerId}（set）。
+// 会话被踢下线后标记kicked字段并保留到token过期，期间该设备的请求会收到被踢下线的响应。
+
+// 会话被踢下线的原因（与游戏服约定一致）
+const (
+	SessionKickAdmin  = "admin"  // 管理员踢下线
+	SessionKickBanned = "banned" // 玩家被封禁
+)
+
+// GameUserSession 玩家登录会话
+type GameUserSession struct {
+	SessionId     string `json:"sessionId"`
+	Device        string `json:"device"`        // 登录设备
+	Ip            string `json:"ip"`            // 最近一次心跳的IP（未心跳时为登录IP）
+	LoginTime     int64  `json:"loginTime"`     // 登录时间（秒级时间戳）
+	LastHeartbeat int64  `json:"lastHeartbeat"` // 最后心跳时间（秒级时间戳）
+	TokenExpireAt int64  `json:"tokenExpireAt"` // token过期时间（秒级时间戳）
+}
+
+// gameSessionKickScript 踢下线玩家的指定会话，sessionId为空时踢下线所有会话（与游戏服逻辑一致）
+// KEYS: 会话集合
+// ARGV: 会话键前缀、原因、当前时间、sessionId
+var gameSessionKickScript = redis.NewScript(`
+local now = tonumber(ARGV[3])
+local count = 0
+for _, id in ipairs(redis.call('SMEMBERS', KEYS[1])) do
+	if ARGV[4] == '' or ARGV[4] == id then
+		local key = ARGV[1] .. id
+		local expireAt = tonumber(redis.call('HGET', key, 'token_expire_at') or '0') or 0
+		if expireAt > now then
+			redis.call('HSET', key, 'kicked', ARGV[2])
+			redis.call('EXPIRE', key, expireAt - now)
+			count = count + 1
+		else
+			redis.call('DEL', key)
+		end
+		redis.call('SREM', KEYS[1], id)
+	end
+end
+return count
+`)
+
+// ListGameUserSessions 获取玩家当前有效的登录会话，按登录时间倒序
+func ListGameUserSessions(appId, playerId string) ([]GameUserSession, error) {
+	sessions := []GameUserSession{}
+	if RedisClient == nil {
+		return sessions, nil
+	}
+	ctx := context.Background()
+
+	ids, err := RedisClient.SMembers(ctx, gameUserSessionsKey(appId, playerId)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		values, err := RedisClient.HGetAll(ctx, gameUserSessionKey(appId, playerId, id)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 || values["kicked"] != "" {
+			continue
+		}
+
+		// token已过期但refreshToken仍有效的会话同样列出（客户端可以刷新token继续使用）
+		sessions = append(sessions, GameUserSession{
+			SessionId:     id,
+			Device:        values["device"],
+			Ip:            values["ip"],
+			LoginTime:     parseSessionInt(values["login_time"]),
+			LastHeartbeat: parseSessionInt(values["last_heartbeat"]),
+			TokenExpireAt: parseSessionInt(values["token_expire_at"]),
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LoginTime > sessions[j].LoginTime
+	})
+	return sessions, nil
+}
+
+// TerminateGameUserSessions 踢下线玩家的登录会话，sessionId为空时踢下线所有会话，返回踢下线的会话数
+func TerminateGameUserSessions(appId, playerId, sessionId, reason string) (int, error) {
+	if RedisClient == nil {
+		return 0, nil
+	}
+	return gameSessionKickScript.Run(context.Background(), RedisClient, []string{gameUserSessionsKey(appId, playerId)},
+		gameUserSessionKey(appId, playerId, ""), reason, time.Now().Unix(), sessionId).Int()
+}
+
+// parseSessionInt 解析会话中的时间戳字段
+func parseSessionInt(value string) int64 {
+	n, _ := strconv.ParseInt(value, 10, 64)
+	return n
+}
+
+// gameUserSessionKey 玩家会话的redis键，sessionId为空时返回键前缀
+func gameUserSessionKey(appId, playerId, sessionId string) string {
+	return fmt.Sprintf("user_session_%s_%s_%s", appId, playerId, sessionId)
+}
+
+// gameUserSessionsKey 玩家有效会话集合的redis键
+func gameUserSessionsKey(appId, playerId string) string {
+	return fmt.Sprintf("user_sessions_%s_%s", appId, playerId)
+}
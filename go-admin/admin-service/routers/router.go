@@ -107,14 +107,24 @@ func init() {
 	web.Router("/app/init", &controllers.ApplicationController{}, "post:CreateApplication")
 	web.Router("/app/query", &controllers.ApplicationController{}, "post:GetApplication")
 	web.Router("/app/getDetail", &controllers.ApplicationController{}, "post:GetApplication")
+	web.Router("/app/getDataSchema", &controllers.ApplicationController{}, "post:GetDataSchema")
+	web.Router("/app/setDataSchema", &controllers.ApplicationController{}, "post:SetDataSchema")
 
 	// 用户管理模块（旧路由）
 	web.Router("/user/getAll", &controllers.UserController{}, "post:GetAllUsers")
 	web.Router("/user/ban", &controllers.UserController{}, "post:BanUser")
 	web.Router("/user/unban", &controllers.UserController{}, "post:UnbanUser")
 	web.Router("/user/delete", &controllers.UserController{}, "post:DeleteUser")
+	web.Router("/user/getSessions", &controllers.UserController{}, "post:GetUserSessions")
+	web.Router("/user/kick", &controllers.UserController{}, "post:KickUser")
 	web.Router("/user/getDetail", &controllers.UserController{}, "post:GetUserDetail")
 	web.Router("/user/setDetail", &controllers.UserController{}, "post:SetUserDetail")
+	web.Router("/user/getSlots", &controllers.UserController{}, "post:GetUserSlots")
+	web.Router("/user/getDataHistory", &controllers.UserController{}, "post:GetUserDataHistory")
+	web.Router("/user/diffData", &controllers.UserController{}, "post:DiffUserData")
+	web.Router("/user/restoreData", &controllers.UserController{}, "post:RestoreUserData")
+	web.Router("/user/getIdentities", &controllers.UserController{}, "post:GetUserIdentities")
+	web.Router("/user/merge", &controllers.UserController{}, "post:MergeUsers")
 	web.Router("/user/getStats", &controllers.UserController{}, "post:GetUserStats")
 	// 排行榜管理模块
 	web.Router("/leaderboard/getAll", &controllers.LeaderboardController{}, "post:GetAllLeaderboards")
@@ -126,6 +136,12 @@ func init() {
 	web.Router("/leaderboard/deleteScore", &controllers.LeaderboardController{}, "post:DeleteLeaderboardScore")
 	web.Router("/leaderboard/commitScore", &controllers.LeaderboardController{}, "post:CommitLeaderboardScore")
 	web.Router("/leaderboard/queryScore", &controllers.LeaderboardController{}, "post:QueryLeaderboardScore")
+	web.Router("/leaderboard/getSeasons", &controllers.LeaderboardController{}, "post:GetLeaderboardSeasons")
+	web.Router("/leaderboard/getSeasonData", &controllers.LeaderboardController{}, "post:GetLeaderboardSeasonData")
+	web.Router("/leaderboard/getSuspicious", &controllers.LeaderboardController{}, "post:GetSuspiciousScores")
+	web.Router("/leaderboard/reviewSuspicious", &controllers.LeaderboardController{}, "post:ReviewSuspiciousScore")
+	web.Router("/leaderboard/reconcile", &controllers.LeaderboardController{}, "post:ReconcileLeaderboard")
+	web.Router("/leaderboard/getReconcileReport", &controllers.LeaderboardController{}, "post:GetReconcileReport")
 	// 计数器管理模块
 	web.Router("/counter/getList", &controllers.CounterController{}, "post:GetCounterList")
 	web.Router("/counter/create", &controllers.CounterController{}, "post:CreateCounter")
@@ -134,6 +150,7 @@ func init() {
 	web.Router("/counter/restore", &controllers.CounterController{}, "post:RestoreCounter")
 	web.Router("/counter/toggleStatus", &controllers.CounterController{}, "post:ToggleCounterStatus")
 	web.Router("/counter/getAllStats", &controllers.CounterController{}, "post:GetAllCounterStats")
+	web.Router("/counter/getHistory", &controllers.CounterController{}, "post:GetCounterHistory")
 	// 统计模块
 	web.Router("/stat/dashboard", &controllers.StatsController{}, "post:GetDashboardStats")
 	web.Router("/stat/getTopApps", &controllers.StatsController{}, "post:GetTopApps")
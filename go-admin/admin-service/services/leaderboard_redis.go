@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"strconv"
 	"time"
 
@@ -55,6 +56,11 @@ type LeaderboardEntry struct {
 	VipLevel interface{} `json:"vipLevel"`
 }
 
+// decodeRedisScore 还原游戏服写入的复合分数（整数部分为分数，小数部分为同分排名用的达成时间）
+func decodeRedisScore(value float64) int64 {
+	return int64(math.Floor(value))
+}
+
 // getLeaderboardKey 获取排行榜Redis键名
 func (s *LeaderboardRedisService) getLeaderboardKey(appId, leaderboardType string) string {
 	return fmt.Sprintf("leaderboard:%s:%s", appId, leaderboardType)
@@ -89,7 +95,8 @@ func (s *LeaderboardRedisService) UpdateScore(appId, leaderboardType, playerId s
 	pipe := s.client.TxPipeline()
 
 	// 检查是否需要根据更新策略处理分数
-	currentScore, err := s.client.ZScore(s.ctx, leaderboardKey, playerId).Result()
+	currentScoreValue, err := s.client.ZScore(s.ctx, leaderboardKey, playerId).Result()
+	currentScore := decodeRedisScore(currentScoreValue)
 	shouldUpdate := false
 
 	if err == redis.Nil {
@@ -101,15 +108,15 @@ func (s *LeaderboardRedisService) UpdateScore(appId, leaderboardType, playerId s
 		// 根据更新策略决定是否更新
 		switch config.UpdateStrategy {
 		case 0: // 最高分
-			if config.ScoreType == "higher_better" && score > int64(currentScore) {
+			if config.ScoreType == "higher_better" && score > currentScore {
 				shouldUpdate = true
-			} else if config.ScoreType == "lower_better" && score < int64(currentScore) {
+			} else if config.ScoreType == "lower_better" && score < currentScore {
 				shouldUpdate = true
 			}
 		case 1: // 最新分
 			shouldUpdate = true
 		case 2: // 累计分
-			score = score + int64(currentScore)
+			score = score + currentScore
 			shouldUpdate = true
 		}
 	}
@@ -171,7 +178,7 @@ func (s *LeaderboardRedisService) GetLeaderboard(appId, leaderboardType string,
 
 	// 根据排序方式获取数据
 	var results []redis.Z
-	if config.Sort == 1 { // 降序
+	if config.ScoreType != "lower_better" { // 降序
 		results, err = s.client.ZRevRangeWithScores(s.ctx, leaderboardKey, start, stop).Result()
 	} else { // 升序
 		results, err = s.client.ZRangeWithScores(s.ctx, leaderboardKey, start, stop).Result()
@@ -184,7 +191,7 @@ func (s *LeaderboardRedisService) GetLeaderboard(appId, leaderboardType string,
 	entries := make([]*LeaderboardEntry, len(results))
 	for i, result := range results {
 		playerId := result.Member.(string)
-		score := int64(result.Score)
+		score := decodeRedisScore(result.Score)
 
 		// 获取玩家详细数据
 		playerData := s.getPlayerData(appId, leaderboardType, playerId)
@@ -227,7 +234,7 @@ func (s *LeaderboardRedisService) GetPlayerRank(appId, leaderboardType, playerId
 		return 0, 0, fmt.Errorf("获取玩家分数失败: %v", err)
 	}
 
-	score = int64(scoreFloat)
+	score = decodeRedisScore(scoreFloat)
 
 	// 获取排名
 	config, err := s.getLeaderboardConfig(appId, leaderboardType)
@@ -236,7 +243,7 @@ func (s *LeaderboardRedisService) GetPlayerRank(appId, leaderboardType, playerId
 	}
 
 	var rankResult int64
-	if config.Sort == 1 { // 降序
+	if config.ScoreType != "lower_better" { // 降序
 		rankResult, err = s.client.ZRevRank(s.ctx, leaderboardKey, playerId).Result()
 	} else { // 升序
 		rankResult, err = s.client.ZRank(s.ctx, leaderboardKey, playerId).Result()
@@ -467,7 +474,7 @@ func (s *LeaderboardRedisService) removeFromMySQL(appId, leaderboardType, player
 			}
 		}()
 
-		models.DeleteLeaderboardScore(appId, leaderboardType, playerId)
+		models.DeleteLeaderboardScore(appId, leaderboardType, "", playerId)
 	}()
 }
 
@@ -510,10 +510,144 @@ func migrateSQLiteDatabase(cfg config.Configer) error {
 // executeMigrations 执行数据库迁移
 func executeMigrations(db *sql.DB, dbType string) error {
 	log.Println("检查并创建缺失的表...")
+
+	// 配置表新增字段
+	configColumns := []struct {
+		table      string
+		name       string
+		definition string
+	}{
+		{"leaderboard_config", "reward_tiers", "TEXT"},
+		{"leaderboard_config", "validation_rules", "TEXT"},
+		{"leaderboard_config", "league_config", "TEXT"},
+		{"counter_config", "scope", "VARCHAR(20) NOT NULL DEFAULT 'global'"},
+		{"counter_config", "max_value", "BIGINT NOT NULL DEFAULT 0"},
+		{"counter_config", "thresholds", "TEXT"},
+		{"apps", "data_schema", "TEXT"},
+		{"apps", "data_max_size", "INT NOT NULL DEFAULT 0"},
+		{"apps", "data_max_depth", "INT NOT NULL DEFAULT 0"},
+		{"apps", "single_device_login", "TINYINT(1) NOT NULL DEFAULT 0"},
+	}
+	for _, column := range configColumns {
+		if columnExists(db, column.table, column.name, dbType) {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", column.table, column.name, column.definition)); err != nil {
+			return fmt.Errorf("添加字段%s.%s失败: %v", column.table, column.name, err)
+		}
+		log.Printf("已添加字段: %s.%s", column.table, column.name)
+	}
+
+	// 应用数据表只在MySQL中创建
+	if dbType == "mysql" {
+		if err := migrateAppTables(db); err != nil {
+			return err
+		}
+	}
+
 	log.Println("数据库迁移完成")
 	return nil
 }
 
+// migrateAppTables 升级各应用已存在的数据表结构
+func migrateAppTables(db *sql.DB) error {
+	rows, err := db.Query("SELECT app_id FROM apps")
+	if err != nil {
+		return fmt.Errorf("查询应用列表失败: %v", err)
+	}
+	var appIds []string
+	for rows.Next() {
+		var appId string
+		if err := rows.Scan(&appId); err != nil {
+			rows.Close()
+			return fmt.Errorf("读取应用列表失败: %v", err)
+		}
+		appIds = append(appIds, appId)
+	}
+	rows.Close()
+
+	for _, appId := range appIds {
+		cleanAppId := CleanAppId(appId)
+
+		// 排行榜分区：增加partition_key字段，唯一键和分数索引按分区区分
+		leaderboardTable := "leaderboard_" + cleanAppId
+		if tableExists(db, leaderboardTable, "mysql") && !columnExists(db, leaderboardTable, "partition_key", "mysql") {
+			_, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s
+				ADD COLUMN partition_key varchar(100) NOT NULL DEFAULT '' COMMENT '分区键（如区服、地区）' AFTER type,
+				DROP INDEX uk_leaderboard_user,
+				ADD UNIQUE KEY uk_leaderboard_user (type, partition_key, player_id),
+				DROP INDEX idx_leaderboard_score,
+				ADD KEY idx_leaderboard_score (type, partition_key, score DESC)`, leaderboardTable))
+			if err != nil {
+				return fmt.Errorf("升级表%s失败: %v", leaderboardTable, err)
+			}
+			log.Printf("已升级表: %s", leaderboardTable)
+		}
+
+		historyTable := "leaderboard_history_" + cleanAppId
+		if tableExists(db, historyTable, "mysql") && !columnExists(db, historyTable, "partition_key", "mysql") {
+			_, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s
+				ADD COLUMN partition_key varchar(100) NOT NULL DEFAULT '' COMMENT '分区键（如区服、地区）' AFTER season,
+				DROP INDEX uk_season_player,
+				ADD UNIQUE KEY uk_season_player (type, season, partition_key, player_id),
+				DROP INDEX idx_season_ranking,
+				ADD KEY idx_season_ranking (type, season, partition_key, ranking)`, historyTable))
+			if err != nil {
+				return fmt.Errorf("升级表%s失败: %v", historyTable, err)
+			}
+			log.Printf("已升级表: %s", historyTable)
+		}
+
+		// 玩家计数器：增加player_id字段，唯一键按玩家区分（旧表的唯一键名不统一，按字段查找）
+		counterTable := "counter_" + cleanAppId
+		if tableExists(db, counterTable, "mysql") && !columnExists(db, counterTable, "player_id", "mysql") {
+			var uniqueKey string
+			err := db.QueryRow(`SELECT INDEX_NAME FROM information_schema.STATISTICS
+				WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND NON_UNIQUE = 0 AND INDEX_NAME <> 'PRIMARY' LIMIT 1`,
+				counterTable).Scan(&uniqueKey)
+			if err != nil {
+				return fmt.Errorf("查询表%s唯一键失败: %v", counterTable, err)
+			}
+			_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s
+				ADD COLUMN player_id varchar(100) NOT NULL DEFAULT '' COMMENT '玩家ID（全局计数器为空）' AFTER counter_key,
+				DROP INDEX %s,
+				ADD UNIQUE KEY %s (counter_key, player_id, location),
+				ADD KEY idx_player (player_id)`, counterTable, uniqueKey, uniqueKey))
+			if err != nil {
+				return fmt.Errorf("升级表%s失败: %v", counterTable, err)
+			}
+			log.Printf("已升级表: %s", counterTable)
+		}
+
+		// 玩家存档：增加data_version字段用于保存时的版本校验
+		userTable := "user_" + cleanAppId
+		if tableExists(db, userTable, "mysql") && !columnExists(db, userTable, "data_version", "mysql") {
+			_, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s
+				ADD COLUMN data_version bigint(20) NOT NULL DEFAULT 0 COMMENT '游戏数据版本号（每次保存加1）' AFTER data`, userTable))
+			if err != nil {
+				return fmt.Errorf("升级表%s失败: %v", userTable, err)
+			}
+			log.Printf("已升级表: %s", userTable)
+		}
+	}
+
+	return nil
+}
+
+// columnExists 检查表字段是否存在
+func columnExists(db *sql.DB, tableName, columnName, dbType string) bool {
+	if dbType == "mysql" {
+		var count int
+		err := db.QueryRow("SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?",
+			tableName, columnName).Scan(&count)
+		return err == nil && count > 0
+	}
+
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", tableName, columnName).Scan(&count)
+	return err == nil && count > 0
+}
+
 // tableExists 检查表是否存在
 func tableExists(db *sql.DB, tableName, dbType string) bool {
 	var query string
@@ -591,6 +725,10 @@ func getMySQLTables() []string {
 			daily_active BIGINT NOT NULL DEFAULT 0 COMMENT '日活跃用户',
 			monthly_active BIGINT NOT NULL DEFAULT 0 COMMENT '月活跃用户',
 			created_by VARCHAR(50) NOT NULL DEFAULT '' COMMENT '创建者',
+			data_schema TEXT COMMENT '玩家存档JSON Schema',
+			data_max_size INT NOT NULL DEFAULT 0 COMMENT '玩家存档最大字节数，0表示使用默认值',
+			data_max_depth INT NOT NULL DEFAULT 0 COMMENT '玩家存档最大嵌套层数，0表示使用默认值',
+			single_device_login TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否限制玩家只能在一台设备登录',
 			INDEX idx_app_id (app_id),
 			INDEX idx_status (status),
 			INDEX idx_category (category),
@@ -645,6 +783,9 @@ func getMySQLTables() []string {
 			reset_time DATETIME NULL,
 			update_strategy INT NOT NULL DEFAULT 0,
 			sort INT NOT NULL DEFAULT 1,
+			reward_tiers TEXT,
+			validation_rules TEXT,
+			league_config TEXT,
 			score_count INT NOT NULL DEFAULT 0,
 			participant_count INT NOT NULL DEFAULT 0,
 			last_reset_time DATETIME NULL,
@@ -666,6 +807,9 @@ func getMySQLTables() []string {
 			next_reset_time DATETIME NULL COMMENT '下次重置时间',
 			description TEXT NULL COMMENT '描述',
 			is_active TINYINT NOT NULL DEFAULT 1 COMMENT '是否启用',
+			scope VARCHAR(20) NOT NULL DEFAULT 'global' COMMENT '作用域: global/player',
+			max_value BIGINT NOT NULL DEFAULT 0 COMMENT '上限，0表示不限',
+			thresholds TEXT NULL COMMENT '阈值触发配置（JSON数组）',
 			created_at DATETIME NOT NULL,
 			updated_at DATETIME NOT NULL,
 			INDEX idx_app_id (app_id),
@@ -821,7 +965,11 @@ func getSQLiteTables() []string {
 			score_count INTEGER NOT NULL DEFAULT 0,
 			daily_active INTEGER NOT NULL DEFAULT 0,
 			monthly_active INTEGER NOT NULL DEFAULT 0,
-			created_by TEXT NOT NULL DEFAULT ''
+			created_by TEXT NOT NULL DEFAULT '',
+			data_schema TEXT,
+			data_max_size INTEGER NOT NULL DEFAULT 0,
+			data_max_depth INTEGER NOT NULL DEFAULT 0,
+			single_device_login INTEGER NOT NULL DEFAULT 0
 		)`,
 
 		// 分数记录表 - 对齐MySQL版本
@@ -886,6 +1034,9 @@ func getSQLiteTables() []string {
 			reset_time DATETIME NULL,
 			update_strategy INTEGER NOT NULL DEFAULT 0,
 			sort INTEGER NOT NULL DEFAULT 1,
+			reward_tiers TEXT,
+			validation_rules TEXT,
+			league_config TEXT,
 			score_count INTEGER NOT NULL DEFAULT 0,
 			participant_count INTEGER NOT NULL DEFAULT 0,
 			last_reset_time DATETIME NULL,
@@ -905,6 +1056,9 @@ func getSQLiteTables() []string {
 			next_reset_time DATETIME NULL,
 			description TEXT NULL,
 			is_active INTEGER NOT NULL DEFAULT 1,
+			scope TEXT NOT NULL DEFAULT 'global',
+			max_value INTEGER NOT NULL DEFAULT 0,
+			thresholds TEXT NULL,
 			UNIQUE(app_id, counter_key)
 		)`,
 
@@ -2,6 +2,8 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"game-service/models"
 	"game-service/utils"
 
@@ -14,32 +16,42 @@ type CounterController struct {
 
 // JSON请求结构体（对齐JS接口）
 type GetCounterRequest struct {
-	Key string `json:"key"`
+	Key      string `json:"key"`
+	PlayerId string `json:"playerId,omitempty"` // 玩家计数器必填
 }
 
 type IncrementCounterRequest struct {
 	Key       string `json:"key"`
+	PlayerId  string `json:"playerId,omitempty"`
 	Location  string `json:"location,omitempty"`
 	Increment int64  `json:"increment,omitempty"`
 }
 
 type DecrementCounterRequest struct {
 	Key       string `json:"key"`
+	PlayerId  string `json:"playerId,omitempty"`
 	Location  string `json:"location,omitempty"`
 	Decrement int64  `json:"decrement,omitempty"`
 }
 
 type SetCounterRequest struct {
 	Key      string `json:"key"`
+	PlayerId string `json:"playerId,omitempty"`
 	Location string `json:"location,omitempty"`
 	Value    int64  `json:"value"`
 }
 
 type ResetCounterRequest struct {
 	Key      string `json:"key"`
+	PlayerId string `json:"playerId,omitempty"`
 	Location string `json:"location,omitempty"`
 }
 
+type BatchCounterRequest struct {
+	PlayerId   string                  `json:"playerId,omitempty"` // 包含玩家计数器时必填
+	Operations []models.CounterBatchOp `json:"operations"`
+}
+
 // GetCounter 获取计数器（对齐JS getCounter功能）
 // 传入key，获取所有location数值
 func (c *CounterController) GetCounter() {
@@ -60,13 +72,9 @@ func (c *CounterController) GetCounter() {
 	}
 
 	// 获取计数器值
-	value, err := models.GetCounterValues(appId, req.Key)
+	value, err := models.GetCounterValues(appId, req.Key, req.PlayerId)
 	if err != nil {
-		if err.Error() == "计数器配置不存在" {
-			utils.ErrorResponse(c.Ctx, 4004, "计数器["+req.Key+"]不存在，请先在管理后台创建", nil)
-		} else {
-			utils.ErrorResponse(c.Ctx, 5001, err.Error(), nil)
-		}
+		c.counterErrorResponse(req.Key, "", 0, err)
 		return
 	}
 
@@ -108,15 +116,9 @@ func (c *CounterController) IncrementCounter() {
 	}
 
 	// 增加计数器值
-	newValue, err := models.IncrementCounterValue(appId, req.Key, location, increment)
+	newValue, err := models.IncrementCounterValue(appId, req.Key, req.PlayerId, location, increment)
 	if err != nil {
-		if err.Error() == "计数器配置不存在" {
-			utils.ErrorResponse(c.Ctx, 4004, "计数器["+req.Key+"]不存在，请先在管理后台创建", nil)
-		} else if err.Error() == "点位不存在" {
-			utils.ErrorResponse(c.Ctx, 4004, "计数器["+req.Key+"]的点位["+location+"]不存在", nil)
-		} else {
-			utils.ErrorResponse(c.Ctx, 5001, err.Error(), nil)
-		}
+		c.counterErrorResponse(req.Key, location, newValue, err)
 		return
 	}
 
@@ -159,15 +161,9 @@ func (c *CounterController) DecrementCounter() {
 	}
 
 	// 减少计数器值
-	newValue, err := models.DecrementCounterValue(appId, req.Key, location, decrement)
+	newValue, err := models.DecrementCounterValue(appId, req.Key, req.PlayerId, location, decrement)
 	if err != nil {
-		if err.Error() == "计数器配置不存在" {
-			utils.ErrorResponse(c.Ctx, 4004, "计数器["+req.Key+"]不存在，请先在管理后台创建", nil)
-		} else if err.Error() == "点位不存在" {
-			utils.ErrorResponse(c.Ctx, 4004, "计数器["+req.Key+"]的点位["+location+"]不存在", nil)
-		} else {
-			utils.ErrorResponse(c.Ctx, 5001, err.Error(), nil)
-		}
+		c.counterErrorResponse(req.Key, location, newValue, err)
 		return
 	}
 
@@ -205,15 +201,9 @@ func (c *CounterController) SetCounter() {
 	}
 
 	// 设置计数器值
-	newValue, err := models.SetCounterValue(appId, req.Key, location, req.Value)
+	newValue, err := models.SetCounterValue(appId, req.Key, req.PlayerId, location, req.Value)
 	if err != nil {
-		if err.Error() == "计数器配置不存在" {
-			utils.ErrorResponse(c.Ctx, 4004, "计数器["+req.Key+"]不存在，请先在管理后台创建", nil)
-		} else if err.Error() == "点位不存在" {
-			utils.ErrorResponse(c.Ctx, 4004, "计数器["+req.Key+"]的点位["+location+"]不存在", nil)
-		} else {
-			utils.ErrorResponse(c.Ctx, 5001, err.Error(), nil)
-		}
+		c.counterErrorResponse(req.Key, location, newValue, err)
 		return
 	}
 
@@ -251,15 +241,9 @@ func (c *CounterController) ResetCounter() {
 	}
 
 	// 重置计数器值
-	newValue, err := models.ResetCounterValue(appId, req.Key, location)
+	newValue, err := models.ResetCounterValue(appId, req.Key, req.PlayerId, location)
 	if err != nil {
-		if err.Error() == "计数器配置不存在" {
-			utils.ErrorResponse(c.Ctx, 4004, "计数器["+req.Key+"]不存在，请先在管理后台创建", nil)
-		} else if err.Error() == "点位不存在" {
-			utils.ErrorResponse(c.Ctx, 4004, "计数器["+req.Key+"]的点位["+location+"]不存在", nil)
-		} else {
-			utils.ErrorResponse(c.Ctx, 5001, err.Error(), nil)
-		}
+		c.counterErrorResponse(req.Key, location, newValue, err)
 		return
 	}
 
@@ -272,6 +256,48 @@ func (c *CounterController) ResetCounter() {
 	utils.SuccessResponse(c.Ctx, "success", result)
 }
 
+// BatchCounter 批量操作计数器
+// 在一个事务中依次执行多个计数器的get/increment/decrement操作，任一操作失败时整批均不生效
+func (c *CounterController) BatchCounter() {
+	// 从中间件获取应用ID
+	appId := c.Ctx.Input.GetData("app_id").(string)
+
+	// 解析JSON请求体
+	var req BatchCounterRequest
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		utils.ErrorResponse(c.Ctx, 4001, "参数解析失败: "+err.Error(), nil)
+		return
+	}
+
+	// 验证参数
+	if len(req.Operations) == 0 || len(req.Operations) > models.CounterBatchMaxOps {
+		utils.ErrorResponse(c.Ctx, 4001, fmt.Sprintf("参数[operations]错误，数量应为1-%d", models.CounterBatchMaxOps), nil)
+		return
+	}
+
+	// 执行批量操作
+	results, err := models.ApplyCounterBatch(appId, req.PlayerId, req.Operations)
+	if err != nil {
+		var batchErr *models.CounterBatchError
+		if !errors.As(err, &batchErr) {
+			utils.ErrorResponse(c.Ctx, 5001, err.Error(), nil)
+			return
+		}
+		if errors.Is(batchErr.Err, models.ErrCounterBatchInvalid) {
+			utils.ErrorResponse(c.Ctx, 4001, fmt.Sprintf("参数[operations][%d]错误", batchErr.Index), nil)
+			return
+		}
+		c.counterErrorResponse(batchErr.Key, batchErr.Location, batchErr.CurrentValue, batchErr.Err)
+		return
+	}
+
+	result := map[string]interface{}{
+		"results": results,
+	}
+
+	utils.SuccessResponse(c.Ctx, "success", result)
+}
+
 // GetAllCounters 获取所有计数器
 func (c *CounterController) GetAllCounters() {
 	// 从中间件获取应用ID
@@ -286,3 +312,27 @@ func (c *CounterController) GetAllCounters() {
 
 	utils.SuccessResponse(c.Ctx, "获取成功", counters)
 }
+
+// counterErrorResponse 输出计数器操作的错误响应
+func (c *CounterController) counterErrorResponse(key, location string, currentValue int64, err error) {
+	switch {
+	case errors.Is(err, models.ErrCounterNotFound):
+		utils.ErrorResponse(c.Ctx, 4004, "计数器["+key+"]不存在，请先在管理后台创建", nil)
+	case err.Error() == "点位不存在":
+		utils.ErrorResponse(c.Ctx, 4004, "计数器["+key+"]的点位["+location+"]不存在", nil)
+	case errors.Is(err, models.ErrCounterPlayerRequired):
+		utils.ErrorResponse(c.Ctx, 4001, "参数[playerId]错误", nil)
+	case errors.Is(err, models.ErrCounterReadOnly):
+		utils.ErrorResponse(c.Ctx, 4003, "计数器["+key+"]只允许增加", nil)
+	case errors.Is(err, models.ErrCounterPlayerLocked):
+		utils.ErrorResponse(c.Ctx, 4009, err.Error(), nil)
+	case errors.Is(err, models.ErrCounterLimitReached):
+		utils.ErrorResponse(c.Ctx, 4005, "计数器["+key+"]已达上限", map[string]interface{}{
+			"key":          key,
+			"location":     location,
+			"currentValue": currentValue,
+		})
+	default:
+		utils.ErrorResponse(c.Ctx, 5001, err.Error(), nil)
+	}
+}
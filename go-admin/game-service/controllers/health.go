@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/beego/beego/v2/core/logs"
 	"github.com/beego/beego/v2/server/web"
 )
 
@@ -101,6 +102,13 @@ func (c *HealthController) Heartbeat() {
 		return
 	}
 
+	// 记录会话心跳（被踢下线的会话在中间件中已返回4010）
+	if sessionId, ok := c.Ctx.Input.GetData("session_id").(string); ok {
+		if err := models.TouchUserSession(req.AppId, req.PlayerId, sessionId, c.Ctx.Input.IP()); err != nil {
+			logs.Warning("更新会话心跳失败: %v", err)
+		}
+	}
+
 	// 检查是否有新邮件 - 首先从Redis缓存检查，如果没有则查询数据库
 	hasNewMail := false
 
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"game-service/models"
 	"game-service/utils"
 	"strings"
@@ -22,6 +23,7 @@ type SubmitScoreRequest struct {
 	Ver       string `json:"ver"`
 	Sign      string `json:"sign"`
 	Type      string `json:"type"`
+	Partition string `json:"partition"` // 分区键（如区服、地区），为空表示默认分区
 	Score     int64  `json:"score"`
 	ExtraData string `json:"extraData"`
 }
@@ -35,7 +37,10 @@ type GetLeaderboardRequest struct {
 	Ver       string `json:"ver"`
 	Sign      string `json:"sign"`
 	Type      string `json:"type"`
+	Partition string `json:"partition"` // 分区键（如区服、地区），为空表示默认分区
 	Limit     int    `json:"limit"`
+	Offset    int    `json:"offset"` // 跳过的条数（按名次分页）
+	Cursor    string `json:"cursor"` // 上一页返回的nextCursor，传入时忽略offset
 }
 
 // GetUserRankRequest 获取用户排名请求
@@ -49,6 +54,82 @@ type GetUserRankRequest struct {
 	Type      string `json:"type"`
 }
 
+// GetAroundRankRequest 获取玩家周边排名请求
+type GetAroundRankRequest struct {
+	AppId     string `json:"appId"`
+	PlayerId  string `json:"playerId"`
+	Token     string `json:"token"`
+	Timestamp int64  `json:"timestamp"`
+	Ver       string `json:"ver"`
+	Sign      string `json:"sign"`
+	Type      string `json:"type"`
+	Partition string `json:"partition"` // 分区键（如区服、地区），为空表示默认分区
+	Count     int    `json:"count"`     // 前后各返回的条数
+}
+
+// GetFriendRankRequest 获取好友排行榜请求
+type GetFriendRankRequest struct {
+	AppId     string   `json:"appId"`
+	PlayerId  string   `json:"playerId"`
+	Token     string   `json:"token"`
+	Timestamp int64    `json:"timestamp"`
+	Ver       string   `json:"ver"`
+	Sign      string   `json:"sign"`
+	Type      string   `json:"type"`
+	Partition string   `json:"partition"` // 分区键（如区服、地区），为空表示默认分区
+	PlayerIds []string `json:"playerIds"` // 需要参与排名的玩家ID列表（如同玩的微信好友）
+}
+
+// maxFriendRankPlayers 好友排行榜单次查询的最大玩家数
+const maxFriendRankPlayers = 200
+
+// maxPartitionLength 排行榜分区键的最大长度
+const maxPartitionLength = 64
+
+// isValidPartition 校验排行榜分区键（不能包含冒号，避免与Redis键名分隔符冲突）
+func isValidPartition(partition string) bool {
+	return len(partition) <= maxPartitionLength && !strings.Contains(partition, ":")
+}
+
+// GetSeasonRankRequest 获取历史赛季排名请求
+type GetSeasonRankRequest struct {
+	AppId     string `json:"appId"`
+	PlayerId  string `json:"playerId"`
+	Token     string `json:"token"`
+	Timestamp int64  `json:"timestamp"`
+	Ver       string `json:"ver"`
+	Sign      string `json:"sign"`
+	Type      string `json:"type"`
+	Partition string `json:"partition"` // 分区键（如区服、地区），为空表示默认分区
+	Season    int    `json:"season"`    // 赛季号，不传表示上一赛季
+	Count     int    `json:"count"`
+}
+
+// GetHallOfFameRequest 获取名人堂请求
+type GetHallOfFameRequest struct {
+	AppId     string `json:"appId"`
+	PlayerId  string `json:"playerId"`
+	Token     string `json:"token"`
+	Timestamp int64  `json:"timestamp"`
+	Ver       string `json:"ver"`
+	Sign      string `json:"sign"`
+	Type      string `json:"type"`
+	Partition string `json:"partition"` // 分区键（如区服、地区），为空表示默认分区
+	Seasons   int    `json:"seasons"`   // 返回最近的赛季数
+	Top       int    `json:"top"`       // 每个赛季返回前几名
+}
+
+// GetLeagueRequest 获取联赛分组排名请求
+type GetLeagueRequest struct {
+	AppId     string `json:"appId"`
+	PlayerId  string `json:"playerId"`
+	Token     string `json:"token"`
+	Timestamp int64  `json:"timestamp"`
+	Ver       string `json:"ver"`
+	Sign      string `json:"sign"`
+	Type      string `json:"type"`
+}
+
 // ResetLeaderboardRequest 重置排行榜请求
 type ResetLeaderboardRequest struct {
 	AppId     string `json:"appId"`
@@ -87,6 +168,10 @@ func (c *LeaderboardController) CommitScore() {
 		utils.ErrorResponse(c.Ctx, 1002, "type参数不能为空", nil)
 		return
 	}
+	if !isValidPartition(req.Partition) {
+		utils.ErrorResponse(c.Ctx, 1002, "partition参数无效", nil)
+		return
+	}
 
 	leaderboardName := req.Type
 	score := req.Score
@@ -94,11 +179,19 @@ func (c *LeaderboardController) CommitScore() {
 	userId := req.PlayerId
 
 	// 提交分数（包含用户验证、更新策略、重置检查等）
-	err := models.SubmitScore(req.AppId, userId, leaderboardName, score, extraData)
+	err := models.SubmitScore(req.AppId, userId, leaderboardName, req.Partition, score, extraData)
 	if err != nil {
 		// 根据错误类型返回不同的错误码
 		errorCode := 1003
-		if strings.Contains(err.Error(), "用户不存在") {
+		var validationErr *models.ScoreValidationError
+		if errors.As(err, &validationErr) {
+			utils.ErrorResponse(c.Ctx, 1005, "提交分数失败: "+err.Error(), map[string]interface{}{
+				"rule": validationErr.Rule,
+			})
+			return
+		} else if errors.Is(err, models.ErrLeaderboardSettling) {
+			errorCode = 1006
+		} else if strings.Contains(err.Error(), "用户不存在") {
 			errorCode = 1004
 		} else if strings.Contains(err.Error(), "更新策略异常") {
 			errorCode = 1001
@@ -128,6 +221,15 @@ func (c *LeaderboardController) QueryTopRank() {
 		utils.ErrorResponse(c.Ctx, 1002, "type参数不能为空", nil)
 		return
 	}
+	if !isValidPartition(req.Partition) {
+		utils.ErrorResponse(c.Ctx, 1002, "partition参数无效", nil)
+		return
+	}
+
+	if req.Offset < 0 {
+		utils.ErrorResponse(c.Ctx, 1002, "offset参数无效", nil)
+		return
+	}
 
 	leaderboardName := req.Type
 	limit := req.Limit
@@ -135,20 +237,330 @@ func (c *LeaderboardController) QueryTopRank() {
 		limit = 10
 	}
 
-	// 获取排行榜（包含重置检查和用户信息）
-	rankings, err := models.GetLeaderboard(req.AppId, leaderboardName, limit)
+	// 分页获取排行榜（包含重置检查和用户信息）
+	page, err := models.GetLeaderboardPage(req.AppId, leaderboardName, req.Partition, req.Offset, limit, req.Cursor)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			utils.ErrorResponse(c.Ctx, 1002, "cursor参数无效", nil)
+			return
+		}
 		utils.ErrorResponse(c.Ctx, 1003, "获取排行榜失败: "+err.Error(), nil)
 		return
 	}
 
 	// 构建响应数据（按照JS版本的格式）
+	resultList := make([]map[string]interface{}, len(page.List))
+	for i, ranking := range page.List {
+		item := map[string]interface{}{
+			"rank":     ranking.Rank,
+			"playerId": ranking.UserId,
+			"score":    ranking.Score,
+			"userInfo": ranking.UserInfo,
+		}
+		if ranking.ExtraData != "" {
+			item["extraData"] = ranking.ExtraData
+		}
+		resultList[i] = item
+	}
+
+	// 返回结果（按照JS版本格式，附带分页信息）
+	result := map[string]interface{}{
+		"type":       leaderboardName,
+		"partition":  req.Partition,
+		"count":      len(resultList),
+		"list":       resultList,
+		"total":      page.Total,
+		"offset":     page.Offset,
+		"hasMore":    page.HasMore,
+		"nextCursor": page.NextCursor,
+	}
+
+	utils.SuccessResponse(c.Ctx, "获取成功", result)
+}
+
+// QueryAroundRank 查询玩家自身排名及前后若干名
+func (c *LeaderboardController) QueryAroundRank() {
+	// 解析请求参数
+	var req GetAroundRankRequest
+	if err := c.parseRequest(&req); err != nil {
+		utils.ErrorResponse(c.Ctx, 1002, "参数解析失败: "+err.Error(), nil)
+		return
+	}
+
+	// 参数验证
+	if req.AppId == "" {
+		utils.ErrorResponse(c.Ctx, 1002, "appId参数不能为空", nil)
+		return
+	}
+	if req.PlayerId == "" {
+		utils.ErrorResponse(c.Ctx, 1002, "playerId参数不能为空", nil)
+		return
+	}
+	if req.Type == "" {
+		utils.ErrorResponse(c.Ctx, 1002, "type参数不能为空", nil)
+		return
+	}
+	if !isValidPartition(req.Partition) {
+		utils.ErrorResponse(c.Ctx, 1002, "partition参数无效", nil)
+		return
+	}
+
+	leaderboardName := req.Type
+	count := req.Count
+	if count <= 0 || count > 50 {
+		count = 5
+	}
+
+	around, err := models.GetLeaderboardAroundUser(req.AppId, req.PlayerId, leaderboardName, req.Partition, count)
+	if err != nil {
+		utils.ErrorResponse(c.Ctx, 1003, "获取排名失败: "+err.Error(), nil)
+		return
+	}
+
+	resultList := make([]map[string]interface{}, len(around.List))
+	for i, ranking := range around.List {
+		item := map[string]interface{}{
+			"rank":     ranking.Rank,
+			"playerId": ranking.UserId,
+			"score":    ranking.Score,
+			"userInfo": ranking.UserInfo,
+		}
+		if ranking.ExtraData != "" {
+			item["extraData"] = ranking.ExtraData
+		}
+		resultList[i] = item
+	}
+
+	result := map[string]interface{}{
+		"type":      leaderboardName,
+		"partition": req.Partition,
+		"rank":      around.Rank,
+		"score":     around.Score,
+		"count":     len(resultList),
+		"list":      resultList,
+	}
+
+	utils.SuccessResponse(c.Ctx, "获取成功", result)
+}
+
+// QueryFriendRank 查询指定玩家列表（好友）的排行榜
+func (c *LeaderboardController) QueryFriendRank() {
+	// 解析请求参数
+	var req GetFriendRankRequest
+	if err := c.parseRequest(&req); err != nil {
+		utils.ErrorResponse(c.Ctx, 1002, "参数解析失败: "+err.Error(), nil)
+		return
+	}
+
+	// 参数验证
+	if req.AppId == "" {
+		utils.ErrorResponse(c.Ctx, 1002, "appId参数不能为空", nil)
+		return
+	}
+	if req.Type == "" {
+		utils.ErrorResponse(c.Ctx, 1002, "type参数不能为空", nil)
+		return
+	}
+	if !isValidPartition(req.Partition) {
+		utils.ErrorResponse(c.Ctx, 1002, "partition参数无效", nil)
+		return
+	}
+
+	// 去重，并把当前玩家自己也加入排名
+	playerIds := make([]string, 0, len(req.PlayerIds)+1)
+	seen := make(map[string]bool)
+	for _, id := range append(req.PlayerIds, req.PlayerId) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			playerIds = append(playerIds, id)
+		}
+	}
+	if len(playerIds) == 0 {
+		utils.ErrorResponse(c.Ctx, 1002, "playerIds参数不能为空", nil)
+		return
+	}
+	if len(playerIds) > maxFriendRankPlayers {
+		utils.ErrorResponse(c.Ctx, 1002, "playerIds数量超出限制", nil)
+		return
+	}
+
+	leaderboardName := req.Type
+	rankings, err := models.GetLeaderboardByPlayers(req.AppId, leaderboardName, req.Partition, playerIds)
+	if err != nil {
+		utils.ErrorResponse(c.Ctx, 1003, "获取好友排行榜失败: "+err.Error(), nil)
+		return
+	}
+
 	resultList := make([]map[string]interface{}, len(rankings))
 	for i, ranking := range rankings {
 		item := map[string]interface{}{
+			"rank":     ranking.Rank,
+			"playerId": ranking.UserId,
+			"score":    ranking.Score,
+			"userInfo": ranking.UserInfo,
+		}
+		if ranking.ExtraData != "" {
+			item["extraData"] = ranking.ExtraData
+		}
+		resultList[i] = item
+	}
+
+	result := map[string]interface{}{
+		"type":      leaderboardName,
+		"partition": req.Partition,
+		"count":     len(resultList),
+		"list":      resultList,
+	}
+
+	utils.SuccessResponse(c.Ctx, "获取成功", result)
+}
+
+// QuerySeasonRank 查询历史赛季排名及玩家自己在该赛季的名次
+func (c *LeaderboardController) QuerySeasonRank() {
+	// 解析请求参数
+	var req GetSeasonRankRequest
+	if err := c.parseRequest(&req); err != nil {
+		utils.ErrorResponse(c.Ctx, 1002, "参数解析失败: "+err.Error(), nil)
+		return
+	}
+
+	// 参数验证
+	if req.AppId == "" {
+		utils.ErrorResponse(c.Ctx, 1002, "appId参数不能为空", nil)
+		return
+	}
+	if req.Type == "" {
+		utils.ErrorResponse(c.Ctx, 1002, "type参数不能为空", nil)
+		return
+	}
+	if !isValidPartition(req.Partition) {
+		utils.ErrorResponse(c.Ctx, 1002, "partition参数无效", nil)
+		return
+	}
+
+	leaderboardName := req.Type
+	count := req.Count
+	if count <= 0 || count > 100 {
+		count = 10
+	}
+
+	season, rankings, err := models.GetLeaderboardSeasonRank(req.AppId, leaderboardName, req.Partition, req.Season, count)
+	if err != nil {
+		utils.ErrorResponse(c.Ctx, 1003, "获取赛季排名失败: "+err.Error(), nil)
+		return
+	}
+
+	result := map[string]interface{}{
+		"type":      leaderboardName,
+		"partition": req.Partition,
+		"season":    season,
+		"count":     len(rankings),
+		"list":      rankings,
+		"rank":      -1,
+		"score":     0,
+	}
+
+	// 玩家自己在该赛季的排名
+	if req.PlayerId != "" && season > 0 {
+		mine, err := models.GetUserSeasonRank(req.AppId, req.PlayerId, leaderboardName, req.Partition, season)
+		if err != nil {
+			utils.ErrorResponse(c.Ctx, 1003, "获取赛季排名失败: "+err.Error(), nil)
+			return
+		}
+		if mine != nil {
+			result["rank"] = mine.Rank
+			result["score"] = mine.Score
+		}
+	}
+
+	utils.SuccessResponse(c.Ctx, "获取成功", result)
+}
+
+// QueryHallOfFame 查询名人堂（最近若干赛季的前几名）
+func (c *LeaderboardController) QueryHallOfFame() {
+	// 解析请求参数
+	var req GetHallOfFameRequest
+	if err := c.parseRequest(&req); err != nil {
+		utils.ErrorResponse(c.Ctx, 1002, "参数解析失败: "+err.Error(), nil)
+		return
+	}
+
+	// 参数验证
+	if req.AppId == "" {
+		utils.ErrorResponse(c.Ctx, 1002, "appId参数不能为空", nil)
+		return
+	}
+	if req.Type == "" {
+		utils.ErrorResponse(c.Ctx, 1002, "type参数不能为空", nil)
+		return
+	}
+	if !isValidPartition(req.Partition) {
+		utils.ErrorResponse(c.Ctx, 1002, "partition参数无效", nil)
+		return
+	}
+
+	seasons := req.Seasons
+	if seasons <= 0 || seasons > 50 {
+		seasons = 10
+	}
+	top := req.Top
+	if top <= 0 || top > 10 {
+		top = 3
+	}
+
+	list, err := models.GetLeaderboardHallOfFame(req.AppId, req.Type, req.Partition, seasons, top)
+	if err != nil {
+		utils.ErrorResponse(c.Ctx, 1003, "获取名人堂失败: "+err.Error(), nil)
+		return
+	}
+
+	result := map[string]interface{}{
+		"type":      req.Type,
+		"partition": req.Partition,
+		"count":     len(list),
+		"list":      list,
+	}
+
+	utils.SuccessResponse(c.Ctx, "获取成功", result)
+}
+
+// QueryLeague 查询玩家所在联赛分组的排名及晋级/降级区
+func (c *LeaderboardController) QueryLeague() {
+	// 解析请求参数
+	var req GetLeagueRequest
+	if err := c.parseRequest(&req); err != nil {
+		utils.ErrorResponse(c.Ctx, 1002, "参数解析失败: "+err.Error(), nil)
+		return
+	}
+
+	// 参数验证
+	if req.AppId == "" {
+		utils.ErrorResponse(c.Ctx, 1002, "appId参数不能为空", nil)
+		return
+	}
+	if req.PlayerId == "" {
+		utils.ErrorResponse(c.Ctx, 1002, "playerId参数不能为空", nil)
+		return
+	}
+	if req.Type == "" {
+		utils.ErrorResponse(c.Ctx, 1002, "type参数不能为空", nil)
+		return
+	}
+
+	standing, err := models.GetLeagueStanding(req.AppId, req.PlayerId, req.Type)
+	if err != nil {
+		utils.ErrorResponse(c.Ctx, 1003, "获取联赛排名失败: "+err.Error(), nil)
+		return
+	}
+
+	resultList := make([]map[string]interface{}, len(standing.List))
+	for i, ranking := range standing.List {
+		item := map[string]interface{}{
+			"rank":     ranking.Rank,
 			"playerId": ranking.UserId,
 			"score":    ranking.Score,
 			"userInfo": ranking.UserInfo,
+			"zone":     standing.GetZone(ranking.Rank, len(standing.List)),
 		}
 		if ranking.ExtraData != "" {
 			item["extraData"] = ranking.ExtraData
@@ -156,11 +568,18 @@ func (c *LeaderboardController) QueryTopRank() {
 		resultList[i] = item
 	}
 
-	// 返回结果（按照JS版本格式）
 	result := map[string]interface{}{
-		"type":  leaderboardName,
-		"count": len(resultList),
-		"list":  resultList,
+		"type":          req.Type,
+		"tier":          standing.Tier,
+		"tierName":      standing.TierName,
+		"bucket":        standing.Bucket,
+		"rank":          standing.Rank,
+		"score":         standing.Score,
+		"zone":          standing.GetZone(standing.Rank, len(standing.List)),
+		"promoteCount":  standing.PromoteCount,
+		"relegateCount": standing.RelegateCount,
+		"count":         len(resultList),
+		"list":          resultList,
 	}
 
 	utils.SuccessResponse(c.Ctx, "获取成功", result)
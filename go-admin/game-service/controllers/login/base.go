@@ -1,11 +1,12 @@
 package login
 
 import (
-	"crypto/md5"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"time"
+	"game-service/models"
 
+	"github.com/beego/beego/v2/core/logs"
 	"github.com/beego/beego/v2/server/web"
 )
 
@@ -16,12 +17,14 @@ type BaseLoginController struct {
 
 // LoginData 登录响应数据结构
 type LoginData struct {
-	Token    string `json:"token"`
-	PlayerId string `json:"playerId"`
-	IsNew    bool   `json:"isNew"`
-	OpenId   string `json:"openId,omitempty"`
-	UnionId  string `json:"unionId,omitempty"`
-	Data     string `json:"data"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"` // token有效期（秒）
+	PlayerId     string `json:"playerId"`
+	IsNew        bool   `json:"isNew"`
+	OpenId       string `json:"openId,omitempty"`
+	UnionId      string `json:"unionId,omitempty"`
+	Data         string `json:"data"`
 }
 
 // CommonResponse 通用响应结构
@@ -83,13 +86,71 @@ func (c *BaseLoginController) sendResponse(response CommonResponse) {
 	c.ServeJSON()
 }
 
-// generateToken 生成token
-func generateToken(appId, playerId string) string {
-	// 生成简单的token（时间戳 + 哈希）
-	timestamp := time.Now().Unix()
-	data := fmt.Sprintf("%s:%s:%d", appId, playerId, timestamp)
-	hash := fmt.Sprintf("%x", md5.Sum([]byte(data)))
-	return fmt.Sprintf("%d:%s", timestamp, hash)
+// loginWithIdentity 根据登录身份查找或创建玩家，创建新的登录会话（之前的token全部失效）
+func (c *BaseLoginController) loginWithIdentity(appId, provider, openId, unionId string) (*LoginData, error) {
+	user, isNew, err := models.FindOrCreateUserByIdentity(appId, provider, openId, unionId)
+	if err != nil {
+		return nil, fmt.Errorf("处理用户数据失败: %w", err)
+	}
+
+	if user.IsBanned() {
+		return nil, models.ErrUserBanned
+	}
+
+	session, err := models.CreateUserSession(appId, user.PlayerId, c.requestDevice(), c.Ctx.Input.IP())
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginData{
+		Token:        session.Token,
+		RefreshToken: session.RefreshToken,
+		ExpiresIn:    session.ExpiresIn,
+		PlayerId:     user.PlayerId,
+		IsNew:        isNew,
+		OpenId:       openId,
+		UnionId:      unionId,
+		Data:         user.Data,
+	}, nil
+}
+
+// requestDevice 获取登录设备描述，优先使用请求体中的device字段，否则使用User-Agent
+func (c *BaseLoginController) requestDevice() string {
+	var req struct {
+		Device string `json:"device"`
+	}
+	json.Unmarshal(c.Ctx.Input.RequestBody, &req)
+	if req.Device == "" {
+		req.Device = c.Ctx.Input.UserAgent()
+	}
+	if len(req.Device) > 200 {
+		req.Device = req.Device[:200]
+	}
+	return req.Device
+}
+
+// createLoginErrorResponse 创建登录失败响应，封禁玩家返回4003
+func (c *BaseLoginController) createLoginErrorResponse(err error) CommonResponse {
+	if errors.Is(err, models.ErrUserBanned) {
+		return c.createErrorResponse(4003, err.Error())
+	}
+	if errors.Is(err, models.ErrGuestIdentityLinked) {
+		return c.createErrorResponse(4009, models.ErrGuestIdentityLinked.Error())
+	}
+	return c.createErrorResponse(5001, err.Error())
+}
+
+// getActiveApp 获取应用配置，应用不存在或已禁用时返回错误
+func (c *BaseLoginController) getActiveApp(appId string) (*models.Application, error) {
+	app := &models.Application{}
+	if err := app.GetByAppId(appId); err != nil {
+		logs.Error("获取应用配置失败:", err)
+		return nil, err
+	}
+	if app.Status != "active" {
+		return nil, fmt.Errorf("应用已禁用")
+	}
+	return app, nil
 }
 
 // validateSignature 验证签名（预留）
@@ -1,9 +1,7 @@
 package login
 
 import (
-	"fmt"
 	"game-service/models"
-	"game-service/utils"
 
 	"github.com/beego/beego/v2/core/logs"
 )
@@ -54,7 +52,7 @@ func (c *CommonLoginController) CommonLogin() {
 	// 处理登录逻辑
 	loginData, err := c.processCommonLogin(req.AppId, req.OpenId, req.UnionId)
 	if err != nil {
-		ret := c.createErrorResponse(5001, err.Error())
+		ret := c.createLoginErrorResponse(err)
 		c.sendResponse(ret)
 		return
 	}
@@ -65,59 +63,17 @@ func (c *CommonLoginController) CommonLogin() {
 
 // processCommonLogin 处理通用登录逻辑
 func (c *CommonLoginController) processCommonLogin(appId, openId, unionId string) (*LoginData, error) {
-	// 查找或创建用户
-	user, isNew, err := c.findOrCreateUser(appId, openId, unionId)
+	loginData, err := c.loginWithIdentity(appId, models.IdentityProviderCommon, openId, unionId)
 	if err != nil {
-		return nil, fmt.Errorf("处理用户数据失败: %v", err)
+		return nil, err
 	}
 
-	// 生成token
-	token := generateToken(appId, user.PlayerId)
-
 	// 更新最后登录时间
-	if err := c.updateLastLoginTime(user.PlayerId); err != nil {
+	if err := c.updateLastLoginTime(loginData.PlayerId); err != nil {
 		logs.Warning("更新最后登录时间失败:", err)
 	}
 
-	// 保存用户token到redis
-	if err := models.SaveUserStatusToRedis(appId, user.PlayerId, token); err != nil {
-		logs.Warning("保存用户token到redis失败:", err)
-	}
-
-	return &LoginData{
-		Token:    token,
-		PlayerId: user.PlayerId,
-		IsNew:    isNew,
-		OpenId:   openId,
-		UnionId:  unionId,
-	}, nil
-}
-
-// findOrCreateUser 查找或创建用户
-func (c *CommonLoginController) findOrCreateUser(appId, openId, unionId string) (*models.User, bool, error) {
-	// 尝试根据openId查找用户
-	user, err := models.GetUserByOpenId(appId, openId)
-	if err == nil && user != nil {
-		// 用户已存在
-		return user, false, nil
-	}
-
-	// 用户不存在，创建新用户
-	playerId := utils.GeneratePlayerId()
-
-	newUser := &models.User{
-		AppId:    appId,
-		PlayerId: playerId,
-		OpenId:   openId,
-		Data:     "{}",
-	}
-
-	if err := models.CreateUser(appId, newUser); err != nil {
-		return nil, false, fmt.Errorf("创建用户失败: %v", err)
-	}
-
-	logs.Info("创建新用户成功, appId:", appId, "playerId:", playerId, "openId:", openId)
-	return newUser, true, nil
+	return loginData, nil
 }
 
 // updateLastLoginTime 更新最后登录时间
@@ -1,7 +1,25 @@
 package login
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"game-service/models"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// defaultDouyinCode2SessionURL 抖音code2session接口地址（可通过douyin_code2session_url配置修改）
+const defaultDouyinCode2SessionURL = "https://developer.toutiao.com/api/apps/v2/jscode2session"
+
+// douyinHTTPClient 调用抖音接口使用的HTTP客户端
+var douyinHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
 // DouyinLoginController 抖音登录控制器
-// 预留抖音小程序登录功能
 type DouyinLoginController struct {
 	BaseLoginController
 }
@@ -28,15 +46,96 @@ type DouyinAPIResponse struct {
 
 // DouyinLogin 抖音登录接口
 func (c *DouyinLoginController) DouyinLogin() {
-	// TODO: 实现抖音登录逻辑
-	ret := c.createErrorResponse(5000, "抖音登录功能暂未实现")
+	var req DouyinLoginRequest
+
+	// 解析请求参数
+	if err := c.parseRequest(&req); err != nil {
+		ret := c.createErrorResponse(4001, "参数解析失败: "+err.Error())
+		c.sendResponse(ret)
+		return
+	}
+
+	// 验证基础参数
+	if errResp := c.validateBasicParams(req.AppId, req.Code); errResp != nil {
+		c.sendResponse(*errResp)
+		return
+	}
+
+	// 获取应用配置
+	appConfig, err := c.getActiveApp(req.AppId)
+	if err != nil {
+		ret := c.createErrorResponse(4004, "appId不存在或配置错误")
+		c.sendResponse(ret)
+		return
+	}
+
+	// 调用抖音API获取openId
+	dyResp, err := c.processDouyinAuth(appConfig.ChannelAppId, appConfig.ChannelAppKey, req.Code)
+	if err != nil {
+		ret := c.createErrorResponse(4004, "抖音登录失败: "+err.Error())
+		c.sendResponse(ret)
+		return
+	}
+
+	// 处理登录逻辑
+	loginData, err := c.loginWithIdentity(req.AppId, models.IdentityProviderDouyin, dyResp.Data.OpenId, dyResp.Data.UnionId)
+	if err != nil {
+		ret := c.createLoginErrorResponse(err)
+		c.sendResponse(ret)
+		return
+	}
+
+	ret := c.createSuccessResponse(loginData)
 	c.sendResponse(ret)
 }
 
-// processDouyinAuth 处理抖音授权
-func (c *DouyinLoginController) processDouyinAuth(code string) (*DouyinAPIResponse, error) {
-	// TODO: 调用抖音API获取用户信息
-	// 1. 使用code换取session_key和openid
-	// 2. 返回用户信息
-	return nil, nil
+// processDouyinAuth 使用code换取抖音小游戏的openid和session_key
+func (c *DouyinLoginController) processDouyinAuth(appId, appSecret, code string) (*DouyinAPIResponse, error) {
+	payload, _ := json.Marshal(map[string]string{
+		"appid":  appId,
+		"secret": appSecret,
+		"code":   code,
+	})
+
+	// 发起HTTP请求
+	endpoint := web.AppConfig.DefaultString("douyin_code2session_url", defaultDouyinCode2SessionURL)
+	resp, err := douyinHTTPClient.Post(endpoint, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		logs.Error("调用抖音API失败:", err)
+		return nil, fmt.Errorf("网络请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 读取响应
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		logs.Error("读取抖音API响应失败:", err)
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		logs.Error("抖音API返回HTTP错误:", resp.StatusCode, "响应内容:", string(body))
+		return nil, fmt.Errorf("抖音API请求失败 (HTTP %d)", resp.StatusCode)
+	}
+
+	// 解析响应JSON
+	var dyResp DouyinAPIResponse
+	if err := json.Unmarshal(body, &dyResp); err != nil {
+		logs.Error("解析抖音API响应失败:", err, "响应内容:", string(body))
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	// 检查抖音API错误
+	if dyResp.ErrNo != 0 {
+		logs.Error("抖音API返回错误:", dyResp.ErrNo, dyResp.ErrTips)
+		return nil, fmt.Errorf("抖音API错误: %s (code: %d)", dyResp.ErrTips, dyResp.ErrNo)
+	}
+
+	if dyResp.Data.OpenId == "" {
+		logs.Error("抖音API未返回openid")
+		return nil, fmt.Errorf("抖音API未返回有效的openid")
+	}
+
+	logs.Info("抖音登录成功, openId:", dyResp.Data.OpenId, "unionId:", dyResp.Data.UnionId)
+	return &dyResp, nil
 }
@@ -0,0 +1,104 @@
+package login
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// newDouyinStubServer 启动模拟抖音code2session接口的本地服务，并把接口地址指向该服务
+func newDouyinStubServer(t *testing.T, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	if err := web.AppConfig.Set("douyin_code2session_url", server.URL+"/api/apps/v2/jscode2session"); err != nil {
+		t.Fatalf("设置抖音接口地址失败: %v", err)
+	}
+	t.Cleanup(func() {
+		web.AppConfig.Set("douyin_code2session_url", defaultDouyinCode2SessionURL)
+	})
+}
+
+// TestProcessDouyinAuth 抖音code2session接口调用测试（使用本地模拟服务）
+func TestProcessDouyinAuth(t *testing.T) {
+	c := &DouyinLoginController{}
+
+	t.Run("换取openid成功", func(t *testing.T) {
+		newDouyinStubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("请求方法应为POST，实际为%s", r.Method)
+			}
+			if r.URL.Path != "/api/apps/v2/jscode2session" {
+				t.Errorf("请求路径错误: %s", r.URL.Path)
+			}
+			if ct := r.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+				t.Errorf("Content-Type应为application/json，实际为%s", ct)
+			}
+
+			var body map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Fatalf("解析请求体失败: %v", err)
+			}
+			if body["appid"] != "tt_app" || body["secret"] != "tt_secret" || body["code"] != "login_code" {
+				t.Errorf("请求参数错误: %v", body)
+			}
+
+			w.Write([]byte(`{"err_no":0,"err_tips":"success","data":{"session_key":"key","openid":"dy_openid","unionid":"dy_unionid"}}`))
+		})
+
+		resp, err := c.processDouyinAuth("tt_app", "tt_secret", "login_code")
+		if err != nil {
+			t.Fatalf("换取openid失败: %v", err)
+		}
+		if resp.Data.OpenId != "dy_openid" || resp.Data.UnionId != "dy_unionid" || resp.Data.SessionKey != "key" {
+			t.Errorf("返回数据错误: %+v", resp.Data)
+		}
+	})
+
+	t.Run("抖音返回错误码", func(t *testing.T) {
+		newDouyinStubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"err_no":40015,"err_tips":"bad code","data":{}}`))
+		})
+
+		_, err := c.processDouyinAuth("tt_app", "tt_secret", "bad_code")
+		if err == nil || !strings.Contains(err.Error(), "bad code") || !strings.Contains(err.Error(), "40015") {
+			t.Errorf("应返回抖音错误信息，实际为: %v", err)
+		}
+	})
+
+	t.Run("未返回openid", func(t *testing.T) {
+		newDouyinStubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"err_no":0,"err_tips":"success","data":{"session_key":"key"}}`))
+		})
+
+		if _, err := c.processDouyinAuth("tt_app", "tt_secret", "login_code"); err == nil {
+			t.Error("未返回openid时应返回错误")
+		}
+	})
+
+	t.Run("HTTP状态码错误", func(t *testing.T) {
+		newDouyinStubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`bad gateway`))
+		})
+
+		_, err := c.processDouyinAuth("tt_app", "tt_secret", "login_code")
+		if err == nil || !strings.Contains(err.Error(), "502") {
+			t.Errorf("应返回HTTP错误，实际为: %v", err)
+		}
+	})
+
+	t.Run("响应不是JSON", func(t *testing.T) {
+		newDouyinStubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`<html></html>`))
+		})
+
+		if _, err := c.processDouyinAuth("tt_app", "tt_secret", "login_code"); err == nil {
+			t.Error("响应不是JSON时应返回错误")
+		}
+	})
+}
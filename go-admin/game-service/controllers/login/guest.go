@@ -0,0 +1,68 @@
+package login
+
+import (
+	"game-service/models"
+	"regexp"
+)
+
+// GuestLoginController 游客登录控制器
+// 使用客户端生成的设备ID登录，之后可以通过/user/identity/link绑定平台账号，保留游戏进度
+// 绑定平台账号后设备ID不能再登录该玩家，需使用平台账号登录
+type GuestLoginController struct {
+	BaseLoginController
+}
+
+// GuestLoginRequest 游客登录请求结构
+type GuestLoginRequest struct {
+	AppId     string `json:"appId"`     // 应用ID
+	DeviceId  string `json:"deviceId"`  // 客户端生成的设备ID
+	Timestamp int64  `json:"timestamp"` // 时间戳
+	Ver       string `json:"ver"`       // 版本号
+	Sign      string `json:"sign"`      // 签名
+}
+
+// deviceIdPattern 设备ID格式：8-64位字母、数字、下划线或中划线（如UUID）
+var deviceIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{8,64}$`)
+
+// GuestLogin 游客登录接口
+func (c *GuestLoginController) GuestLogin() {
+	var req GuestLoginRequest
+
+	// 解析请求参数
+	if err := c.parseRequest(&req); err != nil {
+		ret := c.createErrorResponse(4001, "参数解析失败: "+err.Error())
+		c.sendResponse(ret)
+		return
+	}
+
+	// 验证基础参数
+	if req.AppId == "" {
+		ret := c.createErrorResponse(4001, "appId不能为空")
+		c.sendResponse(ret)
+		return
+	}
+
+	if !deviceIdPattern.MatchString(req.DeviceId) {
+		ret := c.createErrorResponse(4001, "deviceId格式错误，应为8-64位字母、数字、下划线或中划线")
+		c.sendResponse(ret)
+		return
+	}
+
+	// 验证应用是否存在
+	if _, err := c.getActiveApp(req.AppId); err != nil {
+		ret := c.createErrorResponse(4004, "appId不存在或已禁用")
+		c.sendResponse(ret)
+		return
+	}
+
+	// 处理登录逻辑
+	loginData, err := c.loginWithIdentity(req.AppId, models.IdentityProviderGuest, req.DeviceId, "")
+	if err != nil {
+		ret := c.createLoginErrorResponse(err)
+		c.sendResponse(ret)
+		return
+	}
+
+	ret := c.createSuccessResponse(loginData)
+	c.sendResponse(ret)
+}
@@ -0,0 +1,161 @@
+package login
+
+import (
+	"errors"
+	"fmt"
+	"game-service/models"
+)
+
+// IdentityController 登录身份绑定控制器（同一玩家绑定多个渠道的账号）
+type IdentityController struct {
+	BaseLoginController
+}
+
+// IdentityRequest 绑定/解绑登录身份请求结构
+type IdentityRequest struct {
+	AppId     string `json:"appId"`     // 应用ID
+	PlayerId  string `json:"playerId"`  // 玩家ID
+	Token     string `json:"token"`     // 登录Token
+	Provider  string `json:"provider"`  // 渠道：wx/qq/douyin/yalla/common
+	Code      string `json:"code"`      // 渠道授权码（yalla为sdkUserId）
+	Timestamp int64  `json:"timestamp"` // 时间戳
+	Ver       string `json:"ver"`       // 版本号
+	Sign      string `json:"sign"`      // 签名
+}
+
+// LinkIdentity 绑定其他渠道的账号到当前玩家（游客绑定平台账号后保留原有进度）
+func (c *IdentityController) LinkIdentity() {
+	var req IdentityRequest
+	if !c.parseIdentityRequest(&req) {
+		return
+	}
+
+	if req.Code == "" {
+		c.sendResponse(c.createErrorResponse(4001, "code不能为空"))
+		return
+	}
+
+	openId, unionId, err := c.resolveIdentity(req.AppId, req.Provider, req.Code)
+	if err != nil {
+		c.sendResponse(c.createErrorResponse(4004, err.Error()))
+		return
+	}
+
+	identity, err := models.LinkUserIdentity(req.AppId, req.PlayerId, req.Provider, openId, unionId)
+	switch {
+	case errors.Is(err, models.ErrIdentityLinkedToOther), errors.Is(err, models.ErrIdentityProviderLinked):
+		c.sendResponse(c.createErrorResponse(4009, err.Error()))
+	case err != nil:
+		c.sendResponse(c.createErrorResponse(5001, "绑定失败: "+err.Error()))
+	default:
+		c.sendResponse(c.createSuccessResponse(identity))
+	}
+}
+
+// UnlinkIdentity 解绑当前玩家某个渠道的账号
+func (c *IdentityController) UnlinkIdentity() {
+	var req IdentityRequest
+	if !c.parseIdentityRequest(&req) {
+		return
+	}
+
+	err := models.UnlinkUserIdentity(req.AppId, req.PlayerId, req.Provider)
+	switch {
+	case errors.Is(err, models.ErrIdentityNotFound):
+		c.sendResponse(c.createErrorResponse(4004, err.Error()))
+	case errors.Is(err, models.ErrIdentityLastOne):
+		c.sendResponse(c.createErrorResponse(4003, err.Error()))
+	case err != nil:
+		c.sendResponse(c.createErrorResponse(5001, "解绑失败: "+err.Error()))
+	default:
+		c.sendResponse(c.createSuccessResponse(nil))
+	}
+}
+
+// ListIdentities 获取当前玩家绑定的所有登录身份
+func (c *IdentityController) ListIdentities() {
+	var req IdentityRequest
+	if err := c.parseRequest(&req); err != nil {
+		c.sendResponse(c.createErrorResponse(4001, "参数解析失败: "+err.Error()))
+		return
+	}
+	if req.AppId == "" || req.PlayerId == "" {
+		c.sendResponse(c.createErrorResponse(4001, "appId和playerId不能为空"))
+		return
+	}
+
+	identities, err := models.ListUserIdentities(req.AppId, req.PlayerId)
+	if err != nil {
+		c.sendResponse(c.createErrorResponse(5001, "获取登录身份失败: "+err.Error()))
+		return
+	}
+
+	c.sendResponse(c.createSuccessResponse(map[string]interface{}{
+		"list": identities,
+	}))
+}
+
+// parseIdentityRequest 解析请求并校验基础参数，失败时直接输出错误响应
+func (c *IdentityController) parseIdentityRequest(req *IdentityRequest) bool {
+	if err := c.parseRequest(req); err != nil {
+		c.sendResponse(c.createErrorResponse(4001, "参数解析失败: "+err.Error()))
+		return false
+	}
+	if req.AppId == "" || req.PlayerId == "" {
+		c.sendResponse(c.createErrorResponse(4001, "appId和playerId不能为空"))
+		return false
+	}
+	if req.Provider == "" {
+		c.sendResponse(c.createErrorResponse(4001, "provider不能为空"))
+		return false
+	}
+	return true
+}
+
+// resolveIdentity 使用渠道授权码换取openId和unionId（与对应渠道的登录接口一致）
+func (c *IdentityController) resolveIdentity(appId, provider, code string) (string, string, error) {
+	switch provider {
+	case models.IdentityProviderCommon:
+		return code, "", nil
+	case models.IdentityProviderWechat:
+		wechat := &WechatLoginController{}
+		appConfig, err := wechat.getAppConfig(appId)
+		if err != nil {
+			return "", "", fmt.Errorf("appId不存在或配置错误")
+		}
+		wxResp, err := wechat.callWxAPI(appConfig.ChannelAppId, appConfig.ChannelAppKey, code)
+		if err != nil {
+			return "", "", fmt.Errorf("微信授权失败: %v", err)
+		}
+		return wxResp.OpenId, wxResp.UnionId, nil
+	case models.IdentityProviderQQ:
+		qq := &QQLoginController{}
+		appConfig, err := qq.getActiveApp(appId)
+		if err != nil {
+			return "", "", fmt.Errorf("appId不存在或配置错误")
+		}
+		qqResp, err := qq.processQQAuth(appConfig.ChannelAppId, appConfig.ChannelAppKey, code)
+		if err != nil {
+			return "", "", fmt.Errorf("QQ授权失败: %v", err)
+		}
+		return qqResp.OpenId, qqResp.UnionId, nil
+	case models.IdentityProviderDouyin:
+		douyin := &DouyinLoginController{}
+		appConfig, err := douyin.getActiveApp(appId)
+		if err != nil {
+			return "", "", fmt.Errorf("appId不存在或配置错误")
+		}
+		dyResp, err := douyin.processDouyinAuth(appConfig.ChannelAppId, appConfig.ChannelAppKey, code)
+		if err != nil {
+			return "", "", fmt.Errorf("抖音授权失败: %v", err)
+		}
+		return dyResp.Data.OpenId, dyResp.Data.UnionId, nil
+	case models.IdentityProviderYalla:
+		yalla := &YallaLoginController{}
+		if err := yalla.validateYallaUser(appId, code); err != nil {
+			return "", "", fmt.Errorf("Yalla用户验证失败: %v", err)
+		}
+		return code, "", nil
+	}
+	return "", "", fmt.Errorf("暂不支持绑定%s账号", provider)
+}
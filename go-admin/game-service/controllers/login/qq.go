@@ -1,7 +1,25 @@
 package login
 
+import (
+	"encoding/json"
+	"fmt"
+	"game-service/models"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// defaultQQCode2SessionURL QQ小程序code2session接口地址（可通过qq_code2session_url配置修改）
+const defaultQQCode2SessionURL = "https://api.q.qq.com/sns/jscode2session"
+
+// qqHTTPClient 调用QQ接口使用的HTTP客户端
+var qqHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
 // QQLoginController QQ登录控制器
-// 预留QQ小程序登录功能
 type QQLoginController struct {
 	BaseLoginController
 }
@@ -26,15 +44,95 @@ type QQAPIResponse struct {
 
 // QQLogin QQ登录接口
 func (c *QQLoginController) QQLogin() {
-	// TODO: 实现QQ登录逻辑
-	ret := c.createErrorResponse(5000, "QQ登录功能暂未实现")
+	var req QQLoginRequest
+
+	// 解析请求参数
+	if err := c.parseRequest(&req); err != nil {
+		ret := c.createErrorResponse(4001, "参数解析失败: "+err.Error())
+		c.sendResponse(ret)
+		return
+	}
+
+	// 验证基础参数
+	if errResp := c.validateBasicParams(req.AppId, req.Code); errResp != nil {
+		c.sendResponse(*errResp)
+		return
+	}
+
+	// 获取应用配置
+	appConfig, err := c.getActiveApp(req.AppId)
+	if err != nil {
+		ret := c.createErrorResponse(4004, "appId不存在或配置错误")
+		c.sendResponse(ret)
+		return
+	}
+
+	// 调用QQ API获取openId
+	qqResp, err := c.processQQAuth(appConfig.ChannelAppId, appConfig.ChannelAppKey, req.Code)
+	if err != nil {
+		ret := c.createErrorResponse(4004, "QQ登录失败: "+err.Error())
+		c.sendResponse(ret)
+		return
+	}
+
+	// 处理登录逻辑
+	loginData, err := c.loginWithIdentity(req.AppId, models.IdentityProviderQQ, qqResp.OpenId, qqResp.UnionId)
+	if err != nil {
+		ret := c.createLoginErrorResponse(err)
+		c.sendResponse(ret)
+		return
+	}
+
+	ret := c.createSuccessResponse(loginData)
 	c.sendResponse(ret)
 }
 
-// processQQAuth 处理QQ授权
-func (c *QQLoginController) processQQAuth(code string) (*QQAPIResponse, error) {
-	// TODO: 调用QQ API获取用户信息
-	// 1. 使用code换取session_key和openid
-	// 2. 返回用户信息
-	return nil, nil
+// processQQAuth 使用code换取QQ小程序的openid和session_key
+func (c *QQLoginController) processQQAuth(appId, appSecret, code string) (*QQAPIResponse, error) {
+	query := url.Values{}
+	query.Set("appid", appId)
+	query.Set("secret", appSecret)
+	query.Set("js_code", code)
+	query.Set("grant_type", "authorization_code")
+
+	// 发起HTTP请求
+	endpoint := web.AppConfig.DefaultString("qq_code2session_url", defaultQQCode2SessionURL)
+	resp, err := qqHTTPClient.Get(endpoint + "?" + query.Encode())
+	if err != nil {
+		logs.Error("调用QQ API失败:", err)
+		return nil, fmt.Errorf("网络请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 读取响应
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		logs.Error("读取QQ API响应失败:", err)
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		logs.Error("QQ API返回HTTP错误:", resp.StatusCode, "响应内容:", string(body))
+		return nil, fmt.Errorf("QQ API请求失败 (HTTP %d)", resp.StatusCode)
+	}
+
+	// 解析响应JSON
+	var qqResp QQAPIResponse
+	if err := json.Unmarshal(body, &qqResp); err != nil {
+		logs.Error("解析QQ API响应失败:", err, "响应内容:", string(body))
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	// 检查QQ API错误
+	if qqResp.ErrCode != 0 {
+		logs.Error("QQ API返回错误:", qqResp.ErrCode, qqResp.ErrMsg)
+		return nil, fmt.Errorf("QQ API错误: %s (code: %d)", qqResp.ErrMsg, qqResp.ErrCode)
+	}
+
+	if qqResp.OpenId == "" {
+		logs.Error("QQ API未返回openid")
+		return nil, fmt.Errorf("QQ API未返回有效的openid")
+	}
+
+	return &qqResp, nil
 }
@@ -0,0 +1,110 @@
+package login
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// newQQStubServer 启动模拟QQ code2session接口的本地服务，并把接口地址指向该服务
+func newQQStubServer(t *testing.T, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	if err := web.AppConfig.Set("qq_code2session_url", server.URL+"/sns/jscode2session"); err != nil {
+		t.Fatalf("设置QQ接口地址失败: %v", err)
+	}
+	t.Cleanup(func() {
+		web.AppConfig.Set("qq_code2session_url", defaultQQCode2SessionURL)
+	})
+}
+
+// TestProcessQQAuth QQ code2session接口调用测试（使用本地模拟服务）
+func TestProcessQQAuth(t *testing.T) {
+	c := &QQLoginController{}
+
+	t.Run("换取openid成功", func(t *testing.T) {
+		newQQStubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				t.Errorf("请求方法应为GET，实际为%s", r.Method)
+			}
+			if r.URL.Path != "/sns/jscode2session" {
+				t.Errorf("请求路径错误: %s", r.URL.Path)
+			}
+
+			query := r.URL.Query()
+			if query.Get("appid") != "qq_app" || query.Get("secret") != "qq_secret" || query.Get("js_code") != "login_code" || query.Get("grant_type") != "authorization_code" {
+				t.Errorf("请求参数错误: %v", query)
+			}
+
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok","session_key":"key","openid":"qq_openid","unionid":"qq_unionid"}`))
+		})
+
+		resp, err := c.processQQAuth("qq_app", "qq_secret", "login_code")
+		if err != nil {
+			t.Fatalf("换取openid失败: %v", err)
+		}
+		if resp.OpenId != "qq_openid" || resp.UnionId != "qq_unionid" || resp.SessionKey != "key" {
+			t.Errorf("返回数据错误: %+v", resp)
+		}
+	})
+
+	t.Run("参数需要转义", func(t *testing.T) {
+		newQQStubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if code := r.URL.Query().Get("js_code"); code != "a&b=c" {
+				t.Errorf("js_code应为a&b=c，实际为%s", code)
+			}
+			w.Write([]byte(`{"errcode":0,"openid":"qq_openid"}`))
+		})
+
+		if _, err := c.processQQAuth("qq_app", "qq_secret", "a&b=c"); err != nil {
+			t.Fatalf("换取openid失败: %v", err)
+		}
+	})
+
+	t.Run("QQ返回错误码", func(t *testing.T) {
+		newQQStubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"errcode":40029,"errmsg":"invalid code"}`))
+		})
+
+		_, err := c.processQQAuth("qq_app", "qq_secret", "bad_code")
+		if err == nil || !strings.Contains(err.Error(), "invalid code") || !strings.Contains(err.Error(), "40029") {
+			t.Errorf("应返回QQ错误信息，实际为: %v", err)
+		}
+	})
+
+	t.Run("未返回openid", func(t *testing.T) {
+		newQQStubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"errcode":0,"session_key":"key"}`))
+		})
+
+		if _, err := c.processQQAuth("qq_app", "qq_secret", "login_code"); err == nil {
+			t.Error("未返回openid时应返回错误")
+		}
+	})
+
+	t.Run("HTTP状态码错误", func(t *testing.T) {
+		newQQStubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`bad gateway`))
+		})
+
+		_, err := c.processQQAuth("qq_app", "qq_secret", "login_code")
+		if err == nil || !strings.Contains(err.Error(), "502") {
+			t.Errorf("应返回HTTP错误，实际为: %v", err)
+		}
+	})
+
+	t.Run("响应不是JSON", func(t *testing.T) {
+		newQQStubServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`<html></html>`))
+		})
+
+		if _, err := c.processQQAuth("qq_app", "qq_secret", "login_code"); err == nil {
+			t.Error("响应不是JSON时应返回错误")
+		}
+	})
+}
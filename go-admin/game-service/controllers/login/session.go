@@ -0,0 +1,75 @@
+package login
+
+import (
+	"errors"
+	"game-service/models"
+)
+
+// SessionController 登录会话控制器（刷新token、登出）
+type SessionController struct {
+	BaseLoginController
+}
+
+// SessionRequest 会话请求结构
+type SessionRequest struct {
+	AppId        string `json:"appId"`        // 应用ID
+	PlayerId     string `json:"playerId"`     // 玩家ID
+	Token        string `json:"token"`        // 登录Token
+	RefreshToken string `json:"refreshToken"` // 刷新Token（仅刷新时使用）
+	Timestamp    int64  `json:"timestamp"`    // 时间戳
+	Ver          string `json:"ver"`          // 版本号
+	Sign         string `json:"sign"`         // 签名
+}
+
+// RefreshToken 使用refreshToken换取新的token（token过期后无需重新走渠道登录）
+func (c *SessionController) RefreshToken() {
+	var req SessionRequest
+	if err := c.parseRequest(&req); err != nil {
+		c.sendResponse(c.createErrorResponse(4001, "参数解析失败: "+err.Error()))
+		return
+	}
+	if req.AppId == "" || req.PlayerId == "" {
+		c.sendResponse(c.createErrorResponse(4001, "appId和playerId不能为空"))
+		return
+	}
+	if req.RefreshToken == "" {
+		c.sendResponse(c.createErrorResponse(4001, "refreshToken不能为空"))
+		return
+	}
+
+	session, err := models.RefreshUserSession(req.AppId, req.PlayerId, req.RefreshToken)
+	var kicked *models.SessionKickedError
+	switch {
+	case errors.Is(err, models.ErrRefreshTokenInvalid):
+		c.sendResponse(c.createErrorResponse(401, err.Error()))
+	case errors.Is(err, models.ErrUserBanned):
+		c.sendResponse(c.createErrorResponse(4003, err.Error()))
+	case errors.As(err, &kicked):
+		c.sendResponse(c.createErrorResponse(4010, err.Error()))
+	case err != nil:
+		c.sendResponse(c.createErrorResponse(5001, "刷新token失败: "+err.Error()))
+	default:
+		c.sendResponse(c.createSuccessResponse(session))
+	}
+}
+
+// Logout 登出，当前设备的token和refreshToken立即失效
+func (c *SessionController) Logout() {
+	var req SessionRequest
+	if err := c.parseRequest(&req); err != nil {
+		c.sendResponse(c.createErrorResponse(4001, "参数解析失败: "+err.Error()))
+		return
+	}
+	if req.AppId == "" || req.PlayerId == "" {
+		c.sendResponse(c.createErrorResponse(4001, "appId和playerId不能为空"))
+		return
+	}
+
+	sessionId, _ := c.Ctx.Input.GetData("session_id").(string)
+	if err := models.RevokeUserSession(req.AppId, req.PlayerId, sessionId); err != nil {
+		c.sendResponse(c.createErrorResponse(5001, "登出失败: "+err.Error()))
+		return
+	}
+
+	c.sendResponse(c.createSuccessResponse(nil))
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"game-service/models"
-	"game-service/utils"
 	"io/ioutil"
 	"net/http"
 
@@ -70,16 +69,11 @@ func (c *WechatLoginController) WxLogin() {
 	// 处理登录逻辑
 	loginData, err := c.processLogin(req.AppId, wxResp.OpenId, wxResp.UnionId)
 	if err != nil {
-		ret := c.createErrorResponse(5001, err.Error())
+		ret := c.createLoginErrorResponse(err)
 		c.sendResponse(ret)
 		return
 	}
 
-	// 保存用户token到redis
-	if err := models.SaveUserStatusToRedis(req.AppId, loginData.PlayerId, loginData.Token); err != nil {
-		logs.Warning("保存用户token到redis失败:", err)
-	}
-
 	ret := c.createSuccessResponse(loginData)
 	c.sendResponse(ret)
 }
@@ -139,57 +133,19 @@ func (c *WechatLoginController) callWxAPI(appId, appSecret, code string) (*WxAPI
 	return &wxResp, nil
 }
 
-// processLogin 处理登录逻辑 (复用CommonLoginController的逻辑)
+// processLogin 处理登录逻辑
 func (c *WechatLoginController) processLogin(appId, openId, unionId string) (*LoginData, error) {
-	// 查找或创建用户
-	user, isNew, err := c.findOrCreateUser(appId, openId, unionId)
+	loginData, err := c.loginWithIdentity(appId, models.IdentityProviderWechat, openId, unionId)
 	if err != nil {
-		return nil, fmt.Errorf("处理用户数据失败: %v", err)
+		return nil, err
 	}
 
-	// 生成token
-	token := generateToken(appId, user.PlayerId)
-
 	// 更新最后登录时间
-	if err := c.updateLastLoginTime(user.PlayerId); err != nil {
+	if err := c.updateLastLoginTime(loginData.PlayerId); err != nil {
 		logs.Warning("更新最后登录时间失败:", err)
 	}
 
-	return &LoginData{
-		Token:    token,
-		PlayerId: user.PlayerId,
-		IsNew:    isNew,
-		OpenId:   openId,
-		UnionId:  unionId,
-		Data:     user.Data,
-	}, nil
-}
-
-// findOrCreateUser 查找或创建用户
-func (c *WechatLoginController) findOrCreateUser(appId, openId, unionId string) (*models.User, bool, error) {
-	// 尝试根据openId查找用户
-	user, err := models.GetUserByOpenId(appId, openId)
-	if err == nil && user != nil {
-		// 用户已存在
-		return user, false, nil
-	}
-
-	// 用户不存在，创建新用户
-	playerId := utils.GeneratePlayerId()
-
-	newUser := &models.User{
-		AppId:    appId,
-		PlayerId: playerId,
-		OpenId:   openId,
-		Data:     "{}",
-	}
-
-	if err := models.CreateUser(appId, newUser); err != nil {
-		return nil, false, fmt.Errorf("创建用户失败: %v", err)
-	}
-
-	logs.Info("创建新用户成功, appId:", appId, "playerId:", playerId, "openId:", openId)
-	return newUser, true, nil
+	return loginData, nil
 }
 
 // updateLastLoginTime 更新最后登录时间
@@ -1,9 +1,7 @@
 package login
 
 import (
-	"fmt"
 	"game-service/models"
-	"game-service/utils"
 	"game-service/yalla/services"
 
 	"github.com/beego/beego/v2/core/logs"
@@ -65,16 +63,11 @@ func (c *YallaLoginController) YallaLogin() {
 	// 处理登录逻辑，将sdkUserId作为openId处理
 	loginData, err := c.processYallaLogin(req.AppId, req.SdkUserId)
 	if err != nil {
-		ret := c.createErrorResponse(5001, err.Error())
+		ret := c.createLoginErrorResponse(err)
 		c.sendResponse(ret)
 		return
 	}
 
-	// 保存用户token到redis
-	if err := models.SaveUserStatusToRedis(req.AppId, loginData.PlayerId, loginData.Token); err != nil {
-		logs.Warning("保存用户token到redis失败:", err)
-	}
-
 	ret := c.createSuccessResponse(loginData)
 	c.sendResponse(ret)
 }
@@ -129,57 +122,19 @@ func (c *YallaLoginController) validateYallaUser(appId, sdkUserId string) error
 	return nil
 }
 
-// processYallaLogin 处理Yalla登录逻辑
+// processYallaLogin 处理Yalla登录逻辑，将sdkUserId作为openId处理
 func (c *YallaLoginController) processYallaLogin(appId, sdkUserId string) (*LoginData, error) {
-	// 将sdkUserId作为openId来查找或创建用户
-	user, isNew, err := c.findOrCreateUser(appId, sdkUserId, "")
+	loginData, err := c.loginWithIdentity(appId, models.IdentityProviderYalla, sdkUserId, "")
 	if err != nil {
-		return nil, fmt.Errorf("处理用户数据失败: %v", err)
+		return nil, err
 	}
 
-	// 生成token
-	token := generateToken(appId, user.PlayerId)
-
 	// 更新最后登录时间
-	if err := c.updateLastLoginTime(user.PlayerId); err != nil {
+	if err := c.updateLastLoginTime(loginData.PlayerId); err != nil {
 		logs.Warning("更新最后登录时间失败:", err)
 	}
 
-	return &LoginData{
-		Token:    token,
-		PlayerId: user.PlayerId,
-		IsNew:    isNew,
-		OpenId:   sdkUserId, // 将sdkUserId作为openId返回
-		UnionId:  "",        // Yalla可能没有unionId概念
-		Data:     user.Data,
-	}, nil
-}
-
-// findOrCreateUser 查找或创建用户
-func (c *YallaLoginController) findOrCreateUser(appId, openId, unionId string) (*models.User, bool, error) {
-	// 尝试根据openId查找用户
-	user, err := models.GetUserByOpenId(appId, openId)
-	if err == nil && user != nil {
-		// 用户已存在
-		return user, false, nil
-	}
-
-	// 用户不存在，创建新用户
-	playerId := utils.GeneratePlayerId()
-
-	newUser := &models.User{
-		AppId:    appId,
-		PlayerId: playerId,
-		OpenId:   openId,
-		Data:     "{}",
-	}
-
-	if err := models.CreateUser(appId, newUser); err != nil {
-		return nil, false, fmt.Errorf("创建用户失败: %v", err)
-	}
-
-	logs.Info("创建新Yalla用户成功, appId:", appId, "playerId:", playerId, "sdkUserId:", openId)
-	return newUser, true, nil
+	return loginData, nil
 }
 
 // updateLastLoginTime 更新最后登录时间
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"game-service/models"
 	"game-service/utils"
@@ -36,6 +37,12 @@ type ResponseCommon struct {
 	Timestamp int64       `json:"timestamp"`
 }
 
+// UserDataResponse 用户数据响应结构，version为存档版本号，保存时回传用于冲突检测
+type UserDataResponse struct {
+	ResponseCommon
+	Version int64 `json:"version"`
+}
+
 // =============================================================================
 // 登录相关接口
 // =============================================================================
@@ -189,13 +196,21 @@ func (c *UserController) WxLogin() {
 // 数据相关接口
 // =============================================================================
 
+// GetDataRequest 获取数据请求结构
+type GetDataRequest struct {
+	BaseRequest
+	Paths []string `json:"paths,omitempty"` // 只获取指定路径的数据（如bag.items），不传返回全部数据
+}
+
 // GetData 获取用户数据接口
 func (c *UserController) GetData() {
-	var req BaseRequest
-	ret := ResponseCommon{
-		Code:      0,
-		Msg:       "success",
-		Timestamp: time.Now().UnixMilli(),
+	var req GetDataRequest
+	ret := UserDataResponse{
+		ResponseCommon: ResponseCommon{
+			Code:      0,
+			Msg:       "success",
+			Timestamp: time.Now().UnixMilli(),
+		},
 	}
 
 	// 解析请求参数
@@ -211,7 +226,14 @@ func (c *UserController) GetData() {
 	playerId := req.PlayerId
 
 	// 获取用户数据
-	userData, err := models.GetUserData(appId, playerId)
+	var userData map[string]interface{}
+	var version int64
+	var err error
+	if len(req.Paths) > 0 {
+		userData, version, err = models.GetUserDataPaths(appId, playerId, req.Paths)
+	} else {
+		userData, version, err = models.GetUserDataWithVersion(appId, playerId)
+	}
 	if err != nil {
 		ret.Code = 5001
 		ret.Msg = "获取数据失败: " + err.Error()
@@ -220,13 +242,15 @@ func (c *UserController) GetData() {
 	}
 
 	ret.Data = userData
+	ret.Version = version
 	c.Ctx.Output.JSON(ret, false, false)
 }
 
 // SaveDataRequest 保存数据请求结构
 type SaveDataRequest struct {
 	BaseRequest
-	Data interface{} `json:"data"`
+	Data    interface{} `json:"data"`
+	Version *int64      `json:"version,omitempty"` // 客户端持有的存档版本号，不传则不校验
 }
 
 // SaveData 保存用户数据接口
@@ -250,12 +274,41 @@ func (c *UserController) SaveData() {
 	appId := c.Ctx.Input.GetData("app_id").(string)
 	playerId := req.PlayerId
 
-	// 保存数据
+	// 保存数据（兼容直接传JSON对象）
 	var data string
-	if req.Data != nil {
-		data = req.Data.(string)
+	switch v := req.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	default:
+		encoded, _ := json.Marshal(v)
+		data = string(encoded)
+	}
+
+	// 按应用规则校验数据
+	if err := models.ValidateUserData(appId, data); err != nil {
+		ret.Code = 4001
+		ret.Msg = err.Error()
+		c.Ctx.Output.JSON(ret, false, false)
+		return
+	}
+
+	version, err := models.SaveUserDataWithVersion(appId, playerId, data, req.Version, c.Ctx.Input.IP())
+	if errors.Is(err, models.ErrUserDataConflict) {
+		// 版本冲突时返回服务器上的存档，由客户端决定合并或覆盖
+		ret.Code = 4009
+		ret.Msg = "数据已在其他设备更新，请合并后重试"
+		serverData, serverVersion, err := models.GetUserDataWithVersion(appId, playerId)
+		if err == nil {
+			version = serverVersion
+		}
+		ret.Data = map[string]interface{}{
+			"data":    serverData,
+			"version": version,
+		}
+		c.Ctx.Output.JSON(ret, false, false)
+		return
 	}
-	err := models.SaveUserData(appId, playerId, data)
 	if err != nil {
 		ret.Code = 5001
 		ret.Msg = "保存数据失败: " + err.Error()
@@ -263,6 +316,69 @@ func (c *UserController) SaveData() {
 		return
 	}
 
+	ret.Data = map[string]interface{}{
+		"version": version,
+	}
+	c.Ctx.Output.JSON(ret, false, false)
+}
+
+// PatchDataRequest 局部更新数据请求结构
+type PatchDataRequest struct {
+	BaseRequest
+	MergePatch map[string]interface{}   `json:"mergePatch,omitempty"` // JSON Merge Patch，null表示删除字段
+	Operations []models.UserDataPatchOp `json:"operations,omitempty"` // 按路径set/increment/remove，在mergePatch之后执行
+	Version    *int64                   `json:"version,omitempty"`    // 客户端持有的存档版本号，不传则不校验
+}
+
+// PatchData 局部更新用户数据接口
+func (c *UserController) PatchData() {
+	var req PatchDataRequest
+	ret := ResponseCommon{
+		Code:      0,
+		Msg:       "success",
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	// 解析请求参数
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		ret.Code = 4001
+		ret.Msg = "参数解析失败: " + err.Error()
+		c.Ctx.Output.JSON(ret, false, false)
+		return
+	}
+
+	// 从中间件获取已验证的appId
+	appId := c.Ctx.Input.GetData("app_id").(string)
+	playerId := req.PlayerId
+
+	// 更新数据
+	version, values, err := models.PatchUserData(appId, playerId, req.MergePatch, req.Operations, req.Version, c.Ctx.Input.IP())
+	if errors.Is(err, models.ErrUserDataConflict) {
+		ret.Code = 4009
+		ret.Msg = "数据已在其他设备更新，请刷新后重试"
+		ret.Data = map[string]interface{}{
+			"version": version,
+		}
+		c.Ctx.Output.JSON(ret, false, false)
+		return
+	}
+	if errors.Is(err, models.ErrUserDataPatchInvalid) || errors.Is(err, models.ErrUserDataInvalid) {
+		ret.Code = 4001
+		ret.Msg = err.Error()
+		c.Ctx.Output.JSON(ret, false, false)
+		return
+	}
+	if err != nil {
+		ret.Code = 5001
+		ret.Msg = "保存数据失败: " + err.Error()
+		c.Ctx.Output.JSON(ret, false, false)
+		return
+	}
+
+	ret.Data = map[string]interface{}{
+		"version": version,
+		"values":  values,
+	}
 	c.Ctx.Output.JSON(ret, false, false)
 }
 
@@ -310,6 +426,14 @@ func (c *UserController) SaveUserInfo() {
 	appId := c.Ctx.Input.GetData("app_id").(string)
 	playerId := req.PlayerId
 
+	// 校验数据大小和层数
+	if err := models.ValidateUserDataLimits(appId, req.UserInfo); err != nil {
+		ret.Code = 4001
+		ret.Msg = err.Error()
+		c.Ctx.Output.JSON(ret, false, false)
+		return
+	}
+
 	// 解析用户信息
 	var userInfo UserInfo
 	if err := json.Unmarshal([]byte(req.UserInfo), &userInfo); err != nil {
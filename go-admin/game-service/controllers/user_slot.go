@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"game-service/models"
+)
+
+// =============================================================================
+// 命名存档相关接口
+// =============================================================================
+
+// SlotRequest 存档槽位请求结构
+type SlotRequest struct {
+	BaseRequest
+	Slot string `json:"slot"` // 槽位名称，如slot1、settings
+}
+
+// SaveSlotRequest 保存存档槽位请求结构
+type SaveSlotRequest struct {
+	SlotRequest
+	Data    interface{} `json:"data"`              // 存档内容，JSON对象或JSON字符串
+	Version *int64      `json:"version,omitempty"` // 客户端持有的存档版本号，新槽位为0，不传则不校验
+}
+
+// GetSlot 获取命名存档接口
+func (c *UserController) GetSlot() {
+	var req SlotRequest
+	ret := ResponseCommon{
+		Code:      0,
+		Msg:       "success",
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	if !c.parseSlotRequest(&req, &req.Slot, &ret) {
+		return
+	}
+	appId := c.Ctx.Input.GetData("app_id").(string)
+
+	slot, err := models.GetUserDataSlot(appId, req.PlayerId, req.Slot)
+	if errors.Is(err, models.ErrUserDataSlotNotFound) {
+		ret.Code = 4004
+		ret.Msg = "存档[" + req.Slot + "]不存在"
+		c.Ctx.Output.JSON(ret, false, false)
+		return
+	}
+	if err != nil {
+		ret.Code = 5001
+		ret.Msg = "获取存档失败: " + err.Error()
+		c.Ctx.Output.JSON(ret, false, false)
+		return
+	}
+
+	ret.Data = map[string]interface{}{
+		"slot":      slot.Slot,
+		"data":      decodeSlotData(slot.Data),
+		"version":   slot.Version,
+		"updatedAt": slot.UpdatedAt,
+	}
+	c.Ctx.Output.JSON(ret, false, false)
+}
+
+// SaveSlot 保存命名存档接口
+func (c *UserController) SaveSlot() {
+	var req SaveSlotRequest
+	ret := ResponseCommon{
+		Code:      0,
+		Msg:       "success",
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	if !c.parseSlotRequest(&req, &req.Slot, &ret) {
+		return
+	}
+	appId := c.Ctx.Input.GetData("app_id").(string)
+
+	// 存档内容统一保存为JSON字符串
+	var data string
+	switch v := req.Data.(type) {
+	case nil:
+		data = "{}"
+	case string:
+		data = v
+	default:
+		encoded, _ := json.Marshal(v)
+		data = string(encoded)
+	}
+	if err := models.ValidateUserDataLimits(appId, data); err != nil {
+		ret.Code = 4001
+		ret.Msg = err.Error()
+		c.Ctx.Output.JSON(ret, false, false)
+		return
+	}
+
+	version, err := models.SaveUserDataSlot(appId, req.PlayerId, req.Slot, data, req.Version)
+	switch {
+	case errors.Is(err, models.ErrUserDataConflict):
+		// 版本冲突时返回服务器上的存档，由客户端决定合并或覆盖
+		ret.Code = 4009
+		ret.Msg = "存档已在其他设备更新，请合并后重试"
+		serverData := map[string]interface{}{"version": version}
+		if slot, err := models.GetUserDataSlot(appId, req.PlayerId, req.Slot); err == nil {
+			serverData["data"] = decodeSlotData(slot.Data)
+			serverData["version"] = slot.Version
+		}
+		ret.Data = serverData
+	case errors.Is(err, models.ErrUserDataSlotLimit):
+		ret.Code = 4003
+		ret.Msg = err.Error()
+	case err != nil:
+		ret.Code = 5001
+		ret.Msg = "保存存档失败: " + err.Error()
+	default:
+		ret.Data = map[string]interface{}{
+			"slot":    req.Slot,
+			"version": version,
+		}
+	}
+
+	c.Ctx.Output.JSON(ret, false, false)
+}
+
+// ListSlots 获取命名存档列表接口（不含存档内容）
+func (c *UserController) ListSlots() {
+	var req BaseRequest
+	ret := ResponseCommon{
+		Code:      0,
+		Msg:       "success",
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &req); err != nil {
+		ret.Code = 4001
+		ret.Msg = "参数解析失败: " + err.Error()
+		c.Ctx.Output.JSON(ret, false, false)
+		return
+	}
+	appId := c.Ctx.Input.GetData("app_id").(string)
+
+	slots, err := models.ListUserDataSlots(appId, req.PlayerId)
+	if err != nil {
+		ret.Code = 5001
+		ret.Msg = "获取存档列表失败: " + err.Error()
+		c.Ctx.Output.JSON(ret, false, false)
+		return
+	}
+
+	ret.Data = map[string]interface{}{
+		"list": slots,
+	}
+	c.Ctx.Output.JSON(ret, false, false)
+}
+
+// DeleteSlot 删除命名存档接口
+func (c *UserController) DeleteSlot() {
+	var req SlotRequest
+	ret := ResponseCommon{
+		Code:      0,
+		Msg:       "success",
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	if !c.parseSlotRequest(&req, &req.Slot, &ret) {
+		return
+	}
+	appId := c.Ctx.Input.GetData("app_id").(string)
+
+	err := models.DeleteUserDataSlot(appId, req.PlayerId, req.Slot)
+	if errors.Is(err, models.ErrUserDataSlotNotFound) {
+		ret.Code = 4004
+		ret.Msg = "存档[" + req.Slot + "]不存在"
+	} else if err != nil {
+		ret.Code = 5001
+		ret.Msg = "删除存档失败: " + err.Error()
+	}
+
+	c.Ctx.Output.JSON(ret, false, false)
+}
+
+// parseSlotRequest 解析存档请求并校验槽位名称，失败时直接输出错误响应
+func (c *UserController) parseSlotRequest(req interface{}, slot *string, ret *ResponseCommon) bool {
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, req); err != nil {
+		ret.Code = 4001
+		ret.Msg = "参数解析失败: " + err.Error()
+		c.Ctx.Output.JSON(ret, false, false)
+		return false
+	}
+
+	if err := models.ValidateUserDataSlot(*slot); err != nil {
+		ret.Code = 4001
+		ret.Msg = "参数[slot]错误: " + err.Error()
+		c.Ctx.Output.JSON(ret, false, false)
+		return false
+	}
+	return true
+}
+
+// decodeSlotData 解析存档内容，不是合法JSON时原样返回
+func decodeSlotData(data string) interface{} {
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return data
+	}
+	return value
+}
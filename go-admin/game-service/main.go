@@ -1,7 +1,7 @@
 package main
 
 import (
-	_ "game-service/models"
+	"game-service/models"
 	_ "game-service/routers"
 	_ "game-service/yalla/models"
 
@@ -19,6 +19,18 @@ func main() {
 	// 设置静态文件路径
 	web.SetStaticPath("/static", "static")
 
+	// 启动排行榜Redis/MySQL对账任务
+	models.StartLeaderboardReconciler()
+
+	// 启动排行榜赛季结算任务
+	models.StartLeaderboardSettler()
+
+	// 启动计数器落库任务
+	models.StartCounterFlusher()
+
+	// 启动计数器历史采样任务
+	models.StartCounterHistorySampler()
+
 	// 启动Web服务
 	web.Run()
 }
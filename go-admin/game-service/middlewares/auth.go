@@ -3,6 +3,7 @@ package middlewares
 import (
 	"crypto/md5"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"game-service/models"
 	"sort"
@@ -29,6 +30,8 @@ func SignAuthMiddleware(ctx *context.Context) {
 		"/user/login/douyin",
 		"/user/login/qq",
 		"/user/login/yalla",
+		"/user/login/guest",
+		"/user/refreshToken",
 	}
 
 	requestPath := ctx.Request.URL.Path
@@ -72,26 +75,6 @@ func SignAuthMiddleware(ctx *context.Context) {
 		}
 	}
 
-	if !skipToken {
-		// 从数据库查询用户token是否有效
-		var token string
-		tokenVal, ok := requestBody["token"]
-		if ok {
-			playerId, ok := requestBody["playerId"]
-			if !ok {
-				responseError(ctx, 1001, "缺少playerId参数")
-				return
-			}
-
-			token = tokenVal.(string)
-			userToken, err := models.GetUserStatusFromRedis(appId, playerId.(string))
-			if err != nil && userToken != token {
-				responseError(ctx, 1001, "token无效")
-				return
-			}
-		}
-	}
-
 	// 处理timestamp类型转换（可能是number或string）
 	var ts int64
 	switch v := timestampVal.(type) {
@@ -144,6 +127,33 @@ func SignAuthMiddleware(ctx *context.Context) {
 		return
 	}
 
+	// 除登录接口外，所有接口都必须携带有效的playerId和token
+	if !skipToken {
+		playerId, _ := requestBody["playerId"].(string)
+		token, _ := requestBody["token"].(string)
+		if playerId == "" || token == "" {
+			responseError(ctx, 401, "缺少playerId或token参数")
+			return
+		}
+
+		sessionId, err := models.ValidateUserSession(appId, playerId, token)
+		if err != nil {
+			var kicked *models.SessionKickedError
+			if errors.Is(err, models.ErrSessionInvalid) {
+				responseError(ctx, 401, err.Error())
+			} else if errors.As(err, &kicked) {
+				// 会话已被踢下线（其他设备登录、管理员踢下线、封禁）
+				responseError(ctx, 4010, err.Error())
+			} else {
+				logs.Error("校验登录状态失败: %v", err)
+				responseError(ctx, 5001, "校验登录状态失败")
+			}
+			return
+		}
+		ctx.Input.SetData("player_id", playerId)
+		ctx.Input.SetData("session_id", sessionId)
+	}
+
 	// 将应用信息存储到上下文中
 	ctx.Input.SetData("app_id", appId)
 	ctx.Input.SetData("appSecret", app.ChannelAppKey)
@@ -44,7 +44,7 @@ func init() {
 	orm.RegisterDriver("mysql", orm.DRMySQL)
 
 	// 获取配置
-	appconf, _ := config.NewConfig("ini", "conf/app.conf")
+	appconf := loadAppConfig()
 
 	mysqlHost := appconf.DefaultString("mysql_host", "localhost")
 	mysqlPort := appconf.DefaultString("mysql_port", "3306")
@@ -69,7 +69,7 @@ func init() {
 
 // 初始化Redis连接
 func initRedis() {
-	appconf, _ := config.NewConfig("ini", "conf/app.conf")
+	appconf := loadAppConfig()
 
 	redisHost := appconf.DefaultString("redis_host", "localhost")
 	redisPort := appconf.DefaultString("redis_port", "6379")
@@ -90,6 +90,15 @@ func initRedis() {
 	RedisClient = redis.NewClient(options)
 }
 
+// loadAppConfig 读取conf/app.conf，文件不存在时（如在包目录下运行单元测试）返回空配置，各配置项使用默认值
+func loadAppConfig() config.Configer {
+	appconf, err := config.NewConfig("ini", "conf/app.conf")
+	if err != nil {
+		appconf, _ = config.NewConfigData("ini", []byte{})
+	}
+	return appconf
+}
+
 // SuccessResponse 成功响应
 func SuccessResponse(data interface{}) Response {
 	return Response{
@@ -1,10 +1,10 @@
 package models
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"game-service/utils"
@@ -13,17 +13,49 @@ import (
 	"github.com/beego/beego/v2/core/logs"
 )
 
+// 计数器作用域
+const (
+	CounterScopeGlobal = "global" // 全局计数器，所有玩家共享
+	CounterScopePlayer = "player" // 玩家计数器，每个玩家独立计数（如每日免费次数）
+)
+
+var (
+	// ErrCounterNotFound 计数器配置不存在（已在管理后台删除）
+	ErrCounterNotFound = errors.New("计数器配置不存在")
+	// ErrCounterPlayerRequired 玩家计数器缺少玩家ID
+	ErrCounterPlayerRequired = errors.New("玩家计数器需要playerId")
+	// ErrCounterLimitReached 计数器已达上限
+	ErrCounterLimitReached = errors.New("计数器已达上限")
+	// ErrCounterReadOnly 有上限的玩家计数器不允许通过游戏接口扣减、设置或重置
+	ErrCounterReadOnly = errors.New("计数器不允许扣减、设置或重置")
+	// ErrCounterPlayerLocked 玩家账号正在被管理后台合并，暂时不能读写玩家计数器
+	ErrCounterPlayerLocked = errors.New("玩家账号合并中，请稍后再试")
+)
+
+// counterConfigCacheTTL 计数器配置在进程内的缓存时间
+const counterConfigCacheTTL = 10 * time.Second
+
+// counterConfigCache 计数器配置缓存，键为appId:counterKey
+var counterConfigCache sync.Map
+
+type counterConfigCacheEntry struct {
+	config   *CounterConfig
+	err      error
+	expireAt time.Time
+}
+
 // Counter 计数器模型 - 对应数据库设计的counter_[appid]表（简化结构，对齐JS功能）
 type Counter struct {
 	Id         int64     `orm:"auto" json:"id"`
 	CounterKey string    `orm:"size(100);column(counter_key)" json:"counterKey"`
+	PlayerId   string    `orm:"size(100);default();column(player_id)" json:"playerId,omitempty"`
 	Location   string    `orm:"size(100);default(default);column(location)" json:"location"`
 	Value      int64     `orm:"default(0);column(value)" json:"value"`
 	CreatedAt  time.Time `orm:"auto_now_add;type(datetime);column(created_at)" json:"createdAt"`
 	UpdatedAt  time.Time `orm:"auto_now;type(datetime);column(updated_at)" json:"updatedAt"`
 }
 
-// CounterConfig 计数器配置结构（从admin-service获取）
+// CounterConfig 计数器配置结构（与admin-service共用counter_config表）
 type CounterConfig struct {
 	ID            int64     `json:"id"`
 	AppId         string    `json:"appId"`
@@ -33,8 +65,13 @@ type CounterConfig struct {
 	NextResetTime time.Time `json:"nextResetTime"`
 	Description   string    `json:"description"`
 	IsActive      bool      `json:"isActive"`
+	Scope         string    `json:"scope"`    // global/player
+	MaxValue      int64     `json:"maxValue"` // 上限，0表示不限
+	Thresholds    string    `json:"thresholds"`
 	CreatedAt     time.Time `json:"createdAt"`
 	UpdatedAt     time.Time `json:"updatedAt"`
+
+	thresholds []CounterThreshold // 解析后的阈值
 }
 
 // GetTableName 获取动态表名
@@ -51,39 +88,46 @@ func GetCounterModel(appId string) (*Counter, string, error) {
 	return counterModel, tableName, nil
 }
 
-func GetCounterValues(appId, counterKey string) (map[string]int64, error) {
+// resolveCounterOwner 根据计数器作用域确定计数归属的玩家，全局计数器为空
+func resolveCounterOwner(config *CounterConfig, playerId string) (string, error) {
+	if config.Scope != CounterScopePlayer {
+		return "", nil
+	}
+	if playerId == "" {
+		return "", ErrCounterPlayerRequired
+	}
+	return playerId, nil
+}
+
+// isCounterReadOnly 有上限的玩家计数器只允许增加，防止玩家自行扣减或重置次数
+func isCounterReadOnly(config *CounterConfig) bool {
+	return config.Scope == CounterScopePlayer && config.MaxValue > 0
+}
+
+// GetCounterValues 获取计数器所有点位的值，玩家计数器返回指定玩家的值
+func GetCounterValues(appId, counterKey, playerId string) (map[string]int64, error) {
 	// 1. 检查计数器配置是否存在
-	_, err := getCounterConfig(appId, counterKey)
+	config, err := getCounterConfig(appId, counterKey)
 	if err != nil {
 		return nil, err
 	}
-
-	tableName := utils.GetCounterTableName(appId)
-
-	o := orm.NewOrm()
-	var result []orm.Params
-	selectSQL := fmt.Sprintf(`SELECT location, value FROM %s WHERE counter_key = ?`, tableName)
-	_, err = o.Raw(selectSQL, counterKey).Values(&result)
+	owner, err := resolveCounterOwner(config, playerId)
 	if err != nil {
 		return nil, err
 	}
 
-	values := make(map[string]int64)
-	for _, row := range result {
-		location := row["location"].(string)
-		// 数据库返回的是string，需要转换为int64
-		valueStr := row["value"].(string)
-		value, err := strconv.ParseInt(valueStr, 10, 64)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse counter value: %v", err)
-		}
-		values[location] = value
+	// 2. 启用Redis时从Redis读取
+	if RedisClient != nil {
+		return getCounterValuesFromRedis(appId, counterKey, owner, config)
 	}
-	return values, nil
+
+	// 3. 从数据库读取当前周期的值
+	period := getCounterPeriod(config, time.Now())
+	return getCounterValuesFromDB(orm.NewOrm(), appId, counterKey, owner, "", period, false)
 }
 
 // GetCounterValue 获取计数器指定位置的值（对齐JS getCounter功能）
-func GetCounterValue(appId, counterKey, location string) (int64, error) {
+func GetCounterValue(appId, counterKey, playerId, location string) (int64, error) {
 	if location == "" {
 		location = "default"
 	}
@@ -93,36 +137,28 @@ func GetCounterValue(appId, counterKey, location string) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
-
-	// 2. 检查并处理重置逻辑
-	err = checkAndResetCounter(appId, counterKey, location, config)
+	owner, err := resolveCounterOwner(config, playerId)
 	if err != nil {
 		return 0, err
 	}
 
-	// 3. 从数据库获取值
-	counter, tableName, err := GetCounterModel(appId)
-	if err != nil {
-		return 0, err
+	// 2. 启用Redis时从Redis读取（按周期分键，无需单独处理重置）
+	if RedisClient != nil {
+		return getCounterValueFromRedis(appId, counterKey, owner, location, config)
 	}
 
-	o := orm.NewOrm()
-	err = o.QueryTable(tableName).
-		Filter("counter_key", counterKey).
-		Filter("location", location).
-		One(counter)
-
-	if err == orm.ErrNoRows {
-		return 0, nil // 计数器不存在，返回0
-	} else if err != nil {
+	// 3. 从数据库获取当前周期的值
+	period := getCounterPeriod(config, time.Now())
+	values, err := getCounterValuesFromDB(orm.NewOrm(), appId, counterKey, owner, location, period, false)
+	if err != nil {
 		return 0, err
 	}
-
-	return counter.Value, nil
+	return values[location], nil
 }
 
 // IncrementCounterValue 增加计数器值（对齐JS incrementCounter功能）
-func IncrementCounterValue(appId, counterKey, location string, increment int64) (int64, error) {
+// 配置了上限时，超过上限不增加并返回ErrCounterLimitReached和当前值
+func IncrementCounterValue(appId, counterKey, playerId, location string, increment int64) (int64, error) {
 	if location == "" {
 		location = "default"
 	}
@@ -135,64 +171,35 @@ func IncrementCounterValue(appId, counterKey, location string, increment int64)
 	if err != nil {
 		return 0, err
 	}
-
-	// 2. 检查点位是否存在（通过config中的locations检查）
-	err = checkLocationExists(appId, counterKey, location)
+	owner, err := resolveCounterOwner(config, playerId)
 	if err != nil {
 		return 0, err
 	}
 
-	// 3. 检查并处理重置逻辑
-	err = checkAndResetCounter(appId, counterKey, location, config)
-	if err != nil {
-		return 0, err
-	}
-
-	// 4. 使用 UPSERT 更新计数器值
-	_, tableName, err := GetCounterModel(appId)
+	// 2. 检查点位是否存在（通过config中的locations检查）
+	err = checkLocationExists(appId, counterKey, location)
 	if err != nil {
 		return 0, err
 	}
 
-	o := orm.NewOrm()
-
-	// 使用 ON DUPLICATE KEY UPDATE 进行 upsert 操作
-	sql := fmt.Sprintf(`
-		INSERT INTO %s (counter_key, location, value, created_at, updated_at)
-		VALUES (?, ?, ?, NOW(), NOW())
-		ON DUPLICATE KEY UPDATE
-			value = value + VALUES(value),
-			updated_at = NOW()
-	`, tableName)
-
-	_, err = o.Raw(sql, counterKey, location, increment).Exec()
-	if err != nil {
-		return 0, err
+	// 3. 启用Redis时使用INCRBY累加，由落库任务定期写回数据库，否则在数据库事务中累加
+	var value int64
+	if RedisClient != nil {
+		value, err = incrementCounterInRedis(appId, counterKey, owner, location, increment, false, config.MaxValue, config)
+	} else {
+		value, err = incrementCounterInDB(appId, counterKey, owner, location, increment, false, config.MaxValue, config)
 	}
-
-	// 5. 获取更新后的值
-	var result []orm.Params
-	selectSQL := fmt.Sprintf(`SELECT value FROM %s WHERE location = ?`, tableName)
-	_, err = o.Raw(selectSQL, location).Values(&result)
 	if err != nil {
-		return 0, err
+		return value, err
 	}
 
-	if len(result) > 0 {
-		if valueStr, ok := result[0]["value"].(string); ok {
-			value, err := strconv.ParseInt(valueStr, 10, 64)
-			if err != nil {
-				return 0, fmt.Errorf("failed to parse counter value: %v", err)
-			}
-			return value, nil
-		}
-	}
-
-	return 0, fmt.Errorf("failed to get updated value")
+	// 4. 检查阈值
+	checkCounterThresholds(appId, config, location, value)
+	return value, nil
 }
 
 // DecrementCounterValue 减少计数器值（保持现有接口兼容性）
-func DecrementCounterValue(appId, counterKey, location string, decrement int64) (int64, error) {
+func DecrementCounterValue(appId, counterKey, playerId, location string, decrement int64) (int64, error) {
 	if location == "" {
 		location = "default"
 	}
@@ -205,119 +212,188 @@ func DecrementCounterValue(appId, counterKey, location string, decrement int64)
 	if err != nil {
 		return 0, err
 	}
-
-	// 2. 检查并处理重置逻辑
-	err = checkAndResetCounter(appId, counterKey, location, config)
+	if isCounterReadOnly(config) {
+		return 0, ErrCounterReadOnly
+	}
+	owner, err := resolveCounterOwner(config, playerId)
 	if err != nil {
 		return 0, err
 	}
 
-	// 3. 减少计数器值（不允许小于0）
-	_, tableName, err := GetCounterModel(appId)
+	// 2. 启用Redis时在Redis中扣减，否则在数据库事务中扣减（不允许小于0）
+	var value int64
+	if RedisClient != nil {
+		value, err = incrementCounterInRedis(appId, counterKey, owner, location, -decrement, true, 0, config)
+	} else {
+		value, err = incrementCounterInDB(appId, counterKey, owner, location, -decrement, true, 0, config)
+	}
 	if err != nil {
 		return 0, err
 	}
 
-	o := orm.NewOrm()
+	// 3. 检查阈值
+	checkCounterThresholds(appId, config, location, value)
+	return value, nil
+}
 
-	// 使用 UPDATE 语句减少值，确保不小于0
-	sql := fmt.Sprintf(`
-		UPDATE %s 
-		SET value = GREATEST(0, value - ?), updated_at = NOW()
-		WHERE counter_key = ? AND location = ?
-	`, tableName)
+// SetCounterValue 设置计数器值（对齐JS setCounter功能）
+func SetCounterValue(appId, counterKey, playerId, location string, value int64) (int64, error) {
+	if location == "" {
+		location = "default"
+	}
 
-	_, err = o.Raw(sql, decrement, counterKey, location).Exec()
+	// 1. 检查计数器配置是否存在
+	config, err := getCounterConfig(appId, counterKey)
+	if err != nil {
+		return 0, err
+	}
+	if isCounterReadOnly(config) {
+		return 0, ErrCounterReadOnly
+	}
+	owner, err := resolveCounterOwner(config, playerId)
 	if err != nil {
 		return 0, err
 	}
 
-	// 获取更新后的值
-	var result []orm.Params
-	selectSQL := fmt.Sprintf(`SELECT value FROM %s WHERE counter_key = ? AND location = ?`, tableName)
-	_, err = o.Raw(selectSQL, counterKey, location).Values(&result)
+	// 2. 检查点位是否存在
+	err = checkLocationExists(appId, counterKey, location)
 	if err != nil {
 		return 0, err
 	}
 
-	if len(result) > 0 {
-		if value, ok := result[0]["value"].(int64); ok {
-			return value, nil
-		}
+	// 3. 启用Redis时写入Redis，由落库任务定期写回数据库，否则使用 UPSERT 设置计数器值
+	if RedisClient != nil {
+		err = setCounterInRedis(appId, counterKey, owner, location, value, config)
+	} else {
+		err = upsertCounterValue(orm.NewOrm(), appId, counterKey, owner, location, value)
+	}
+	if err != nil {
+		return 0, err
 	}
 
-	return 0, nil
+	// 4. 检查阈值
+	checkCounterThresholds(appId, config, location, value)
+	return value, nil
 }
 
-// SetCounterValue 设置计数器值（对齐JS setCounter功能）
-func SetCounterValue(appId, counterKey, location string, value int64) (int64, error) {
+// ResetCounterValue 重置计数器值（对齐JS resetCounter功能）
+func ResetCounterValue(appId, counterKey, playerId, location string) (int64, error) {
 	if location == "" {
 		location = "default"
 	}
 
-	// 1. 检查计数器配置是否存在
+	// 1. 检查计数器配置和点位是否存在
 	config, err := getCounterConfig(appId, counterKey)
 	if err != nil {
 		return 0, err
 	}
-
-	// 2. 检查点位是否存在
-	err = checkLocationExists(appId, counterKey, location)
+	if isCounterReadOnly(config) {
+		return 0, ErrCounterReadOnly
+	}
+	owner, err := resolveCounterOwner(config, playerId)
 	if err != nil {
 		return 0, err
 	}
-
-	// 3. 检查并处理重置逻辑
-	err = checkAndResetCounter(appId, counterKey, location, config)
+	err = checkLocationExists(appId, counterKey, location)
 	if err != nil {
 		return 0, err
 	}
 
-	// 4. 使用 UPSERT 设置计数器值
-	_, tableName, err := GetCounterModel(appId)
-	if err != nil {
-		return 0, err
+	// 2. 全局计数器保存重置前的值（按计数器范围判断，请求中总会带上调用者的playerId）
+	if owner == "" {
+		archiveCounterBeforeReset(appId, counterKey, location)
 	}
 
-	o := orm.NewOrm()
+	// 3. 重置计数器值为0
+	return SetCounterValue(appId, counterKey, playerId, location, 0)
+}
+
+// getCounterValuesFromDB 从数据库获取计数器当前周期的值（上次更新不在当前周期内的点位视为已重置），location为空时返回所有点位
+func getCounterValuesFromDB(o orm.QueryExecutor, appId, counterKey, playerId, location string, period counterPeriod, forUpdate bool) (map[string]int64, error) {
+	sql := fmt.Sprintf(`SELECT location, value FROM %s WHERE counter_key = ? AND player_id = ?`, utils.GetCounterTableName(appId))
+	params := []interface{}{counterKey, playerId}
+	if location != "" {
+		sql += ` AND location = ?`
+		params = append(params, location)
+	}
+	if period.Id != counterPermanentPeriod {
+		sql += ` AND updated_at >= ?`
+		params = append(params, period.Start)
+	}
+	if forUpdate {
+		sql += ` FOR UPDATE`
+	}
 
+	var result []orm.Params
+	if _, err := o.Raw(sql, params...).Values(&result); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int64, len(result))
+	for _, row := range result {
+		rowLocation, _ := row["location"].(string)
+		// 数据库返回的是string，需要转换为int64
+		valueStr, _ := row["value"].(string)
+		value, err := strconv.ParseInt(valueStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse counter value: %v", err)
+		}
+		values[rowLocation] = value
+	}
+	return values, nil
+}
+
+// upsertCounterValue 写入计数器点位的值
+func upsertCounterValue(o orm.QueryExecutor, appId, counterKey, playerId, location string, value int64) error {
 	// 使用 ON DUPLICATE KEY UPDATE 进行 upsert 操作
 	sql := fmt.Sprintf(`
-		INSERT INTO %s (counter_key, location, value, created_at, updated_at)
-		VALUES (?, ?, ?, NOW(), NOW())
+		INSERT INTO %s (counter_key, player_id, location, value, created_at, updated_at)
+		VALUES (?, ?, ?, ?, NOW(), NOW())
 		ON DUPLICATE KEY UPDATE
 			value = VALUES(value),
 			updated_at = NOW()
-	`, tableName)
+	`, utils.GetCounterTableName(appId))
+
+	_, err := o.Raw(sql, counterKey, playerId, location, value).Exec()
+	return err
+}
+
+// incrementCounterInDB 在数据库事务中增减计数器值（未启用Redis时使用）
+// limit大于0时增加后超过上限则不修改，clamp为true时结果不小于0
+func incrementCounterInDB(appId, counterKey, playerId, location string, delta int64, clamp bool, limit int64, config *CounterConfig) (int64, error) {
+	period := getCounterPeriod(config, time.Now())
 
-	_, err = o.Raw(sql, counterKey, location, value).Exec()
+	o := orm.NewOrm()
+	tx, err := o.Begin()
 	if err != nil {
 		return 0, err
 	}
 
-	return value, nil
-}
+	values, err := getCounterValuesFromDB(tx, appId, counterKey, playerId, location, period, true)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
 
-// ResetCounterValue 重置计数器值（对齐JS resetCounter功能）
-func ResetCounterValue(appId, counterKey, location string) (int64, error) {
-	if location == "" {
-		location = "default"
+	current := values[location]
+	value := current + delta
+	if limit > 0 && delta > 0 && value > limit {
+		tx.Rollback()
+		return current, ErrCounterLimitReached
+	}
+	if clamp && value < 0 {
+		value = 0
 	}
 
-	// 1. 检查计数器配置是否存在
-	_, err := getCounterConfig(appId, counterKey)
-	if err != nil {
+	if err := upsertCounterValue(tx, appId, counterKey, playerId, location, value); err != nil {
+		tx.Rollback()
 		return 0, err
 	}
-
-	// 2. 检查点位是否存在
-	err = checkLocationExists(appId, counterKey, location)
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		return 0, err
 	}
 
-	// 3. 重置计数器值为0
-	return SetCounterValue(appId, counterKey, location, 0)
+	return value, nil
 }
 
 // SetCounter 设置计数器值
@@ -404,28 +480,28 @@ func GetCounterList(appId string, page, pageSize int, counterKey string) ([]Coun
 
 // GetGlobalCounter 获取全局计数器值（已废弃，使用GetCounterValue替代）
 func GetGlobalCounter(appId, counterKey string) (int64, error) {
-	return GetCounterValue(appId, counterKey, "default")
+	return GetCounterValue(appId, counterKey, "", "default")
 }
 
 // IncrementGlobalCounter 增加全局计数器（已废弃，使用IncrementCounterValue替代）
 func IncrementGlobalCounter(appId, counterKey string, increment int64) (int64, error) {
-	return IncrementCounterValue(appId, counterKey, "default", increment)
+	return IncrementCounterValue(appId, counterKey, "", "default", increment)
 }
 
 // DecrementGlobalCounter 减少全局计数器（已废弃，使用DecrementCounterValue替代）
 func DecrementGlobalCounter(appId, counterKey string, decrement int64) (int64, error) {
-	return DecrementCounterValue(appId, counterKey, "default", decrement)
+	return DecrementCounterValue(appId, counterKey, "", "default", decrement)
 }
 
 // SetGlobalCounter 设置全局计数器值（已废弃，使用SetCounterValue替代）
 func SetGlobalCounter(appId, counterKey string, value int64) error {
-	_, err := SetCounterValue(appId, counterKey, "default", value)
+	_, err := SetCounterValue(appId, counterKey, "", "default", value)
 	return err
 }
 
 // ResetGlobalCounter 重置全局计数器（已废弃，使用ResetCounterValue替代）
 func ResetGlobalCounter(appId, counterKey string) error {
-	_, err := ResetCounterValue(appId, counterKey, "default")
+	_, err := ResetCounterValue(appId, counterKey, "", "default")
 	return err
 }
 
@@ -439,182 +515,101 @@ func GetAllGlobalCounters(appId string) ([]Counter, error) {
 	o := orm.NewOrm()
 
 	var results []Counter
-	_, err = o.QueryTable(tableName).Filter("location", "default").OrderBy("counter_key").All(&results)
-
-	return results, err
-}
-
-// getCounterConfig 从admin-service获取计数器配置
-func getCounterConfig(appId, counterKey string) (*CounterConfig, error) {
-	// 这里应该调用admin-service的API获取配置
-	// 暂时返回一个默认配置，实际使用时需要实现HTTP调用
-	adminServiceURL := utils.GetAdminServiceURL()
-	if adminServiceURL == "" {
-		return &CounterConfig{
-			AppId:      appId,
-			CounterKey: counterKey,
-			ResetType:  "permanent",
-			IsActive:   true,
-		}, nil
-	}
-
-	// 实现HTTP调用获取配置的逻辑
-	url := fmt.Sprintf("%s/counter/getCounterConfig", adminServiceURL)
-
-	requestBody := map[string]interface{}{
-		"appId": appId,
-		"key":   counterKey,
-	}
-
-	_, err := json.Marshal(requestBody)
+	_, err = o.QueryTable(tableName).Filter("player_id", "").Filter("location", "default").OrderBy("counter_key").All(&results)
 	if err != nil {
-		return nil, err
-	}
-
-	resp, err := http.Post(url, "application/json", nil)
-	if err != nil {
-		logs.Error("Failed to get counter config from admin-service: %v", err)
-		// 返回默认配置
-		return &CounterConfig{
-			AppId:      appId,
-			CounterKey: counterKey,
-			ResetType:  "permanent",
-			IsActive:   true,
-		}, nil
-	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Code int            `json:"code"`
-		Data *CounterConfig `json:"data"`
-	}
-
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	if err != nil || result.Code != 0 {
-		// 返回默认配置
-		return &CounterConfig{
-			AppId:      appId,
-			CounterKey: counterKey,
-			ResetType:  "permanent",
-			IsActive:   true,
-		}, nil
-	}
-
-	return result.Data, nil
-}
-
-// checkLocationExists 检查点位是否存在
-func checkLocationExists(appId, counterKey, location string) error {
-	// 这里可以通过admin-service检查点位是否在配置中存在
-	// 暂时返回nil，表示所有点位都允许
-	return nil
-}
-
-// checkAndResetCounter 检查并重置计数器
-func checkAndResetCounter(appId, counterKey, location string, config *CounterConfig) error {
-	if config.ResetType == "permanent" || config.NextResetTime.IsZero() {
-		return nil
+		return results, err
 	}
 
 	now := time.Now()
-	if now.After(config.NextResetTime) {
-		logs.Info("计数器需要重置: appId=%s, key=%s, location=%s, resetTime=%v",
-			appId, counterKey, location, config.NextResetTime)
-
-		// 重置计数器值
-		err := resetCounterValue(appId, counterKey, location)
+	for i := range results {
+		config, err := getCounterConfig(appId, results[i].CounterKey)
 		if err != nil {
-			return fmt.Errorf("重置计数器失败: %v", err)
+			continue
 		}
-
-		// 计算下次重置时间并更新配置（通过admin-service）
-		nextResetTime := calculateCounterNextResetTime(config.ResetType, config.ResetValue)
-		if nextResetTime != nil {
-			err = updateCounterResetTime(appId, counterKey, *nextResetTime)
-			if err != nil {
-				logs.Error("更新计数器重置时间失败: %v", err)
-			} else {
-				logs.Info("计数器重置完成，下次重置时间: %v", *nextResetTime)
+		if RedisClient != nil {
+			// 数据库中的值可能尚未落库，以Redis中的当前值为准
+			if value, err := getCounterValueFromRedis(appId, results[i].CounterKey, "", "default", config); err == nil {
+				results[i].Value = value
 			}
+		} else if period := getCounterPeriod(config, now); period.Id != counterPermanentPeriod && results[i].UpdatedAt.Before(period.Start) {
+			// 上次更新不在当前周期内，视为已重置
+			results[i].Value = 0
 		}
 	}
 
-	return nil
+	return results, nil
 }
 
-// resetCounterValue 重置计数器值
-func resetCounterValue(appId, counterKey, location string) error {
-	_, tableName, err := GetCounterModel(appId)
-	if err != nil {
-		return err
-	}
-
-	o := orm.NewOrm()
-	sql := fmt.Sprintf(`UPDATE %s SET value = 0, updated_at = NOW() WHERE counter_key = ? AND location = ?`, tableName)
-	_, err = o.Raw(sql, counterKey, location).Exec()
-	return err
-}
-
-// calculateCounterNextResetTime 计算计数器下次重置时间
-func calculateCounterNextResetTime(resetType string, resetValue int) *time.Time {
-	now := time.Now()
-	var nextReset time.Time
-
-	switch resetType {
-	case "daily":
-		nextReset = now.AddDate(0, 0, 1).Truncate(24 * time.Hour)
-	case "weekly":
-		// 下周一0点
-		weekday := now.Weekday()
-		daysToMonday := (7 - int(weekday) + 1) % 7
-		if daysToMonday == 0 {
-			daysToMonday = 7
-		}
-		nextReset = now.AddDate(0, 0, daysToMonday).Truncate(24 * time.Hour)
-	case "monthly":
-		// 下月1号0点
-		year, month, _ := now.Date()
-		nextReset = time.Date(year, month+1, 1, 0, 0, 0, 0, now.Location())
-	case "custom":
-		if resetValue > 0 {
-			nextReset = now.Add(time.Duration(resetValue) * time.Hour)
-		} else {
-			return nil
+// getCounterConfig 获取计数器配置（与admin-service共用counter_config表，进程内缓存一小段时间）
+// 未在管理后台创建的计数器按永久全局计数器处理，已删除的计数器返回ErrCounterNotFound
+func getCounterConfig(appId, counterKey string) (*CounterConfig, error) {
+	cacheKey := appId + ":" + counterKey
+	if cached, ok := counterConfigCache.Load(cacheKey); ok {
+		entry := cached.(*counterConfigCacheEntry)
+		if time.Now().Before(entry.expireAt) {
+			return entry.config, entry.err
 		}
-	default:
-		return nil
 	}
 
-	return &nextReset
-}
-
-// updateCounterResetTime 更新计数器重置时间（通过admin-service）
-func updateCounterResetTime(appId, counterKey string, resetTime time.Time) error {
-	// 这里应该调用admin-service的API更新重置时间
-	adminServiceURL := utils.GetAdminServiceURL()
-	if adminServiceURL == "" {
-		return nil // 如果没有admin-service，忽略更新
+	o := orm.NewOrm()
+	var result []orm.Params
+	sql := `SELECT id, reset_type, reset_value, next_reset_time, description, is_active, scope, max_value, thresholds, created_at, updated_at FROM counter_config WHERE app_id = ? AND counter_key = ?`
+	if _, err := o.Raw(sql, appId, counterKey).Values(&result); err != nil {
+		return nil, err
 	}
 
-	// 实现HTTP调用更新重置时间的逻辑
-	url := fmt.Sprintf("%s/counter/updateCounterResetTime", adminServiceURL)
-
-	requestBody := map[string]interface{}{
-		"appId":     appId,
-		"key":       counterKey,
-		"resetTime": resetTime.Format("2006-01-02 15:04:05"),
+	config := &CounterConfig{
+		AppId:      appId,
+		CounterKey: counterKey,
+		ResetType:  "permanent",
+		Scope:      CounterScopeGlobal,
+		IsActive:   true,
 	}
+	var configErr error
+	if len(result) > 0 {
+		data := result[0]
+		config.ID = paramToInt64(data["id"])
+		if resetType, ok := data["reset_type"].(string); ok && resetType != "" {
+			config.ResetType = resetType
+		}
+		config.ResetValue = int(paramToInt64(data["reset_value"]))
+		config.NextResetTime = paramToTime(data["next_reset_time"])
+		if description, ok := data["description"].(string); ok {
+			config.Description = description
+		}
+		config.IsActive = paramToBool(data["is_active"])
+		if scope, ok := data["scope"].(string); ok && scope != "" {
+			config.Scope = scope
+		}
+		config.MaxValue = paramToInt64(data["max_value"])
+		if thresholds, ok := data["thresholds"].(string); ok {
+			config.Thresholds = thresholds
+			parsed, err := parseCounterThresholds(thresholds)
+			if err != nil {
+				// 阈值配置错误不影响计数，只记录日志
+				logs.Error("计数器阈值配置错误: appId=%s, key=%s, err=%v", appId, counterKey, err)
+			}
+			config.thresholds = parsed
+		}
+		config.CreatedAt = paramToTime(data["created_at"])
+		config.UpdatedAt = paramToTime(data["updated_at"])
 
-	_, err := json.Marshal(requestBody)
-	if err != nil {
-		return err
+		if !config.IsActive {
+			config, configErr = nil, ErrCounterNotFound
+		}
 	}
 
-	resp, err := http.Post(url, "application/json", nil)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	counterConfigCache.Store(cacheKey, &counterConfigCacheEntry{
+		config:   config,
+		err:      configErr,
+		expireAt: time.Now().Add(counterConfigCacheTTL),
+	})
+	return config, configErr
+}
 
+// checkLocationExists 检查点位是否存在
+func checkLocationExists(appId, counterKey, location string) error {
+	// 这里可以通过admin-service检查点位是否在配置中存在
+	// 暂时返回nil，表示所有点位都允许
 	return nil
 }
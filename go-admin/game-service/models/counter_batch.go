@@ -0,0 +1,326 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/go-redis/redis/v8"
+)
+
+// 批量操作类型
+const (
+	CounterBatchGet       = "get"
+	CounterBatchIncrement = "increment"
+	CounterBatchDecrement = "decrement"
+)
+
+// CounterBatchMaxOps 单次批量操作的最大数量
+const CounterBatchMaxOps = 50
+
+// ErrCounterBatchInvalid 批量操作参数错误
+var ErrCounterBatchInvalid = errors.New("批量操作参数错误")
+
+// CounterBatchOp 批量操作中的单个操作
+type CounterBatchOp struct {
+	Op       string `json:"op"` // get/increment/decrement
+	Key      string `json:"key"`
+	Location string `json:"location,omitempty"`
+	Value    int64  `json:"value,omitempty"` // 增加或减少的数量，默认1
+}
+
+// CounterBatchResult 单个操作的结果
+type CounterBatchResult struct {
+	Op           string `json:"op"`
+	Key          string `json:"key"`
+	Location     string `json:"location"`
+	CurrentValue int64  `json:"currentValue"`
+}
+
+// CounterBatchError 批量操作中某个操作失败，整批操作均未生效
+type CounterBatchError struct {
+	Index        int // 失败操作的序号（从0开始）
+	Key          string
+	Location     string
+	CurrentValue int64
+	Err          error
+}
+
+func (e *CounterBatchError) Error() string {
+	return fmt.Sprintf("第%d个操作失败: %v", e.Index+1, e.Err)
+}
+
+func (e *CounterBatchError) Unwrap() error {
+	return e.Err
+}
+
+// counterBatchStep 校验后的批量操作
+type counterBatchStep struct {
+	config   *CounterConfig
+	owner    string
+	location string
+	delta    int64
+	write    bool
+}
+
+// counterBatchScript 原子执行一批计数器操作，任一操作超过上限时整批不修改
+// KEYS为涉及的计数器周期键，最后一个为待落库集合
+// ARGV[1]为周期键数量n，随后每个周期键依次为过期时间、待落库标记，再之后每个操作依次为键序号、点位、增量、是否不小于0、上限、是否写入
+// 成功返回{1, 各操作结果...}，超过上限返回{0, 操作序号, 当前值}
+var counterBatchScript = redis.NewScript(`
+local n = tonumber(ARGV[1])
+local base = 2 + 2 * n
+local values = {}
+local written = {}
+local results = {}
+local index = 0
+for o = base, #ARGV, 6 do
+	index = index + 1
+	local k = tonumber(ARGV[o])
+	local id = k .. ':' .. ARGV[o + 1]
+	local cur = values[id]
+	if cur == nil then
+		cur = tonumber(redis.call('HGET', KEYS[k], ARGV[o + 1]) or '0')
+	end
+	if ARGV[o + 5] == '1' then
+		local delta = tonumber(ARGV[o + 2])
+		local limit = tonumber(ARGV[o + 4])
+		local v = cur + delta
+		if limit > 0 and delta > 0 and v > limit then
+			return {0, index, cur}
+		end
+		if ARGV[o + 3] == '1' and v < 0 then
+			v = 0
+		end
+		cur = v
+		written[id] = {k, ARGV[o + 1]}
+	end
+	values[id] = cur
+	results[index] = cur
+end
+local touched = {}
+for id, target in pairs(written) do
+	redis.call('HSET', KEYS[target[1]], target[2], string.format('%d', values[id]))
+	touched[target[1]] = true
+end
+for k in pairs(touched) do
+	redis.call('EXPIRE', KEYS[k], ARGV[2 * k])
+	redis.call('SADD', KEYS[n + 1], ARGV[2 * k + 1])
+end
+local reply = {1}
+for i = 1, index do
+	reply[i + 1] = results[i]
+end
+return reply
+`)
+
+// ApplyCounterBatch 在一个事务中执行一批计数器读取、增加、减少操作，返回每个操作后的值
+// 任一操作失败（如超过上限）时整批操作均不生效，返回CounterBatchError
+func ApplyCounterBatch(appId, playerId string, ops []CounterBatchOp) ([]CounterBatchResult, error) {
+	if len(ops) == 0 || len(ops) > CounterBatchMaxOps {
+		return nil, ErrCounterBatchInvalid
+	}
+
+	// 1. 校验所有操作，同一个计数器的配置只读取一次
+	configs := make(map[string]*CounterConfig)
+	steps := make([]counterBatchStep, len(ops))
+	for i := range ops {
+		op := &ops[i]
+		if op.Location == "" {
+			op.Location = "default"
+		}
+		if op.Key == "" {
+			return nil, &CounterBatchError{Index: i, Location: op.Location, Err: ErrCounterBatchInvalid}
+		}
+		if op.Op != CounterBatchGet && op.Value <= 0 {
+			op.Value = 1
+		}
+
+		config, ok := configs[op.Key]
+		if !ok {
+			var err error
+			if config, err = getCounterConfig(appId, op.Key); err != nil {
+				return nil, &CounterBatchError{Index: i, Key: op.Key, Location: op.Location, Err: err}
+			}
+			configs[op.Key] = config
+		}
+		owner, err := resolveCounterOwner(config, playerId)
+		if err != nil {
+			return nil, &CounterBatchError{Index: i, Key: op.Key, Location: op.Location, Err: err}
+		}
+
+		step := counterBatchStep{config: config, owner: owner, location: op.Location}
+		switch op.Op {
+		case CounterBatchGet:
+		case CounterBatchIncrement:
+			step.delta, step.write = op.Value, true
+		case CounterBatchDecrement:
+			if isCounterReadOnly(config) {
+				return nil, &CounterBatchError{Index: i, Key: op.Key, Location: op.Location, Err: ErrCounterReadOnly}
+			}
+			step.delta, step.write = -op.Value, true
+		default:
+			return nil, &CounterBatchError{Index: i, Key: op.Key, Location: op.Location, Err: ErrCounterBatchInvalid}
+		}
+		if step.write {
+			if err := checkLocationExists(appId, op.Key, op.Location); err != nil {
+				return nil, &CounterBatchError{Index: i, Key: op.Key, Location: op.Location, Err: err}
+			}
+		}
+		steps[i] = step
+	}
+
+	// 2. 启用Redis时通过脚本原子执行，否则在数据库事务中执行
+	var values []int64
+	var err error
+	if RedisClient != nil {
+		values, err = applyCounterBatchInRedis(appId, ops, steps)
+	} else {
+		values, err = applyCounterBatchInDB(appId, ops, steps)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 组装结果并检查阈值
+	results := make([]CounterBatchResult, len(ops))
+	for i, op := range ops {
+		results[i] = CounterBatchResult{Op: op.Op, Key: op.Key, Location: op.Location, CurrentValue: values[i]}
+		if steps[i].write {
+			checkCounterThresholds(appId, steps[i].config, op.Location, values[i])
+		}
+	}
+	return results, nil
+}
+
+// applyCounterBatchInRedis 在Redis中原子执行批量操作
+func applyCounterBatchInRedis(appId string, ops []CounterBatchOp, steps []counterBatchStep) ([]int64, error) {
+	ctx := RedisClient.Context()
+	now := time.Now()
+
+	keyIndex := make(map[string]int)
+	keys := make([]string, 0)
+	keyArgs := make([]interface{}, 0)
+	opArgs := make([]interface{}, 0, len(ops)*6)
+	for i, op := range ops {
+		step := steps[i]
+		period := getCounterPeriod(step.config, now)
+		redisKey := getCounterRedisKey(appId, op.Key, step.owner, period.Id)
+
+		index, ok := keyIndex[redisKey]
+		if !ok {
+			if err := ensureCounterLoaded(ctx, appId, op.Key, step.owner, period); err != nil {
+				return nil, err
+			}
+			keys = append(keys, redisKey)
+			index = len(keys)
+			keyIndex[redisKey] = index
+			keyArgs = append(keyArgs, int64(period.ttl(now).Seconds()), encodeCounterDirtyMember(appId, op.Key, step.owner, period))
+		}
+
+		limit, clamp, write := int64(0), "0", "0"
+		if step.write {
+			write = "1"
+			if step.delta > 0 {
+				limit = step.config.MaxValue
+			} else {
+				clamp = "1"
+			}
+		}
+		opArgs = append(opArgs, index, step.location, step.delta, clamp, limit, write)
+	}
+
+	args := append([]interface{}{len(keys)}, keyArgs...)
+	args = append(args, opArgs...)
+	result, err := counterBatchScript.Run(ctx, RedisClient, append(keys, counterDirtyKey), args...).Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("unexpected counter batch script result: %v", result)
+	}
+
+	if applied, _ := result[0].(int64); applied == 0 {
+		if len(result) != 3 {
+			return nil, fmt.Errorf("unexpected counter batch script result: %v", result)
+		}
+		index, _ := result[1].(int64)
+		current, _ := result[2].(int64)
+		i := int(index) - 1
+		return nil, &CounterBatchError{Index: i, Key: ops[i].Key, Location: ops[i].Location, CurrentValue: current, Err: ErrCounterLimitReached}
+	}
+	if len(result) != len(ops)+1 {
+		return nil, fmt.Errorf("unexpected counter batch script result: %v", result)
+	}
+
+	values := make([]int64, len(ops))
+	for i := range ops {
+		values[i], _ = result[i+1].(int64)
+	}
+	return values, nil
+}
+
+// applyCounterBatchInDB 在数据库事务中执行批量操作（未启用Redis时使用）
+func applyCounterBatchInDB(appId string, ops []CounterBatchOp, steps []counterBatchStep) ([]int64, error) {
+	now := time.Now()
+
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	type target struct {
+		key, owner, location string
+	}
+	current := make(map[target]int64)
+	dirty := make(map[target]bool)
+	written := make([]target, 0)
+	values := make([]int64, len(ops))
+	for i, op := range ops {
+		step := steps[i]
+		t := target{key: op.Key, owner: step.owner, location: step.location}
+
+		value, ok := current[t]
+		if !ok {
+			period := getCounterPeriod(step.config, now)
+			rows, err := getCounterValuesFromDB(tx, appId, op.Key, step.owner, step.location, period, true)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			value = rows[step.location]
+		}
+
+		if step.write {
+			next := value + step.delta
+			if limit := step.config.MaxValue; limit > 0 && step.delta > 0 && next > limit {
+				tx.Rollback()
+				return nil, &CounterBatchError{Index: i, Key: op.Key, Location: op.Location, CurrentValue: value, Err: ErrCounterLimitReached}
+			}
+			if step.delta < 0 && next < 0 {
+				next = 0
+			}
+			if !dirty[t] {
+				dirty[t] = true
+				written = append(written, t)
+			}
+			value = next
+		}
+		current[t] = value
+		values[i] = value
+	}
+
+	for _, t := range written {
+		if err := upsertCounterValue(tx, appId, t.key, t.owner, t.location, current[t]); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
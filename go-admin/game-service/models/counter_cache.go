@@ -0,0 +1,494 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"game-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/config"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/go-redis/redis/v8"
+)
+
+// 计数器Redis键（与管理后台约定一致）
+// 计数值按重置周期分键存放：counter:{appId}:{period}:{key}（hash，字段为点位），进入新周期自动使用新键，无需逐个重置
+// 玩家计数器每个玩家单独一个键：counter_player:{appId}:{period}:{playerId}:{key}
+const (
+	counterRedisKeyPrefix        = "counter:"
+	counterPlayerRedisKeyPrefix  = "counter_player:"
+	counterLoadedKeyPrefix       = "counter_loaded:" // 已从数据库加载的标记，后接与计数器键相同的部分
+	counterPlayerLoadedKeyPrefix = "counter_player_loaded:"
+	counterDirtyKey              = "counter_dirty"        // 待落库的计数器周期键
+	counterPlayerLockKeyPrefix   = "counter_player_lock:" // 管理后台合并玩家期间的玩家计数器锁，后接appId:playerId
+	counterPermanentPeriod       = "all"                  // 永久计数器的周期标识
+)
+
+const (
+	// counterFlushBatchSize 每批落库的计数器数
+	counterFlushBatchSize = 200
+	// counterPermanentTTL 永久计数器的Redis过期时间（每次写入时续期，过期后从数据库重新加载）
+	counterPermanentTTL = 7 * 24 * time.Hour
+	// counterPeriodGrace 周期计数器在周期结束后保留的时间
+	counterPeriodGrace = 24 * time.Hour
+)
+
+// counterIncrScript 原子增减计数值，同时续期并标记待落库
+// limit大于0时增加后超过上限则不修改，返回{0, 当前值}；clamp为1时结果不小于0
+var counterIncrScript = redis.NewScript(`
+local limit = tonumber(ARGV[6])
+if limit > 0 then
+	local cur = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+	if cur + tonumber(ARGV[2]) > limit then
+		return {0, cur}
+	end
+end
+local v = redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[2])
+if ARGV[3] == '1' and v < 0 then
+	v = 0
+	redis.call('HSET', KEYS[1], ARGV[1], 0)
+end
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+redis.call('SADD', KEYS[2], ARGV[5])
+return {1, v}
+`)
+
+// counterLoadScript 从数据库加载的计数值写入Redis，键已存在（或已加载过）时不覆盖
+var counterLoadScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 or redis.call('EXISTS', KEYS[2]) == 1 then
+	return 0
+end
+for i = 2, #ARGV, 2 do
+	redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+if #ARGV > 1 then
+	redis.call('EXPIRE', KEYS[1], ARGV[1])
+end
+redis.call('SET', KEYS[2], 1, 'EX', ARGV[1])
+return 1
+`)
+
+// counterPeriod 计数器当前重置周期
+type counterPeriod struct {
+	Id    string    // 周期标识
+	Start time.Time // 周期开始时间
+	End   time.Time // 周期结束时间
+}
+
+// counterDirtyMember 待落库标记
+type counterDirtyMember struct {
+	AppId      string `json:"a"`
+	CounterKey string `json:"k"`
+	PlayerId   string `json:"u,omitempty"`
+	Period     string `json:"p"`
+	End        int64  `json:"e,omitempty"`
+}
+
+// getCounterPeriod 根据重置类型计算当前周期
+func getCounterPeriod(config *CounterConfig, now time.Time) counterPeriod {
+	year, month, day := now.Date()
+	switch config.ResetType {
+	case "daily":
+		start := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+		return counterPeriod{Id: start.Format("20060102"), Start: start, End: start.AddDate(0, 0, 1)}
+	case "weekly":
+		// 周一为一周的开始
+		offset := (int(now.Weekday()) + 6) % 7
+		start := time.Date(year, month, day-offset, 0, 0, 0, 0, now.Location())
+		return counterPeriod{Id: "w" + start.Format("20060102"), Start: start, End: start.AddDate(0, 0, 7)}
+	case "monthly":
+		start := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+		return counterPeriod{Id: start.Format("200601"), Start: start, End: start.AddDate(0, 1, 0)}
+	case "custom":
+		// 自定义周期以配置的下次重置时间为基准，每resetValue小时重置一次，保持已有计数器的重置时间不变
+		if config.ResetValue > 0 {
+			length := int64(config.ResetValue) * 3600
+			var end int64
+			if !config.NextResetTime.IsZero() {
+				end = config.NextResetTime.Unix()
+			}
+			// 按整数个周期平移结束时间，使now落在[end-length, end)内
+			if diff := now.Unix() - end; diff >= 0 {
+				end += (diff/length + 1) * length
+			} else {
+				end -= ((-diff - 1) / length) * length
+			}
+			return counterPeriod{Id: "c" + strconv.FormatInt(end, 10), Start: time.Unix(end-length, 0), End: time.Unix(end, 0)}
+		}
+	}
+	return counterPeriod{Id: counterPermanentPeriod}
+}
+
+// ttl Redis键的过期时间
+func (p counterPeriod) ttl(now time.Time) time.Duration {
+	if p.Id == counterPermanentPeriod {
+		return counterPermanentTTL
+	}
+	return p.End.Sub(now) + counterPeriodGrace
+}
+
+// getCounterRedisKey 获取计数器周期键，playerId不为空时为玩家计数器
+func getCounterRedisKey(appId, counterKey, playerId, period string) string {
+	if playerId != "" {
+		return counterPlayerRedisKeyPrefix + appId + ":" + period + ":" + playerId + ":" + counterKey
+	}
+	return counterRedisKeyPrefix + appId + ":" + period + ":" + counterKey
+}
+
+// getCounterLoadedKey 获取计数器周期的加载标记键
+func getCounterLoadedKey(appId, counterKey, playerId, period string) string {
+	if playerId != "" {
+		return counterPlayerLoadedKeyPrefix + appId + ":" + period + ":" + playerId + ":" + counterKey
+	}
+	return counterLoadedKeyPrefix + appId + ":" + period + ":" + counterKey
+}
+
+// encodeCounterDirtyMember 生成待落库标记
+func encodeCounterDirtyMember(appId, counterKey, playerId string, period counterPeriod) string {
+	member := counterDirtyMember{AppId: appId, CounterKey: counterKey, PlayerId: playerId, Period: period.Id}
+	if period.Id != counterPermanentPeriod {
+		member.End = period.End.Unix()
+	}
+	data, _ := json.Marshal(member)
+	return string(data)
+}
+
+// ensureCounterLoaded 确保计数器当前周期的数据已从数据库加载到Redis
+// 玩家正在被管理后台合并时返回ErrCounterPlayerLocked，合并期间不读写该玩家的计数器缓存
+func ensureCounterLoaded(ctx context.Context, appId, counterKey, playerId string, period counterPeriod) error {
+	if playerId != "" {
+		locked, err := isCounterPlayerLocked(ctx, appId, playerId)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return ErrCounterPlayerLocked
+		}
+	}
+
+	exists, err := RedisClient.Exists(ctx, getCounterRedisKey(appId, counterKey, playerId, period.Id), getCounterLoadedKey(appId, counterKey, playerId, period.Id)).Result()
+	if err != nil || exists > 0 {
+		return err
+	}
+	return loadCounterFromDB(ctx, appId, counterKey, playerId, period)
+}
+
+// loadCounterFromDB 从数据库加载计数器当前周期的值（上次更新不在当前周期内的点位视为已重置）
+func loadCounterFromDB(ctx context.Context, appId, counterKey, playerId string, period counterPeriod) error {
+	values, err := getCounterValuesFromDB(orm.NewOrm(), appId, counterKey, playerId, "", period, false)
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{int64(period.ttl(time.Now()).Seconds())}
+	for location, value := range values {
+		args = append(args, location, value)
+	}
+
+	keys := []string{getCounterRedisKey(appId, counterKey, playerId, period.Id), getCounterLoadedKey(appId, counterKey, playerId, period.Id)}
+	return counterLoadScript.Run(ctx, RedisClient, keys, args...).Err()
+}
+
+// getCounterValuesFromRedis 从Redis获取计数器所有点位的值
+func getCounterValuesFromRedis(appId, counterKey, playerId string, config *CounterConfig) (map[string]int64, error) {
+	ctx := RedisClient.Context()
+	period := getCounterPeriod(config, time.Now())
+	if err := ensureCounterLoaded(ctx, appId, counterKey, playerId, period); err != nil {
+		return nil, err
+	}
+
+	result, err := RedisClient.HGetAll(ctx, getCounterRedisKey(appId, counterKey, playerId, period.Id)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int64, len(result))
+	for location, valueStr := range result {
+		value, err := strconv.ParseInt(valueStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse counter value: %v", err)
+		}
+		values[location] = value
+	}
+	return values, nil
+}
+
+// getCounterValueFromRedis 从Redis获取计数器指定点位的值
+func getCounterValueFromRedis(appId, counterKey, playerId, location string, config *CounterConfig) (int64, error) {
+	ctx := RedisClient.Context()
+	period := getCounterPeriod(config, time.Now())
+	if err := ensureCounterLoaded(ctx, appId, counterKey, playerId, period); err != nil {
+		return 0, err
+	}
+
+	value, err := RedisClient.HGet(ctx, getCounterRedisKey(appId, counterKey, playerId, period.Id), location).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return value, err
+}
+
+// incrementCounterInRedis 在Redis中增减计数器值
+// limit大于0时增加后超过上限则不修改并返回ErrCounterLimitReached和当前值，clamp为true时结果不小于0
+func incrementCounterInRedis(appId, counterKey, playerId, location string, delta int64, clamp bool, limit int64, config *CounterConfig) (int64, error) {
+	ctx := RedisClient.Context()
+	now := time.Now()
+	period := getCounterPeriod(config, now)
+	if err := ensureCounterLoaded(ctx, appId, counterKey, playerId, period); err != nil {
+		return 0, err
+	}
+
+	clampFlag := "0"
+	if clamp {
+		clampFlag = "1"
+	}
+
+	keys := []string{getCounterRedisKey(appId, counterKey, playerId, period.Id), counterDirtyKey}
+	result, err := counterIncrScript.Run(ctx, RedisClient, keys,
+		location, delta, clampFlag, int64(period.ttl(now).Seconds()),
+		encodeCounterDirtyMember(appId, counterKey, playerId, period), limit).Slice()
+	if err != nil {
+		return 0, err
+	}
+	if len(result) != 2 {
+		return 0, fmt.Errorf("unexpected counter script result: %v", result)
+	}
+
+	value, _ := result[1].(int64)
+	if applied, _ := result[0].(int64); applied == 0 {
+		return value, ErrCounterLimitReached
+	}
+	return value, nil
+}
+
+// setCounterInRedis 在Redis中设置计数器值
+func setCounterInRedis(appId, counterKey, playerId, location string, value int64, config *CounterConfig) error {
+	ctx := RedisClient.Context()
+	now := time.Now()
+	period := getCounterPeriod(config, now)
+	if err := ensureCounterLoaded(ctx, appId, counterKey, playerId, period); err != nil {
+		return err
+	}
+
+	scoreKey := getCounterRedisKey(appId, counterKey, playerId, period.Id)
+	_, err := RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, scoreKey, location, value)
+		pipe.Expire(ctx, scoreKey, period.ttl(now))
+		pipe.SAdd(ctx, counterDirtyKey, encodeCounterDirtyMember(appId, counterKey, playerId, period))
+		return nil
+	})
+	return err
+}
+
+// StartCounterFlusher 启动计数器落库任务
+// 启动时将数据库中的计数器加载到Redis，之后按counter_flush_interval（秒，默认5）把有变化的计数器写回数据库
+func StartCounterFlusher() {
+	if RedisClient == nil {
+		return
+	}
+
+	appconf, _ := config.NewConfig("ini", "conf/app.conf")
+	interval := 5
+	if appconf != nil {
+		interval = appconf.DefaultInt("counter_flush_interval", 5)
+	}
+	if interval <= 0 {
+		interval = 5
+	}
+
+	go func() {
+		rehydrateCounters()
+
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			flushDirtyCounters()
+		}
+	}()
+
+	logs.Info("计数器落库任务已启动: interval=%ds", interval)
+}
+
+// rehydrateCounters 将所有启用应用的全局计数器从数据库加载到Redis（Redis中已有的计数器保持不变）
+// 玩家计数器数量较多，在玩家访问时按需加载
+func rehydrateCounters() {
+	ctx := context.Background()
+	o := orm.NewOrm()
+
+	var apps []orm.Params
+	if _, err := o.Raw(`SELECT app_id FROM apps WHERE status = 'active'`).Values(&apps); err != nil {
+		logs.Warn("加载计数器失败，查询应用列表失败: %v", err)
+		return
+	}
+
+	loaded := 0
+	for _, app := range apps {
+		appId, _ := app["app_id"].(string)
+		if appId == "" {
+			continue
+		}
+
+		var keys []orm.Params
+		sql := fmt.Sprintf(`SELECT DISTINCT counter_key FROM %s WHERE player_id = ''`, utils.GetCounterTableName(appId))
+		if _, err := o.Raw(sql).Values(&keys); err != nil {
+			logs.Warn("加载计数器失败: appId=%s, err=%v", appId, err)
+			continue
+		}
+
+		for _, row := range keys {
+			counterKey, _ := row["counter_key"].(string)
+			if counterKey == "" {
+				continue
+			}
+			counterConfig, err := getCounterConfig(appId, counterKey)
+			if err != nil {
+				continue
+			}
+			period := getCounterPeriod(counterConfig, time.Now())
+			if err := ensureCounterLoaded(ctx, appId, counterKey, "", period); err != nil {
+				logs.Warn("加载计数器失败: appId=%s, key=%s, err=%v", appId, counterKey, err)
+				continue
+			}
+			loaded++
+		}
+	}
+
+	logs.Info("计数器已加载到Redis: count=%d", loaded)
+}
+
+// flushDirtyCounters 将有变化的计数器写回数据库
+// 通过SPOP领取待落库的键，多实例部署时同一个键只会被一个实例写入
+func flushDirtyCounters() {
+	ctx := context.Background()
+	for {
+		members, err := RedisClient.SPopN(ctx, counterDirtyKey, counterFlushBatchSize).Result()
+		if err != nil {
+			if err != redis.Nil {
+				logs.Warn("读取待落库计数器失败: %v", err)
+			}
+			return
+		}
+		if len(members) == 0 {
+			return
+		}
+
+		for _, data := range members {
+			var member counterDirtyMember
+			if err := json.Unmarshal([]byte(data), &member); err != nil || member.AppId == "" {
+				continue
+			}
+			if err := flushCounter(ctx, &member); err != nil {
+				logs.Error("计数器落库失败: appId=%s, key=%s, err=%v", member.AppId, member.CounterKey, err)
+				RedisClient.SAdd(ctx, counterDirtyKey, data)
+			}
+		}
+
+		if len(members) < counterFlushBatchSize {
+			return
+		}
+	}
+}
+
+// flushCounter 将单个计数器周期键写回数据库
+// 已结束的周期在键过期（宽限期）前仍会写回，保证周期最后一次落库前的增量不丢失
+func flushCounter(ctx context.Context, member *counterDirtyMember) error {
+	if member.PlayerId != "" {
+		return flushPlayerCounter(ctx, member)
+	}
+
+	values, err := RedisClient.HGetAll(ctx, getCounterRedisKey(member.AppId, member.CounterKey, member.PlayerId, member.Period)).Result()
+	if err != nil || len(values) == 0 {
+		return err
+	}
+	return writeCounterValues(orm.NewOrm(), member, values)
+}
+
+// flushPlayerCounter 将玩家计数器写回数据库
+// 先对玩家加共享锁再读取缓存，与管理后台合并玩家（对两个玩家加排他锁后接管缓存）互斥：
+// 玩家已被合并删除、或正在合并时跳过，避免旧的缓存值覆盖合并后的结果
+func flushPlayerCounter(ctx context.Context, member *counterDirtyMember) error {
+	tx, err := orm.NewOrm().Begin()
+	if err != nil {
+		return err
+	}
+
+	var users []orm.Params
+	_, err = tx.Raw(fmt.Sprintf(`SELECT player_id FROM %s WHERE player_id = ? LOCK IN SHARE MODE`, utils.GetUserTableName(member.AppId)),
+		member.PlayerId).Values(&users)
+	if err != nil || len(users) == 0 {
+		tx.Rollback()
+		return err
+	}
+
+	locked, err := isCounterPlayerLocked(ctx, member.AppId, member.PlayerId)
+	if err != nil || locked {
+		tx.Rollback()
+		return err
+	}
+
+	values, err := RedisClient.HGetAll(ctx, getCounterRedisKey(member.AppId, member.CounterKey, member.PlayerId, member.Period)).Result()
+	if err != nil || len(values) == 0 {
+		tx.Rollback()
+		return err
+	}
+
+	if err := writeCounterValues(tx, member, values); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// writeCounterValues 把计数器周期键中各点位的值写入数据库
+// 已结束的周期以周期最后一秒作为更新时间写入，且只覆盖更新时间早于周期结束的行，不会覆盖新周期已写入的值
+func writeCounterValues(o orm.QueryExecutor, member *counterDirtyMember, values map[string]string) error {
+	tableName := utils.GetCounterTableName(member.AppId)
+	ended := member.End > 0 && time.Now().Unix() >= member.End
+
+	placeholders := make([]string, 0, len(values))
+	args := make([]interface{}, 0, len(values)*5)
+	for location, value := range values {
+		if ended {
+			placeholders = append(placeholders, "(?, ?, ?, ?, NOW(), ?)")
+			args = append(args, member.CounterKey, member.PlayerId, location, value, time.Unix(member.End-1, 0))
+		} else {
+			placeholders = append(placeholders, "(?, ?, ?, ?, NOW(), NOW())")
+			args = append(args, member.CounterKey, member.PlayerId, location, value)
+		}
+	}
+
+	var sql string
+	if ended {
+		periodEnd := time.Unix(member.End, 0)
+		sql = fmt.Sprintf(`
+			INSERT INTO %s (counter_key, player_id, location, value, created_at, updated_at)
+			VALUES %s
+			ON DUPLICATE KEY UPDATE
+				value = IF(updated_at < ?, VALUES(value), value),
+				updated_at = IF(updated_at < ?, VALUES(updated_at), updated_at)
+		`, tableName, strings.Join(placeholders, ", "))
+		args = append(args, periodEnd, periodEnd)
+	} else {
+		sql = fmt.Sprintf(`
+			INSERT INTO %s (counter_key, player_id, location, value, created_at, updated_at)
+			VALUES %s
+			ON DUPLICATE KEY UPDATE
+				value = VALUES(value),
+				updated_at = NOW()
+		`, tableName, strings.Join(placeholders, ", "))
+	}
+
+	_, err := o.Raw(sql, args...).Exec()
+	return err
+}
+
+// isCounterPlayerLocked 玩家是否正在被管理后台合并
+func isCounterPlayerLocked(ctx context.Context, appId, playerId string) (bool, error) {
+	exists, err := RedisClient.Exists(ctx, counterPlayerLockKeyPrefix+appId+":"+playerId).Result()
+	return exists > 0, err
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestGetCounterPeriodCustom 自定义周期以下次重置时间为基准按resetValue小时滚动
+func TestGetCounterPeriodCustom(t *testing.T) {
+	nextReset := time.Date(2026, 3, 10, 15, 30, 0, 0, time.Local)
+	config := &CounterConfig{ResetType: "custom", ResetValue: 6, NextResetTime: nextReset}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		end  time.Time
+	}{
+		{"重置前的当前周期", nextReset.Add(-time.Hour), nextReset},
+		{"当前周期开始时刻", nextReset.Add(-6 * time.Hour), nextReset},
+		{"更早的周期", nextReset.Add(-6*time.Hour - time.Second), nextReset.Add(-6 * time.Hour)},
+		{"恰好到达重置时间", nextReset, nextReset.Add(6 * time.Hour)},
+		{"重置后顺延多个周期", nextReset.Add(20 * time.Hour), nextReset.Add(24 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period := getCounterPeriod(config, tt.now)
+			if !period.End.Equal(tt.end) || !period.Start.Equal(tt.end.Add(-6*time.Hour)) {
+				t.Fatalf("周期应为[%v, %v)，实际为[%v, %v)", tt.end.Add(-6*time.Hour), tt.end, period.Start, period.End)
+			}
+			if tt.now.Before(period.Start) || !tt.now.Before(period.End) {
+				t.Errorf("当前时间%v不在周期内", tt.now)
+			}
+			if again := getCounterPeriod(config, period.Start); again.Id != period.Id {
+				t.Errorf("同一周期的标识应一致: %s != %s", again.Id, period.Id)
+			}
+		})
+	}
+}
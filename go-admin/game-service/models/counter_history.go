@@ -0,0 +1,199 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"game-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/config"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 计数器历史类型（与管理后台约定一致）
+const (
+	counterHistorySample = "sample" // 定时采样
+	counterHistoryReset  = "reset"  // 重置前的值
+)
+
+// StartCounterHistorySampler 启动计数器历史采样任务
+// 按counter_history_interval（秒，默认3600）记录全局计数器各点位的值，周期计数器进入新周期后保存上一周期的最终值
+// 超过counter_history_retention_days（默认90天）的历史数据会被清理
+func StartCounterHistorySampler() {
+	appconf, _ := config.NewConfig("ini", "conf/app.conf")
+	interval := 3600
+	retentionDays := 90
+	if appconf != nil {
+		interval = appconf.DefaultInt("counter_history_interval", 3600)
+		retentionDays = appconf.DefaultInt("counter_history_retention_days", 90)
+	}
+	if interval <= 0 {
+		logs.Info("计数器历史采样已关闭")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			// 采样时间按间隔对齐，多实例部署时同一时间点只会写入一次
+			sampledAt := time.Unix(now.Unix()/int64(interval)*int64(interval), 0)
+			sampleCounterHistory(sampledAt, retentionDays)
+		}
+	}()
+
+	logs.Info("计数器历史采样任务已启动: interval=%ds, retentionDays=%d", interval, retentionDays)
+}
+
+// sampleCounterHistory 对所有启用应用的全局计数器进行一次采样
+func sampleCounterHistory(sampledAt time.Time, retentionDays int) {
+	o := orm.NewOrm()
+
+	var apps []orm.Params
+	if _, err := o.Raw(`SELECT app_id FROM apps WHERE status = 'active'`).Values(&apps); err != nil {
+		logs.Warn("计数器历史采样失败，查询应用列表失败: %v", err)
+		return
+	}
+
+	sampled := 0
+	for _, app := range apps {
+		appId, _ := app["app_id"].(string)
+		if appId == "" {
+			continue
+		}
+
+		var keys []orm.Params
+		sql := fmt.Sprintf(`SELECT DISTINCT counter_key FROM %s WHERE player_id = ''`, utils.GetCounterTableName(appId))
+		if _, err := o.Raw(sql).Values(&keys); err != nil {
+			logs.Warn("计数器历史采样失败: appId=%s, err=%v", appId, err)
+			continue
+		}
+
+		for _, row := range keys {
+			counterKey, _ := row["counter_key"].(string)
+			if counterKey == "" {
+				continue
+			}
+			if err := sampleCounter(o, appId, counterKey, sampledAt); err != nil {
+				logs.Warn("计数器历史采样失败: appId=%s, key=%s, err=%v", appId, counterKey, err)
+				continue
+			}
+			sampled++
+		}
+
+		if retentionDays > 0 {
+			sql = fmt.Sprintf(`DELETE FROM %s WHERE sampled_at < ?`, utils.GetCounterHistoryTableName(appId))
+			if _, err := o.Raw(sql, sampledAt.AddDate(0, 0, -retentionDays)).Exec(); err != nil {
+				logs.Warn("清理计数器历史失败: appId=%s, err=%v", appId, err)
+			}
+		}
+	}
+
+	logs.Debug("计数器历史采样完成: count=%d", sampled)
+}
+
+// sampleCounter 记录单个计数器当前周期的值，并保存上一周期的最终值
+func sampleCounter(o orm.Ormer, appId, counterKey string, sampledAt time.Time) error {
+	counterConfig, err := getCounterConfig(appId, counterKey)
+	if err != nil {
+		return err
+	}
+
+	period := getCounterPeriod(counterConfig, sampledAt)
+	var values map[string]int64
+	if RedisClient != nil {
+		values, err = getCounterValuesFromRedis(appId, counterKey, "", counterConfig)
+	} else {
+		values, err = getCounterValuesFromDB(o, appId, counterKey, "", "", period, false)
+	}
+	if err != nil {
+		return err
+	}
+	if err := insertCounterHistory(o, appId, counterKey, period.Id, counterHistorySample, values, sampledAt); err != nil {
+		return err
+	}
+
+	if period.Id == counterPermanentPeriod {
+		return nil
+	}
+
+	// 上一周期的最终值，以周期结束时间记录，重复执行时唯一索引保证只写入一次
+	prev := getCounterPeriod(counterConfig, period.Start.Add(-time.Second))
+	prevValues, err := getCounterPeriodFinalValues(o, appId, counterKey, prev)
+	if err != nil {
+		return err
+	}
+	return insertCounterHistory(o, appId, counterKey, prev.Id, counterHistoryReset, prevValues, prev.End)
+}
+
+// getCounterPeriodFinalValues 获取计数器已结束周期的最终值
+// Redis中的周期键在结束后仍保留一段时间，优先读取；否则读取数据库中该周期内最后更新的点位
+func getCounterPeriodFinalValues(o orm.Ormer, appId, counterKey string, period counterPeriod) (map[string]int64, error) {
+	if RedisClient != nil {
+		ctx := context.Background()
+		result, err := RedisClient.HGetAll(ctx, getCounterRedisKey(appId, counterKey, "", period.Id)).Result()
+		if err == nil && len(result) > 0 {
+			values := make(map[string]int64, len(result))
+			for location, valueStr := range result {
+				value, _ := strconv.ParseInt(valueStr, 10, 64)
+				values[location] = value
+			}
+			return values, nil
+		}
+	}
+
+	var rows []orm.Params
+	sql := fmt.Sprintf(`SELECT location, value FROM %s WHERE counter_key = ? AND player_id = '' AND updated_at >= ? AND updated_at < ?`,
+		utils.GetCounterTableName(appId))
+	if _, err := o.Raw(sql, counterKey, period.Start, period.End).Values(&rows); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		location, _ := row["location"].(string)
+		values[location] = paramToInt64(row["value"])
+	}
+	return values, nil
+}
+
+// archiveCounterBeforeReset 重置前保存全局计数器点位的当前值
+func archiveCounterBeforeReset(appId, counterKey, location string) {
+	counterConfig, err := getCounterConfig(appId, counterKey)
+	if err != nil {
+		return
+	}
+	value, err := GetCounterValue(appId, counterKey, "", location)
+	if err != nil || value == 0 {
+		return
+	}
+
+	period := getCounterPeriod(counterConfig, time.Now())
+	err = insertCounterHistory(orm.NewOrm(), appId, counterKey, period.Id, counterHistoryReset, map[string]int64{location: value}, time.Now())
+	if err != nil {
+		logs.Warn("保存计数器重置前的值失败: appId=%s, key=%s, location=%s, err=%v", appId, counterKey, location, err)
+	}
+}
+
+// insertCounterHistory 批量写入计数器历史，同一时间点已存在的记录忽略
+func insertCounterHistory(o orm.QueryExecutor, appId, counterKey, period, sampleType string, values map[string]int64, sampledAt time.Time) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, 0, len(values))
+	params := make([]interface{}, 0, len(values)*6)
+	for location, value := range values {
+		placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, NOW())")
+		params = append(params, counterKey, location, period, value, sampleType, sampledAt)
+	}
+
+	sql := fmt.Sprintf(`INSERT IGNORE INTO %s (counter_key, location, period, value, sample_type, sampled_at, created_at) VALUES %s`,
+		utils.GetCounterHistoryTableName(appId), strings.Join(placeholders, ", "))
+	_, err := o.Raw(sql, params...).Exec()
+	return err
+}
@@ -0,0 +1,248 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"game-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 计数器阈值动作类型（与管理后台约定一致）
+const (
+	CounterActionMail    = "mail"    // 发送全服邮件
+	CounterActionConfig  = "config"  // 修改游戏配置
+	CounterActionWebhook = "webhook" // 调用外部接口
+)
+
+const (
+	// counterThresholdMailExpireDays 阈值邮件有效天数
+	counterThresholdMailExpireDays = 7
+	// counterWebhookTimeout 阈值回调超时时间
+	counterWebhookTimeout = 5 * time.Second
+)
+
+// CounterThreshold 计数器阈值，计数值达到阈值时执行动作，每个重置周期只执行一次
+type CounterThreshold struct {
+	Id       string                 `json:"id"`
+	Location string                 `json:"location,omitempty"` // 点位，默认default
+	Operator string                 `json:"operator"`           // gte: 达到或超过, lte: 降到或低于
+	Value    int64                  `json:"value"`
+	Action   CounterThresholdAction `json:"action"`
+}
+
+// CounterThresholdAction 阈值触发的动作
+// 邮件标题、内容和配置值支持占位符: {key} 计数器名称, {location} 点位, {value} 触发时的计数值
+type CounterThresholdAction struct {
+	Type        string          `json:"type"`
+	Title       string          `json:"title,omitempty"`
+	Content     string          `json:"content,omitempty"`
+	Rewards     json.RawMessage `json:"rewards,omitempty"`
+	ConfigKey   string          `json:"configKey,omitempty"`
+	ConfigValue string          `json:"configValue,omitempty"`
+	URL         string          `json:"url,omitempty"`
+}
+
+// reached 计数值是否已达到阈值
+func (t *CounterThreshold) reached(value int64) bool {
+	switch t.Operator {
+	case "gte":
+		return value >= t.Value
+	case "lte":
+		return value <= t.Value
+	}
+	return false
+}
+
+// firedCounterThresholds 本实例已触发（或已确认被其他实例触发）的阈值，避免每次计数都查询数据库
+// 值为所在周期的结束时间（秒级时间戳，永久计数器为0），周期结束后由pruneFiredCounterThresholds清理
+var firedCounterThresholds sync.Map
+
+// firedCounterThresholdsPrunedAt 上次清理已触发阈值的时间（秒级时间戳）
+var firedCounterThresholdsPrunedAt int64
+
+// firedCounterThresholdPruneInterval 清理已触发阈值的最小间隔（秒）
+const firedCounterThresholdPruneInterval = 60
+
+// parseCounterThresholds 解析计数器配置中的阈值
+func parseCounterThresholds(thresholds string) ([]CounterThreshold, error) {
+	if strings.TrimSpace(thresholds) == "" {
+		return nil, nil
+	}
+
+	var parsed []CounterThreshold
+	if err := json.Unmarshal([]byte(thresholds), &parsed); err != nil {
+		return nil, fmt.Errorf("阈值配置格式错误: %v", err)
+	}
+	return parsed, nil
+}
+
+// checkCounterThresholds 计数器写入后检查是否达到阈值，达到时异步执行动作（只检查全局计数器）
+func checkCounterThresholds(appId string, config *CounterConfig, location string, value int64) {
+	if len(config.thresholds) == 0 || config.Scope == CounterScopePlayer {
+		return
+	}
+
+	now := time.Now()
+	pruneFiredCounterThresholds(now)
+
+	var period *counterPeriod
+	for i := range config.thresholds {
+		threshold := config.thresholds[i]
+		thresholdLocation := threshold.Location
+		if thresholdLocation == "" {
+			thresholdLocation = "default"
+		}
+		if thresholdLocation != location || !threshold.reached(value) {
+			continue
+		}
+
+		if period == nil {
+			current := getCounterPeriod(config, now)
+			period = &current
+		}
+		var periodEnd int64
+		if period.Id != counterPermanentPeriod {
+			periodEnd = period.End.Unix()
+		}
+		firedKey := strings.Join([]string{appId, config.CounterKey, threshold.Id, period.Id}, "|")
+		if _, fired := firedCounterThresholds.LoadOrStore(firedKey, periodEnd); fired {
+			continue
+		}
+
+		go fireCounterThreshold(appId, config.CounterKey, location, period.Id, value, threshold, firedKey)
+	}
+}
+
+// pruneFiredCounterThresholds 清理周期已结束的已触发阈值（最多每分钟执行一次）
+func pruneFiredCounterThresholds(now time.Time) {
+	last := atomic.LoadInt64(&firedCounterThresholdsPrunedAt)
+	if now.Unix()-last < firedCounterThresholdPruneInterval || !atomic.CompareAndSwapInt64(&firedCounterThresholdsPrunedAt, last, now.Unix()) {
+		return
+	}
+
+	firedCounterThresholds.Range(func(key, value interface{}) bool {
+		if end, _ := value.(int64); end > 0 && end <= now.Unix() {
+			firedCounterThresholds.Delete(key)
+		}
+		return true
+	})
+}
+
+// fireCounterThreshold 执行阈值动作
+// 先写入触发记录抢占（唯一键为计数器+阈值+周期），多实例部署时只有一个实例执行
+func fireCounterThreshold(appId, counterKey, location, period string, value int64, threshold CounterThreshold, firedKey string) {
+	o := orm.NewOrm()
+	tableName := utils.GetCounterThresholdLogTableName(appId)
+
+	result, err := o.Raw(fmt.Sprintf(`
+		INSERT IGNORE INTO %s (counter_key, threshold_id, period, location, value, action_type, status, fired_at)
+		VALUES (?, ?, ?, ?, ?, ?, 'pending', NOW())
+	`, tableName), counterKey, threshold.Id, period, location, value, threshold.Action.Type).Exec()
+	if err != nil {
+		// 写入失败时允许下次计数重新触发
+		firedCounterThresholds.Delete(firedKey)
+		logs.Error("记录计数器阈值触发失败: appId=%s, key=%s, threshold=%s, err=%v", appId, counterKey, threshold.Id, err)
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return // 本周期已触发
+	}
+
+	replacer := strings.NewReplacer("{key}", counterKey, "{location}", location, "{value}", strconv.FormatInt(value, 10))
+	action := threshold.Action
+	switch action.Type {
+	case CounterActionMail:
+		err = sendCounterThresholdMail(appId, action, replacer)
+	case CounterActionConfig:
+		err = setCounterThresholdConfig(appId, action, replacer)
+	case CounterActionWebhook:
+		err = callCounterThresholdWebhook(appId, counterKey, location, period, value, threshold)
+	default:
+		err = fmt.Errorf("未知的动作类型: %s", action.Type)
+	}
+
+	status, errorMsg := "success", ""
+	if err != nil {
+		status, errorMsg = "failed", err.Error()
+		logs.Error("计数器阈值动作执行失败: appId=%s, key=%s, threshold=%s, err=%v", appId, counterKey, threshold.Id, err)
+	} else {
+		logs.Info("计数器阈值已触发: appId=%s, key=%s, threshold=%s, period=%s, value=%d", appId, counterKey, threshold.Id, period, value)
+	}
+
+	_, err = o.Raw(fmt.Sprintf(`UPDATE %s SET status = ?, error_msg = ? WHERE counter_key = ? AND threshold_id = ? AND period = ?`, tableName),
+		status, errorMsg, counterKey, threshold.Id, period).Exec()
+	if err != nil {
+		logs.Error("更新计数器阈值触发记录失败: %v", err)
+	}
+}
+
+// sendCounterThresholdMail 发送全服邮件
+func sendCounterThresholdMail(appId string, action CounterThresholdAction, replacer *strings.Replacer) error {
+	rewards := "[]"
+	if len(action.Rewards) > 0 {
+		rewards = string(action.Rewards)
+	}
+
+	sql := fmt.Sprintf(`
+		INSERT INTO %s (title, content, type, sender, targets, target_type, rewards, status, send_time, expire_time, created_by)
+		VALUES (?, ?, 'activity', 'system', '[]', 'all', ?, 'sent', NOW(), ?, 'system')
+	`, utils.GetMailTableName(appId))
+
+	_, err := orm.NewOrm().Raw(sql, replacer.Replace(action.Title), replacer.Replace(action.Content), rewards,
+		time.Now().AddDate(0, 0, counterThresholdMailExpireDays)).Exec()
+	return err
+}
+
+// setCounterThresholdConfig 修改游戏配置
+func setCounterThresholdConfig(appId string, action CounterThresholdAction, replacer *strings.Replacer) error {
+	config := &GameConfig{}
+	sql := fmt.Sprintf(`
+		INSERT INTO %s (config_key, config_value, created_at, updated_at)
+		VALUES (?, ?, NOW(), NOW())
+		ON DUPLICATE KEY UPDATE
+			config_value = VALUES(config_value),
+			updated_at = NOW()
+	`, config.GetTableName(appId))
+
+	_, err := orm.NewOrm().Raw(sql, action.ConfigKey, replacer.Replace(action.ConfigValue)).Exec()
+	return err
+}
+
+// callCounterThresholdWebhook 调用外部接口
+func callCounterThresholdWebhook(appId, counterKey, location, period string, value int64, threshold CounterThreshold) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"appId":       appId,
+		"counterKey":  counterKey,
+		"location":    location,
+		"period":      period,
+		"thresholdId": threshold.Id,
+		"threshold":   threshold.Value,
+		"value":       value,
+		"firedAt":     time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: counterWebhookTimeout}
+	resp, err := client.Post(threshold.Action.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("回调返回状态码%d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestPruneFiredCounterThresholds 周期结束后清理已触发的阈值，永久计数器的记录保留
+func TestPruneFiredCounterThresholds(t *testing.T) {
+	now := time.Now()
+	atomic.StoreInt64(&firedCounterThresholdsPrunedAt, 0)
+	t.Cleanup(func() {
+		firedCounterThresholds.Range(func(key, _ interface{}) bool {
+			firedCounterThresholds.Delete(key)
+			return true
+		})
+	})
+
+	firedCounterThresholds.Store("app|key|t1|20260101", now.Add(-time.Hour).Unix())
+	firedCounterThresholds.Store("app|key|t1|current", now.Add(time.Hour).Unix())
+	firedCounterThresholds.Store("app|key|t1|all", int64(0))
+
+	pruneFiredCounterThresholds(now)
+
+	tests := []struct {
+		key  string
+		kept bool
+	}{
+		{"app|key|t1|20260101", false},
+		{"app|key|t1|current", true},
+		{"app|key|t1|all", true},
+	}
+	for _, tt := range tests {
+		if _, ok := firedCounterThresholds.Load(tt.key); ok != tt.kept {
+			t.Errorf("%s 保留状态应为%v，实际为%v", tt.key, tt.kept, ok)
+		}
+	}
+
+	// 清理间隔内不重复清理
+	firedCounterThresholds.Store("app|key|t2|20260101", now.Add(-time.Hour).Unix())
+	pruneFiredCounterThresholds(now.Add(time.Second))
+	if _, ok := firedCounterThresholds.Load("app|key|t2|20260101"); !ok {
+		t.Error("清理间隔内不应再次清理")
+	}
+
+	pruneFiredCounterThresholds(now.Add(firedCounterThresholdPruneInterval * time.Second))
+	if _, ok := firedCounterThresholds.Load("app|key|t2|20260101"); ok {
+		t.Error("超过清理间隔后应清理已结束周期的记录")
+	}
+}
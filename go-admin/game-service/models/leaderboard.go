@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -36,6 +38,9 @@ type LeaderboardConfig struct {
 	ResetTime       time.Time `json:"resetTime"`
 	UpdateStrategy  int       `json:"updateStrategy"`
 	Sort            int       `json:"sort"`
+	RewardTiers     string    `json:"rewardTiers"`     // 赛季奖励档位（JSON数组）
+	ValidationRules string    `json:"validationRules"` // 分数校验规则（JSON对象）
+	LeagueConfig    string    `json:"leagueConfig"`    // 联赛分组配置（JSON对象），为空表示普通排行榜
 	CreatedAt       time.Time `json:"createdAt"`
 	UpdatedAt       time.Time `json:"updatedAt"`
 }
@@ -45,7 +50,7 @@ func getLeaderboardConfig(appId, leaderboardName string) (*LeaderboardConfig, er
 	o := orm.NewOrm()
 
 	var result []orm.Params
-	sql := `SELECT id, app_id, leaderboard_type, name, description, score_type, max_rank, enabled, category, reset_type, reset_value, reset_time, update_strategy, sort, created_at, updated_at FROM leaderboard_config WHERE app_id = ? AND leaderboard_type = ?`
+	sql := `SELECT id, app_id, leaderboard_type, name, description, score_type, max_rank, enabled, category, reset_type, reset_value, reset_time, update_strategy, sort, reward_tiers, validation_rules, league_config, created_at, updated_at FROM leaderboard_config WHERE app_id = ? AND leaderboard_type = ?`
 	_, err := o.Raw(sql, appId, leaderboardName).Values(&result)
 	if err != nil {
 		return nil, err
@@ -59,9 +64,7 @@ func getLeaderboardConfig(appId, leaderboardName string) (*LeaderboardConfig, er
 	config := &LeaderboardConfig{}
 
 	// 解析各字段
-	if id, ok := data["id"].(int64); ok {
-		config.Id = id
-	}
+	config.Id = paramToInt64(data["id"])
 	if appId, ok := data["app_id"].(string); ok {
 		config.AppId = appId
 	}
@@ -77,46 +80,79 @@ func getLeaderboardConfig(appId, leaderboardName string) (*LeaderboardConfig, er
 	if scoreType, ok := data["score_type"].(string); ok {
 		config.ScoreType = scoreType
 	}
-	if maxRank, ok := data["max_rank"].(int); ok {
-		config.MaxRank = maxRank
-	} else if maxRank, ok := data["max_rank"].(int64); ok {
-		config.MaxRank = int(maxRank)
-	}
-	if enabled, ok := data["enabled"].(bool); ok {
-		config.Enabled = enabled
-	}
+	config.MaxRank = int(paramToInt64(data["max_rank"]))
+	config.Enabled = paramToBool(data["enabled"])
 	if category, ok := data["category"].(string); ok {
 		config.Category = category
 	}
 	if resetType, ok := data["reset_type"].(string); ok {
 		config.ResetType = resetType
 	}
-	if resetValue, ok := data["reset_value"].(int); ok {
-		config.ResetValue = resetValue
-	} else if resetValue, ok := data["reset_value"].(int64); ok {
-		config.ResetValue = int(resetValue)
+	config.ResetValue = int(paramToInt64(data["reset_value"]))
+	config.ResetTime = paramToTime(data["reset_time"])
+	config.UpdateStrategy = int(paramToInt64(data["update_strategy"]))
+	config.Sort = int(paramToInt64(data["sort"]))
+	if rewardTiers, ok := data["reward_tiers"].(string); ok {
+		config.RewardTiers = rewardTiers
 	}
-	if resetTime, ok := data["reset_time"].(time.Time); ok {
-		config.ResetTime = resetTime
+	if validationRules, ok := data["validation_rules"].(string); ok {
+		config.ValidationRules = validationRules
 	}
-	if updateStrategy, ok := data["update_strategy"].(int); ok {
-		config.UpdateStrategy = updateStrategy
-	} else if updateStrategy, ok := data["update_strategy"].(int64); ok {
-		config.UpdateStrategy = int(updateStrategy)
+	if leagueConfig, ok := data["league_config"].(string); ok {
+		config.LeagueConfig = leagueConfig
 	}
-	if sort, ok := data["sort"].(int); ok {
-		config.Sort = sort
-	} else if sort, ok := data["sort"].(int64); ok {
-		config.Sort = int(sort)
-	}
-	if createdAt, ok := data["created_at"].(time.Time); ok {
-		config.CreatedAt = createdAt
+	config.CreatedAt = paramToTime(data["created_at"])
+	config.UpdatedAt = paramToTime(data["updated_at"])
+
+	return config, nil
+}
+
+// paramToInt64 将原生SQL查询结果字段转换为int64（Values查询返回的均为字符串）
+func paramToInt64(val interface{}) int64 {
+	switch v := val.(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case string:
+		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return parsed
+		}
+	case []byte:
+		if parsed, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return parsed
+		}
 	}
-	if updatedAt, ok := data["updated_at"].(time.Time); ok {
-		config.UpdatedAt = updatedAt
+	return 0
+}
+
+// paramToBool 将原生SQL查询结果字段转换为bool
+func paramToBool(val interface{}) bool {
+	switch v := val.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "1" || strings.EqualFold(v, "true")
 	}
+	return paramToInt64(val) != 0
+}
 
-	return config, nil
+// paramToTime 将原生SQL查询结果字段转换为time.Time
+func paramToTime(val interface{}) time.Time {
+	switch v := val.(type) {
+	case time.Time:
+		return v
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t
+		}
+		if t, err := time.ParseInLocation("2006-01-02 15:04:05", v, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
 }
 
 // getLeaderboardConfigId 根据appId和leaderboardName获取type
@@ -128,9 +164,83 @@ func getLeaderboardConfigId(appId, leaderboardName string) (int64, error) {
 	return config.Id, nil
 }
 
-// getLeaderboardRedisKey 获取排行榜Redis键名
-func getLeaderboardRedisKey(appId, leaderboardName string) string {
-	return fmt.Sprintf("leaderboard:%s:%s", appId, leaderboardName)
+// getLeaderboardRedisKey 获取排行榜Redis键名（partition为空时使用默认分区）
+func getLeaderboardRedisKey(appId, leaderboardName, partition string) string {
+	if partition == "" {
+		return fmt.Sprintf("leaderboard:%s:%s", appId, leaderboardName)
+	}
+	return fmt.Sprintf("leaderboard:%s:%s:p:%s", appId, leaderboardName, partition)
+}
+
+// leaderboardTiebreakEpoch 同分排名的时间基准（Redis复合分数的小数部分从该时间开始计秒）
+var leaderboardTiebreakEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	// tiebreakScale 复合分数中时间部分的缩放系数（秒级精度，可覆盖约31年）
+	tiebreakScale = 1e9
+	// maxTiebreakScore 分数绝对值达到该值后float64无法再保留时间精度，不再附加时间部分
+	maxTiebreakScore = 1 << 21
+)
+
+// isScoreAscending 排行榜是否为升序（分数越低排名越靠前，如竞速、解谜用时）
+func isScoreAscending(config *LeaderboardConfig) bool {
+	return config.ScoreType == "lower_better"
+}
+
+// isBetterScore 判断新分数是否优于旧分数
+func isBetterScore(newScore, oldScore int64, ascending bool) bool {
+	if ascending {
+		return newScore < oldScore
+	}
+	return newScore > oldScore
+}
+
+// getScoreOrder 获取数据库排序方向
+func getScoreOrder(ascending bool) string {
+	if ascending {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+// encodeRedisScore 生成Redis有序集合的复合分数
+// 整数部分为真实分数，小数部分为达成该分数的时间，保证同分时先达成的玩家排名靠前
+func encodeRedisScore(score int64, reachedAt time.Time, ascending bool) float64 {
+	if score >= maxTiebreakScore || score <= -maxTiebreakScore {
+		return float64(score)
+	}
+
+	elapsed := int64(reachedAt.Sub(leaderboardTiebreakEpoch) / time.Second)
+	if elapsed < 0 {
+		elapsed = 0
+	} else if elapsed > tiebreakScale-1 {
+		elapsed = tiebreakScale - 1
+	}
+
+	if ascending {
+		// 升序：越早达成小数部分越小
+		return float64(score) + float64(elapsed)/tiebreakScale
+	}
+	// 降序：越早达成小数部分越大
+	return float64(score) + float64(tiebreakScale-1-elapsed)/tiebreakScale
+}
+
+// decodeRedisScore 从复合分数还原真实分数
+func decodeRedisScore(value float64) int64 {
+	return int64(math.Floor(value))
+}
+
+// sortLeaderboards 按分数和达成时间排序并重新计算名次
+func sortLeaderboards(leaderboards []Leaderboard, ascending bool) {
+	sort.SliceStable(leaderboards, func(i, j int) bool {
+		if leaderboards[i].Score != leaderboards[j].Score {
+			return isBetterScore(leaderboards[i].Score, leaderboards[j].Score, ascending)
+		}
+		return leaderboards[i].UpdatedAt < leaderboards[j].UpdatedAt
+	})
+	for i := range leaderboards {
+		leaderboards[i].Rank = i + 1
+	}
 }
 
 // LeaderboardRedisEntry Redis排行榜条目结构
@@ -149,9 +259,13 @@ type Leaderboard struct {
 	UserId    string                 `orm:"size(100);column(player_id)" json:"user_id"`
 	Score     int64                  `orm:"default(0)" json:"score"`
 	ExtraData string                 `orm:"type(text);column(extra_data)" json:"extra_data"`
+	Rank      int                    `orm:"-" json:"rank,omitempty"`     // 排名，查询时动态计算
 	UserInfo  map[string]interface{} `orm:"-" json:"userInfo,omitempty"` // 用户信息，不存储到数据库
 	CreatedAt string                 `orm:"auto_now_add;type(datetime);column(created_at)" json:"createdAt"`
 	UpdatedAt string                 `orm:"auto_now;type(datetime);column(updated_at)" json:"updatedAt"`
+
+	redisScore float64   // Redis中的复合分数（仅从Redis读取时有值，用于分页游标）
+	reachedAt  time.Time // 达成当前分数的时间（用于分页游标）
 }
 
 // GetTableName 获取动态表名
@@ -196,53 +310,24 @@ func calculateNextResetTime(resetType string, resetValue int) *time.Time {
 	return &nextReset
 }
 
-// checkAndResetLeaderboard 检查并重置排行榜
-func checkAndResetLeaderboard(appId, leaderboardName string, config *LeaderboardConfig) error {
+// checkAndResetLeaderboard 检查排行榜是否已到重置时间，到期时通知后台任务结算赛季
+// 归档、奖励发放和清空数据由后台任务完成（见leaderboard_settle.go），玩家请求不等待结算；返回true表示本赛季正在结算
+func checkAndResetLeaderboard(appId, leaderboardName string, config *LeaderboardConfig) bool {
 	if config.ResetType == "permanent" || config.ResetTime.IsZero() {
-		return nil
+		return false
 	}
 
-	now := time.Now()
-	if now.After(config.ResetTime) {
-		logs.Info("排行榜需要重置: appId=%s, type=%s, resetTime=%v", appId, leaderboardName, config.ResetTime)
-
-		// 清空排行榜数据
-		err := ResetLeaderboard(appId, leaderboardName)
-		if err != nil {
-			return fmt.Errorf("重置排行榜失败: %v", err)
-		}
-
-		// 计算下次重置时间
-		nextResetTime := calculateNextResetTime(config.ResetType, config.ResetValue)
-		if nextResetTime != nil {
-			// 更新配置中的重置时间
-			err = updateLeaderboardResetTime(appId, leaderboardName, *nextResetTime)
-			if err != nil {
-				return fmt.Errorf("更新重置时间失败: %v", err)
-			}
-			logs.Info("排行榜重置完成，下次重置时间: %v", *nextResetTime)
-		}
+	if !time.Now().After(config.ResetTime) {
+		return false
 	}
 
-	return nil
-}
-
-// updateLeaderboardResetTime 更新排行榜重置时间
-func updateLeaderboardResetTime(appId, leaderboardName string, resetTime time.Time) error {
-	o := orm.NewOrm()
-
-	sql := `UPDATE leaderboard_config SET reset_time = ?, updated_at = NOW() WHERE app_id = ? AND leaderboard_type = ?`
-	_, err := o.Raw(sql, resetTime, appId, leaderboardName).Exec()
-	if err != nil {
-		logs.Error("更新排行榜重置时间失败: %v", err)
-		return err
-	}
-
-	return nil
+	requestLeaderboardSettle(appId, leaderboardName)
+	return true
 }
 
 // SubmitScore 提交分数到排行榜（支持Redis缓存，包含完整的JS逻辑）
-func SubmitScore(appId, userId, leaderboardName string, score int64, extraData string) error {
+// partition 为分区键（如区服、地区），同一排行榜的不同分区独立排名，为空表示默认分区
+func SubmitScore(appId, userId, leaderboardName, partition string, score int64, extraData string) error {
 	o := orm.NewOrm()
 	tableName := getLeaderboardTableName(appId)
 
@@ -266,18 +351,40 @@ func SubmitScore(appId, userId, leaderboardName string, score int64, extraData s
 		return fmt.Errorf("更新策略异常")
 	}
 
-	// 4. 检查是否需要重置排行榜
-	err = checkAndResetLeaderboard(appId, leaderboardName, config)
+	// 4. 检查是否需要重置排行榜，赛季结算完成前拒绝提交，避免新分数混入上赛季
+	if checkAndResetLeaderboard(appId, leaderboardName, config) {
+		return ErrLeaderboardSettling
+	}
+
+	// 联赛排行榜：分区由玩家本赛季所在的段位分组决定，忽略客户端传入的分区
+	// 此处只读取已分配的分组，尚未分组的玩家在分数通过校验后再分配，避免被拒绝的提交占用分组名额
+	league, err := parseLeagueConfig(config.LeagueConfig)
 	if err != nil {
 		return err
 	}
+	leagueAssigned := false
+	if league != nil {
+		tier, bucket, err := getLeagueMember(o, appId, userId, leaderboardName)
+		if err != nil {
+			return fmt.Errorf("查询联赛分组失败: %v", err)
+		}
+		leagueAssigned = bucket > 0
+		partition = ""
+		if leagueAssigned {
+			partition = getLeaguePartition(tier, bucket)
+		}
+	}
+
+	ascending := isScoreAscending(config)
 
-	// 5. 查找现有记录
+	// 5. 查找现有记录（未分组的联赛玩家本赛季没有记录）
 	var existingRecord []orm.Params
-	findSQL := fmt.Sprintf(`SELECT id, score FROM %s WHERE type = ? AND player_id = ?`, tableName)
-	_, err = o.Raw(findSQL, leaderboardName, userId).Values(&existingRecord)
-	if err != nil {
-		return fmt.Errorf("查询现有记录失败: %v", err)
+	if league == nil || leagueAssigned {
+		findSQL := fmt.Sprintf(`SELECT id, score FROM %s WHERE type = ? AND partition_key = ? AND player_id = ?`, tableName)
+		_, err = o.Raw(findSQL, leaderboardName, partition, userId).Values(&existingRecord)
+		if err != nil {
+			return fmt.Errorf("查询现有记录失败: %v", err)
+		}
 	}
 
 	// 6. 使用安全的插入/更新逻辑，避免重复记录
@@ -314,8 +421,8 @@ func SubmitScore(appId, userId, leaderboardName string, score int64, extraData s
 
 		// 根据更新策略决定是否更新
 		switch config.UpdateStrategy {
-		case 0: // 历史最高值
-			shouldUpdate = oldScore <= score
+		case 0: // 历史最佳值（降序取最高，升序取最低）
+			shouldUpdate = isBetterScore(score, oldScore, ascending)
 		case 1: // 最近记录
 			shouldUpdate = true
 		case 2: // 历史总和
@@ -323,44 +430,64 @@ func SubmitScore(appId, userId, leaderboardName string, score int64, extraData s
 		}
 	}
 
+	// 校验分数规则，违规提交记录到可疑分数表并拒绝
+	err = validateScoreSubmission(appId, userId, leaderboardName, config, score, oldScore, len(existingRecord) > 0, extraData)
+	if err != nil {
+		return err
+	}
+
+	// 校验通过后为尚未分组的联赛玩家分配分组
+	if league != nil && !leagueAssigned {
+		partition, err = assignLeagueBucket(appId, userId, leaderboardName, config, league)
+		if err != nil {
+			return fmt.Errorf("分配联赛分组失败: %v", err)
+		}
+	}
+
 	// 使用 INSERT ... ON DUPLICATE KEY UPDATE 确保不会产生重复记录
+	// updated_at 记录达成当前分数的时间，用于同分排名（先达成者靠前），分数不变时保持原值
+	// 注意：MySQL按顺序执行赋值，score必须放在最后，否则前面的条件会读到新分数
 	if shouldUpdate {
 		var upsertSQL string
 		var args []interface{}
 
 		switch config.UpdateStrategy {
-		case 0: // 历史最高值 - 只有新分数更高时才更新
+		case 0: // 历史最佳值 - 只有新分数更优时才更新
+			betterOp := ">"
+			if ascending {
+				betterOp = "<"
+			}
 			upsertSQL = fmt.Sprintf(`
-				INSERT INTO %s (type, player_id, score, extra_data, created_at, updated_at)
-				VALUES (?, ?, ?, ?, NOW(), NOW())
+				INSERT INTO %s (type, partition_key, player_id, score, extra_data, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, NOW(), NOW())
 				ON DUPLICATE KEY UPDATE
-					score = CASE WHEN VALUES(score) > score THEN VALUES(score) ELSE score END,
-					extra_data = CASE WHEN VALUES(score) > score THEN VALUES(extra_data) ELSE extra_data END,
-					updated_at = NOW()
-			`, tableName)
-			args = []interface{}{leaderboardName, userId, score, extraData}
+					extra_data = CASE WHEN VALUES(score) %s score THEN VALUES(extra_data) ELSE extra_data END,
+					updated_at = CASE WHEN VALUES(score) %s score THEN NOW() ELSE updated_at END,
+					score = CASE WHEN VALUES(score) %s score THEN VALUES(score) ELSE score END
+			`, tableName, betterOp, betterOp, betterOp)
+			args = []interface{}{leaderboardName, partition, userId, score, extraData}
 
 		case 1: // 最近记录 - 总是更新
 			upsertSQL = fmt.Sprintf(`
-				INSERT INTO %s (type, player_id, score, extra_data, created_at, updated_at)
-				VALUES (?, ?, ?, ?, NOW(), NOW())
+				INSERT INTO %s (type, partition_key, player_id, score, extra_data, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, NOW(), NOW())
 				ON DUPLICATE KEY UPDATE
-					score = VALUES(score),
 					extra_data = VALUES(extra_data),
-					updated_at = NOW()
+					updated_at = CASE WHEN VALUES(score) <> score THEN NOW() ELSE updated_at END,
+					score = VALUES(score)
 			`, tableName)
-			args = []interface{}{leaderboardName, userId, score, extraData}
+			args = []interface{}{leaderboardName, partition, userId, score, extraData}
 
 		case 2: // 历史总和 - 累加分数
 			upsertSQL = fmt.Sprintf(`
-				INSERT INTO %s (type, player_id, score, extra_data, created_at, updated_at)
-				VALUES (?, ?, ?, ?, NOW(), NOW())
+				INSERT INTO %s (type, partition_key, player_id, score, extra_data, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, NOW(), NOW())
 				ON DUPLICATE KEY UPDATE
-					score = score + VALUES(score),
 					extra_data = VALUES(extra_data),
-					updated_at = NOW()
+					updated_at = NOW(),
+					score = score + VALUES(score)
 			`, tableName)
-			args = []interface{}{leaderboardName, userId, score, extraData}
+			args = []interface{}{leaderboardName, partition, userId, score, extraData}
 		}
 
 		_, err = o.Raw(upsertSQL, args...).Exec()
@@ -369,9 +496,9 @@ func SubmitScore(appId, userId, leaderboardName string, score int64, extraData s
 		}
 	}
 
-	// 7. 同步到Redis（如果Redis可用）
+	// 7. 同步到Redis（如果Redis可用），以数据库中生效的分数为准
 	if RedisClient != nil {
-		err = syncScoreToRedis(appId, userId, leaderboardName, score, extraData)
+		err = syncPlayerScoreToRedis(appId, userId, leaderboardName, partition, config)
 		if err != nil {
 			// Redis错误不影响主流程，记录日志即可
 			logs.Warn("Redis同步失败: %v", err)
@@ -381,55 +508,65 @@ func SubmitScore(appId, userId, leaderboardName string, score int64, extraData s
 	return nil
 }
 
-// syncScoreToRedis 同步分数到Redis
-func syncScoreToRedis(appId, userId, leaderboardName string, score int64, extraData string) error {
-	ctx := RedisClient.Context()
-
-	// 排行榜有序集合的key（用于存储分数排名）
-	scoreKey := getLeaderboardRedisKey(appId, leaderboardName)
+// syncPlayerScoreToRedis 读取玩家在数据库中的生效分数并同步到Redis
+func syncPlayerScoreToRedis(appId, userId, leaderboardName, partition string, config *LeaderboardConfig) error {
+	o := orm.NewOrm()
 
-	// 获取排行榜配置以确定过期策略
-	config, err := getLeaderboardConfig(appId, leaderboardName)
+	var rows []orm.Params
+	sql := fmt.Sprintf(`SELECT score, extra_data, updated_at FROM %s WHERE type = ? AND partition_key = ? AND player_id = ?`, getLeaderboardTableName(appId))
+	_, err := o.Raw(sql, leaderboardName, partition, userId).Values(&rows)
 	if err != nil {
-		return fmt.Errorf("获取排行榜配置失败: %v", err)
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
 	}
 
-	// 1. 更新有序集合中的分数（使用用户ID作为member，分数作为score）
+	extraData, _ := rows[0]["extra_data"].(string)
+	return syncScoreToRedis(appId, userId, leaderboardName, partition, config, paramToInt64(rows[0]["score"]), extraData, paramToTime(rows[0]["updated_at"]))
+}
+
+// syncScoreToRedis 同步分数到Redis（reachedAt为达成该分数的时间，用于同分排名）
+func syncScoreToRedis(appId, userId, leaderboardName, partition string, config *LeaderboardConfig, score int64, extraData string, reachedAt time.Time) error {
+	ctx := RedisClient.Context()
+
+	// 排行榜有序集合的key（用于存储分数排名）
+	scoreKey := getLeaderboardRedisKey(appId, leaderboardName, partition)
+
+	// 1. 更新有序集合中的分数（使用用户ID作为member，复合分数作为score）
 	member := &redis.Z{
-		Score:  float64(score),
+		Score:  encodeRedisScore(score, reachedAt, isScoreAscending(config)),
 		Member: userId,
 	}
 
-	err = RedisClient.ZAdd(ctx, scoreKey, member).Err()
+	err := RedisClient.ZAdd(ctx, scoreKey, member).Err()
 	if err != nil {
 		return err
 	}
 
-	// 2. 只有当extraData不为空时才存储详细信息
-	if extraData != "" {
-		userDetails := map[string]interface{}{
-			"extra_data":  extraData,
-			"update_time": time.Now().Unix(),
-		}
-
-		// 序列化用户详情为JSON
-		detailsJSON, err := json.Marshal(userDetails)
-		if err != nil {
-			return err
-		}
+	// 2. 存储详细信息（额外数据和达成时间）
+	userDetails := map[string]interface{}{
+		"extra_data":  extraData,
+		"update_time": reachedAt.Unix(),
+	}
 
-		// 用户详情哈希表的key（用于存储额外数据）
-		detailKey := getLeaderboardRedisKey(appId, leaderboardName) + ":details"
+	// 序列化用户详情为JSON
+	detailsJSON, err := json.Marshal(userDetails)
+	if err != nil {
+		return err
+	}
 
-		err = RedisClient.HSet(ctx, detailKey, userId, string(detailsJSON)).Err()
-		if err != nil {
-			return err
-		}
+	// 用户详情哈希表的key（用于存储额外数据）
+	detailKey := getLeaderboardRedisKey(appId, leaderboardName, partition) + ":details"
 
-		// 根据排行榜类型设置详情数据过期时间
-		setRedisExpiry(ctx, detailKey, config)
+	err = RedisClient.HSet(ctx, detailKey, userId, string(detailsJSON)).Err()
+	if err != nil {
+		return err
 	}
 
+	// 根据排行榜类型设置详情数据过期时间
+	setRedisExpiry(ctx, detailKey, config)
+
 	// 3. 根据排行榜类型设置分数数据过期时间
 	setRedisExpiry(ctx, scoreKey, config)
 
@@ -527,8 +664,8 @@ func UpdateScore(appId, userId, leaderboardName string, score int64, extraData s
 	return updateLeaderboardRanks(o, tableName, leaderboardName)
 }
 
-// GetLeaderboard 获取排行榜（优先从Redis读取，包含重置检查）
-func GetLeaderboard(appId, leaderboardName string, limit int) ([]Leaderboard, error) {
+// GetLeaderboard 获取排行榜指定分区的前limit名（优先从Redis读取，包含重置检查）
+func GetLeaderboard(appId, leaderboardName, partition string, limit int) ([]Leaderboard, error) {
 	// 1. 获取排行榜配置并检查重置
 	config, err := getLeaderboardConfig(appId, leaderboardName)
 	if err != nil {
@@ -536,43 +673,57 @@ func GetLeaderboard(appId, leaderboardName string, limit int) ([]Leaderboard, er
 	}
 
 	// 2. 检查是否需要重置排行榜
-	err = checkAndResetLeaderboard(appId, leaderboardName, config)
-	if err != nil {
-		return nil, err
-	}
+	checkAndResetLeaderboard(appId, leaderboardName, config)
 
 	// 3. 尝试从Redis获取
 	if RedisClient != nil {
-		leaderboards, err := getLeaderboardFromRedis(appId, leaderboardName, limit)
+		leaderboards, err := getLeaderboardFromRedis(appId, leaderboardName, partition, limit, isScoreAscending(config))
 		if err == nil && len(leaderboards) > 0 {
+			fillLeaderboardUserInfo(appId, leaderboards)
 			return leaderboards, nil
 		}
 		// Redis失败或没有数据，继续从数据库读取
 	}
 
 	// 4. 从数据库获取
-	return getLeaderboardFromDBWithConfig(appId, leaderboardName, limit, config)
+	return getLeaderboardFromDBWithConfig(appId, leaderboardName, partition, limit, config)
 }
 
 // getLeaderboardFromRedis 从Redis获取排行榜
-func getLeaderboardFromRedis(appId, leaderboardName string, limit int) ([]Leaderboard, error) {
+func getLeaderboardFromRedis(appId, leaderboardName, partition string, limit int, ascending bool) ([]Leaderboard, error) {
+	return getLeaderboardRangeFromRedis(appId, leaderboardName, partition, 0, int64(limit-1), ascending)
+}
+
+// getLeaderboardRangeFromRedis 从Redis获取指定排名区间的排行榜数据（start/stop从0开始，包含两端）
+func getLeaderboardRangeFromRedis(appId, leaderboardName, partition string, start, stop int64, ascending bool) ([]Leaderboard, error) {
 	ctx := RedisClient.Context()
 
 	// 排行榜有序集合的key
-	scoreKey := getLeaderboardRedisKey(appId, leaderboardName)
+	scoreKey := getLeaderboardRedisKey(appId, leaderboardName, partition)
 	// 用户详情哈希表的key
-	detailKey := getLeaderboardRedisKey(appId, leaderboardName) + ":details"
+	detailKey := getLeaderboardRedisKey(appId, leaderboardName, partition) + ":details"
 
-	// 获取Redis有序集合的前N个成员（按分数降序）
-	results, err := RedisClient.ZRevRangeWithScores(ctx, scoreKey, 0, int64(limit-1)).Result()
+	// 获取Redis有序集合指定区间的成员（按复合分数排序）
+	var results []redis.Z
+	var err error
+	if ascending {
+		results, err = RedisClient.ZRangeWithScores(ctx, scoreKey, start, stop).Result()
+	} else {
+		results, err = RedisClient.ZRevRangeWithScores(ctx, scoreKey, start, stop).Result()
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	return redisMembersToLeaderboards(ctx, leaderboardName, detailKey, results, int(start)), nil
+}
+
+// redisMembersToLeaderboards 将Redis有序集合成员转换为Leaderboard结构（offset为第一个成员之前的名次）
+func redisMembersToLeaderboards(ctx context.Context, leaderboardName, detailKey string, results []redis.Z, offset int) []Leaderboard {
 	var leaderboards []Leaderboard
-	for _, result := range results {
+	for i, result := range results {
 		userId := result.Member.(string)
-		score := int64(result.Score)
+		score := decodeRedisScore(result.Score)
 
 		var extraData string
 		var updateTime int64 = time.Now().Unix()
@@ -593,31 +744,32 @@ func getLeaderboardFromRedis(appId, leaderboardName string, limit int) ([]Leader
 		}
 
 		lb := Leaderboard{
-			Type:      leaderboardName,
-			UserId:    userId,
-			Score:     score,
-			ExtraData: extraData,
-			UpdatedAt: time.Unix(updateTime, 0).Format("2006-01-02 15:04:05"),
+			Type:       leaderboardName,
+			UserId:     userId,
+			Score:      score,
+			ExtraData:  extraData,
+			Rank:       offset + i + 1,
+			UpdatedAt:  time.Unix(updateTime, 0).Format("2006-01-02 15:04:05"),
+			redisScore: result.Score,
+			reachedAt:  time.Unix(updateTime, 0),
 		}
 
 		leaderboards = append(leaderboards, lb)
 	}
 
-	return leaderboards, nil
+	return leaderboards
 }
 
 // getLeaderboardFromDBWithConfig 从数据库获取排行榜（带配置）
-func getLeaderboardFromDBWithConfig(appId, leaderboardName string, limit int, config *LeaderboardConfig) ([]Leaderboard, error) {
+func getLeaderboardFromDBWithConfig(appId, leaderboardName, partition string, limit int, config *LeaderboardConfig) ([]Leaderboard, error) {
+	return getLeaderboardRangeFromDB(appId, leaderboardName, partition, 0, limit, isScoreAscending(config))
+}
+
+// getLeaderboardRangeFromDB 从数据库获取指定偏移量的排行榜数据（同分时先达成者靠前）
+func getLeaderboardRangeFromDB(appId, leaderboardName, partition string, offset, limit int, ascending bool) ([]Leaderboard, error) {
 	o := orm.NewOrm()
 	tableName := getLeaderboardTableName(appId)
 
-	// 确定排序方式
-	sortOrder := "DESC"
-	if config.Sort == 0 {
-		sortOrder = "ASC"
-	}
-
-	// 查询排行榜数据
 	sql := fmt.Sprintf(`
 		SELECT 
 			id,
@@ -628,17 +780,22 @@ func getLeaderboardFromDBWithConfig(appId, leaderboardName string, limit int, co
 			created_at as create_time,
 			updated_at as update_time
 		FROM %s
-		WHERE type = ?
-		ORDER BY score %s, created_at ASC
-		LIMIT ?
-	`, tableName, sortOrder)
+		WHERE type = ? AND partition_key = ?
+		ORDER BY score %s, updated_at ASC, id ASC
+		LIMIT ? OFFSET ?
+	`, tableName, getScoreOrder(ascending))
 
 	var results []orm.Params
-	_, err := o.Raw(sql, leaderboardName, limit).Values(&results)
+	_, err := o.Raw(sql, leaderboardName, partition, limit, offset).Values(&results)
 	if err != nil {
 		return nil, err
 	}
 
+	return convertLeaderboardRows(appId, results, offset), nil
+}
+
+// convertLeaderboardRows 将数据库查询结果转换为Leaderboard结构（附带用户信息和排名）
+func convertLeaderboardRows(appId string, results []orm.Params, offset int) []Leaderboard {
 	// 获取用户信息映射
 	userInfoMap, err := getUserInfoMapForLeaderboard(appId, results)
 	if err != nil {
@@ -648,8 +805,8 @@ func getLeaderboardFromDBWithConfig(appId, leaderboardName string, limit int, co
 
 	// 转换为Leaderboard结构
 	var leaderboards []Leaderboard
-	for _, result := range results {
-		lb := Leaderboard{}
+	for i, result := range results {
+		lb := Leaderboard{Rank: offset + i + 1}
 
 		// 安全的ID类型转换
 		if idVal, ok := result["id"]; ok {
@@ -696,17 +853,18 @@ func getLeaderboardFromDBWithConfig(appId, leaderboardName string, limit int, co
 		if extraData, ok := result["extra_data"].(string); ok {
 			lb.ExtraData = extraData
 		}
-		if createTime, ok := result["create_time"].(time.Time); ok {
+		if createTime := paramToTime(result["create_time"]); !createTime.IsZero() {
 			lb.CreatedAt = createTime.Format("2006-01-02 15:04:05")
 		}
-		if updateTime, ok := result["update_time"].(time.Time); ok {
+		if updateTime := paramToTime(result["update_time"]); !updateTime.IsZero() {
 			lb.UpdatedAt = updateTime.Format("2006-01-02 15:04:05")
+			lb.reachedAt = updateTime
 		}
 
 		leaderboards = append(leaderboards, lb)
 	}
 
-	return leaderboards, nil
+	return leaderboards
 }
 
 // getUserInfoMapForLeaderboard 获取排行榜用户信息映射
@@ -781,11 +939,39 @@ func getUserInfoMapForLeaderboard(appId string, leaderboardResults []orm.Params)
 	return userInfoMap, nil
 }
 
-// GetUserRank 获取用户在排行榜中的排名（优先从Redis读取）
-func GetUserRank(appId, userId, leaderboardName string) (int, int64, error) {
+// fillLeaderboardUserInfo 为排行榜条目填充用户昵称、头像信息
+func fillLeaderboardUserInfo(appId string, leaderboards []Leaderboard) {
+	rows := make([]orm.Params, 0, len(leaderboards))
+	for _, lb := range leaderboards {
+		rows = append(rows, orm.Params{"user_id": lb.UserId})
+	}
+
+	userInfoMap, err := getUserInfoMapForLeaderboard(appId, rows)
+	if err != nil {
+		logs.Warn("获取用户信息失败: %v", err)
+		userInfoMap = make(map[string]map[string]interface{})
+	}
+
+	for i := range leaderboards {
+		if userInfo, exists := userInfoMap[leaderboards[i].UserId]; exists {
+			leaderboards[i].UserInfo = userInfo
+		} else {
+			leaderboards[i].UserInfo = map[string]interface{}{}
+		}
+	}
+}
+
+// GetUserRank 获取用户在排行榜指定分区中的排名（优先从Redis读取）
+func GetUserRank(appId, userId, leaderboardName, partition string) (int, int64, error) {
+	config, err := getLeaderboardConfig(appId, leaderboardName)
+	if err != nil {
+		return 0, 0, fmt.Errorf("获取排行榜配置失败: %v", err)
+	}
+	ascending := isScoreAscending(config)
+
 	// 尝试从Redis获取
 	if RedisClient != nil {
-		rank, score, err := getUserRankFromRedis(appId, userId, leaderboardName)
+		rank, score, err := getUserRankFromRedis(appId, userId, leaderboardName, partition, ascending)
 		if err == nil && rank > 0 {
 			return rank, score, nil
 		}
@@ -793,15 +979,228 @@ func GetUserRank(appId, userId, leaderboardName string) (int, int64, error) {
 	}
 
 	// 从数据库获取
-	return getUserRankFromDB(appId, userId, leaderboardName)
+	return getUserRankFromDB(appId, userId, leaderboardName, partition, ascending)
+}
+
+// LeaderboardAroundResult 玩家周边排名查询结果
+type LeaderboardAroundResult struct {
+	Rank  int           `json:"rank"`  // 玩家自身排名（0表示未上榜）
+	Score int64         `json:"score"` // 玩家自身分数
+	List  []Leaderboard `json:"list"`  // 玩家及其前后若干名的排行数据
+}
+
+// GetLeaderboardAroundUser 获取玩家在指定分区的自身排名以及前后各count名玩家（优先从Redis读取）
+func GetLeaderboardAroundUser(appId, userId, leaderboardName, partition string, count int) (*LeaderboardAroundResult, error) {
+	// 1. 获取排行榜配置并检查重置
+	config, err := getLeaderboardConfig(appId, leaderboardName)
+	if err != nil {
+		return nil, fmt.Errorf("获取排行榜配置失败: %v", err)
+	}
+
+	checkAndResetLeaderboard(appId, leaderboardName, config)
+
+	result := &LeaderboardAroundResult{List: []Leaderboard{}}
+	ascending := isScoreAscending(config)
+
+	// 2. 尝试从Redis获取
+	if RedisClient != nil {
+		rank, score, err := getUserRankFromRedis(appId, userId, leaderboardName, partition, ascending)
+		if err == nil && rank > 0 {
+			start, stop := getAroundRange(rank, count)
+			list, err := getLeaderboardRangeFromRedis(appId, leaderboardName, partition, int64(start), int64(stop), ascending)
+			if err == nil && len(list) > 0 {
+				fillLeaderboardUserInfo(appId, list)
+				result.Rank = rank
+				result.Score = score
+				result.List = list
+				return result, nil
+			}
+		}
+		// Redis失败或没有数据，继续从数据库读取
+	}
+
+	// 3. 从数据库获取
+	rank, score, err := getUserRankFromDB(appId, userId, leaderboardName, partition, ascending)
+	if err != nil {
+		return nil, err
+	}
+	if rank == 0 {
+		return result, nil // 用户不在排行榜中
+	}
+
+	start, stop := getAroundRange(rank, count)
+	list, err := getLeaderboardRangeFromDB(appId, leaderboardName, partition, start, stop-start+1, ascending)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Rank = rank
+	result.Score = score
+	if len(list) > 0 {
+		result.List = list
+	}
+	return result, nil
+}
+
+// GetLeaderboardByPlayers 获取指定玩家列表在排行榜指定分区中的分数并排序（好友排行榜，优先从Redis读取）
+func GetLeaderboardByPlayers(appId, leaderboardName, partition string, playerIds []string) ([]Leaderboard, error) {
+	// 1. 获取排行榜配置并检查重置
+	config, err := getLeaderboardConfig(appId, leaderboardName)
+	if err != nil {
+		return nil, fmt.Errorf("获取排行榜配置失败: %v", err)
+	}
+
+	checkAndResetLeaderboard(appId, leaderboardName, config)
+
+	if len(playerIds) == 0 {
+		return []Leaderboard{}, nil
+	}
+
+	// 2. 尝试从Redis获取
+	var leaderboards []Leaderboard
+	fromRedis := false
+	if RedisClient != nil {
+		leaderboards, err = getPlayersScoreFromRedis(appId, leaderboardName, partition, playerIds)
+		if err == nil {
+			fromRedis = true
+		} else if err != redis.Nil {
+			logs.Warn("从Redis获取好友排行失败: %v", err)
+		}
+		// Redis失败或排行榜缓存不存在，继续从数据库读取
+	}
+
+	// 3. 从数据库获取
+	if !fromRedis {
+		leaderboards, err = getPlayersScoreFromDB(appId, leaderboardName, partition, playerIds)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 4. 按分数和达成时间排序并计算名次
+	sortLeaderboards(leaderboards, isScoreAscending(config))
+
+	fillLeaderboardUserInfo(appId, leaderboards)
+	return leaderboards, nil
+}
+
+// getPlayersScoreFromRedis 通过一次pipeline批量获取玩家分数和额外数据
+// 排行榜缓存不存在时返回redis.Nil，由调用方回退到数据库
+func getPlayersScoreFromRedis(appId, leaderboardName, partition string, playerIds []string) ([]Leaderboard, error) {
+	ctx := RedisClient.Context()
+
+	scoreKey := getLeaderboardRedisKey(appId, leaderboardName, partition)
+	detailKey := getLeaderboardRedisKey(appId, leaderboardName, partition) + ":details"
+
+	pipe := RedisClient.Pipeline()
+	existsCmd := pipe.Exists(ctx, scoreKey)
+	scoreCmds := make([]*redis.FloatCmd, len(playerIds))
+	for i, playerId := range playerIds {
+		scoreCmds[i] = pipe.ZScore(ctx, scoreKey, playerId)
+	}
+	detailCmd := pipe.HMGet(ctx, detailKey, playerIds...)
+
+	// 部分成员不存在时Exec会返回redis.Nil，属于正常情况
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	if existsCmd.Val() == 0 {
+		return nil, redis.Nil
+	}
+
+	details := detailCmd.Val()
+	leaderboards := make([]Leaderboard, 0, len(playerIds))
+	for i, playerId := range playerIds {
+		score, err := scoreCmds[i].Result()
+		if err == redis.Nil {
+			continue // 玩家不在排行榜中
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		lb := Leaderboard{
+			Type:   leaderboardName,
+			UserId: playerId,
+			Score:  decodeRedisScore(score),
+		}
+
+		// 解析用户详情JSON
+		if i < len(details) {
+			if userDetail, ok := details[i].(string); ok && userDetail != "" {
+				var detailMap map[string]interface{}
+				if err := json.Unmarshal([]byte(userDetail), &detailMap); err == nil {
+					if ed, ok := detailMap["extra_data"].(string); ok {
+						lb.ExtraData = ed
+					}
+					if ut, ok := detailMap["update_time"].(float64); ok {
+						lb.UpdatedAt = time.Unix(int64(ut), 0).Format("2006-01-02 15:04:05")
+					}
+				}
+			}
+		}
+
+		leaderboards = append(leaderboards, lb)
+	}
+
+	return leaderboards, nil
+}
+
+// getPlayersScoreFromDB 从数据库批量获取玩家分数
+func getPlayersScoreFromDB(appId, leaderboardName, partition string, playerIds []string) ([]Leaderboard, error) {
+	o := orm.NewOrm()
+	tableName := getLeaderboardTableName(appId)
+
+	sql := fmt.Sprintf(`
+		SELECT 
+			id,
+			type,
+			player_id as user_id,
+			score,
+			extra_data,
+			created_at as create_time,
+			updated_at as update_time
+		FROM %s
+		WHERE type = ? AND partition_key = ? AND player_id IN (%s)
+	`, tableName, utils.BuildPlaceholders(len(playerIds)))
+
+	args := make([]interface{}, 0, len(playerIds)+2)
+	args = append(args, leaderboardName, partition)
+	for _, playerId := range playerIds {
+		args = append(args, playerId)
+	}
+
+	var results []orm.Params
+	_, err := o.Raw(sql, args...).Values(&results)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertLeaderboardRows(appId, results, 0), nil
+}
+
+// getAroundRange 根据玩家排名计算前后count名的查询区间（从0开始，包含两端）
+func getAroundRange(rank, count int) (int, int) {
+	start := rank - 1 - count
+	if start < 0 {
+		start = 0
+	}
+	return start, rank - 1 + count
 }
 
 // getUserRankFromRedis 从Redis获取用户排名
-func getUserRankFromRedis(appId, userId, leaderboardName string) (int, int64, error) {
-	redisKey := getLeaderboardRedisKey(appId, leaderboardName)
+func getUserRankFromRedis(appId, userId, leaderboardName, partition string, ascending bool) (int, int64, error) {
+	redisKey := getLeaderboardRedisKey(appId, leaderboardName, partition)
 
 	// 查找用户在有序集合中的排名（从0开始，需要+1）
-	rank, err := RedisClient.ZRevRank(RedisClient.Context(), redisKey, userId).Result()
+	var rank int64
+	var err error
+	if ascending {
+		rank, err = RedisClient.ZRank(RedisClient.Context(), redisKey, userId).Result()
+	} else {
+		rank, err = RedisClient.ZRevRank(RedisClient.Context(), redisKey, userId).Result()
+	}
 	if err == redis.Nil {
 		return 0, 0, nil // 用户不在排行榜中
 	}
@@ -815,28 +1214,22 @@ func getUserRankFromRedis(appId, userId, leaderboardName string) (int, int64, er
 		return 0, 0, err
 	}
 
-	return int(rank) + 1, int64(score), nil
+	return int(rank) + 1, decodeRedisScore(score), nil
 }
 
-// getUserRankFromDB 从数据库获取用户排名
-func getUserRankFromDB(appId, userId, leaderboardName string) (int, int64, error) {
+// getUserRankFromDB 从数据库获取用户排名（同分时先达成者靠前）
+func getUserRankFromDB(appId, userId, leaderboardName, partition string, ascending bool) (int, int64, error) {
 	o := orm.NewOrm()
 	tableName := getLeaderboardTableName(appId)
 
-	// 获取排行榜配置ID
-	configId, err := getLeaderboardConfigId(appId, leaderboardName)
-	if err != nil {
-		return 0, 0, err
-	}
-
 	// 先获取用户分数
 	userSQL := fmt.Sprintf(`
-		SELECT score FROM %s 
-		WHERE type = ? AND player_id = ?
+		SELECT id, score, updated_at FROM %s 
+		WHERE type = ? AND partition_key = ? AND player_id = ?
 	`, tableName)
 
 	var userResult []orm.Params
-	_, err = o.Raw(userSQL, configId, userId).Values(&userResult)
+	_, err := o.Raw(userSQL, leaderboardName, partition, userId).Values(&userResult)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -872,14 +1265,24 @@ func getUserRankFromDB(appId, userId, leaderboardName string) (int, int64, error
 		}
 	}
 
-	// 计算排名：统计分数比该用户高的人数 + 1
+	// 计算排名：统计排在该用户之前的人数 + 1（分数更优，或同分但更早达成）
+	betterOp := ">"
+	if ascending {
+		betterOp = "<"
+	}
 	rankSQL := fmt.Sprintf(`
-		SELECT COUNT(*) + 1 as rank FROM %s 
-		WHERE type = ? AND score > ?
-	`, tableName)
+		SELECT COUNT(*) + 1 as user_rank FROM %s 
+		WHERE type = ? AND partition_key = ? AND (
+			score %s ?
+			OR (score = ? AND (updated_at < ? OR (updated_at = ? AND id < ?)))
+		)
+	`, tableName, betterOp)
+
+	recordId := paramToInt64(userResult[0]["id"])
+	updatedAt := paramToTime(userResult[0]["updated_at"])
 
 	var result []orm.Params
-	_, err = o.Raw(rankSQL, configId, userScore).Values(&result)
+	_, err = o.Raw(rankSQL, leaderboardName, partition, userScore, userScore, updatedAt, updatedAt, recordId).Values(&result)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -892,7 +1295,7 @@ func getUserRankFromDB(appId, userId, leaderboardName string) (int, int64, error
 	var rank int64
 
 	// 安全的类型转换
-	if rankVal, ok := data["rank"]; ok {
+	if rankVal, ok := data["user_rank"]; ok {
 		switch v := rankVal.(type) {
 		case int64:
 			rank = v
@@ -920,34 +1323,26 @@ func getUserRankFromDB(appId, userId, leaderboardName string) (int, int64, error
 	return int(rank), userScore, nil
 }
 
-// ResetLeaderboard 重置排行榜（同时清理Redis）
+// ResetLeaderboard 重置排行榜（归档本赛季排名并发放奖励后清空，同时清理Redis）
 func ResetLeaderboard(appId, leaderboardName string) error {
-	o := orm.NewOrm()
-	tableName := getLeaderboardTableName(appId)
+	_, err := settleLeaderboardSeason(appId, leaderboardName, false)
+	return err
+}
 
-	// 获取排行榜配置ID
-	configId, err := getLeaderboardConfigId(appId, leaderboardName)
-	if err != nil {
-		return err
+// clearLeaderboardRedis 清理排行榜各分区的Redis缓存
+func clearLeaderboardRedis(appId, leaderboardName string, partitions []string) {
+	if RedisClient == nil || len(partitions) == 0 {
+		return
 	}
 
-	sql := fmt.Sprintf(`
-		DELETE FROM %s 
-		WHERE type = ?
-	`, tableName)
-
-	_, err = o.Raw(sql, configId).Exec()
-	if err != nil {
-		return err
+	keys := make([]string, 0, len(partitions)*2)
+	for _, partition := range partitions {
+		redisKey := getLeaderboardRedisKey(appId, leaderboardName, partition)
+		keys = append(keys, redisKey, redisKey+":details")
 	}
-
-	// 同时清理Redis数据
-	if RedisClient != nil {
-		redisKey := getLeaderboardRedisKey(appId, leaderboardName)
-		RedisClient.Del(RedisClient.Context(), redisKey)
+	if err := RedisClient.Del(RedisClient.Context(), keys...).Err(); err != nil {
+		logs.Warn("清理排行榜Redis缓存失败: %v", err)
 	}
-
-	return nil
 }
 
 // GetLeaderboardList 获取排行榜列表（管理后台使用）
@@ -0,0 +1,350 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"game-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 联赛分组中玩家所处的区域
+const (
+	LeagueZonePromote  = "promote"  // 晋级区
+	LeagueZoneRelegate = "relegate" // 降级区
+	LeagueZoneStay     = "stay"     // 保级区
+)
+
+// getLeaderboardLeagueTableName 获取排行榜联赛分组表名
+func getLeaderboardLeagueTableName(appId string) string {
+	return utils.GetLeaderboardLeagueTableName(appId)
+}
+
+// LeaderboardLeagueConfig 联赛分组配置（存储在leaderboard_config.league_config）
+// 玩家在赛季内首次提交分数时分配到所在段位的一个分组，排名在分组内计算；
+// 赛季结束时每组前PromoteCount名晋级、后RelegateCount名降级
+type LeaderboardLeagueConfig struct {
+	BucketSize    int      `json:"bucketSize"`          // 每个分组的人数
+	TierCount     int      `json:"tierCount"`           // 段位数量（1为最低段位）
+	PromoteCount  int      `json:"promoteCount"`        // 每组晋级人数
+	RelegateCount int      `json:"relegateCount"`       // 每组降级人数
+	TierNames     []string `json:"tierNames,omitempty"` // 段位名称（按段位从低到高）
+}
+
+// LeagueStanding 玩家在联赛中的当前分组排名
+type LeagueStanding struct {
+	Tier          int           `json:"tier"`
+	TierName      string        `json:"tierName"`
+	Bucket        int           `json:"bucket"` // 0表示本赛季尚未分组
+	Rank          int           `json:"rank"`   // 0表示未上榜
+	Score         int64         `json:"score"`
+	PromoteCount  int           `json:"promoteCount"`
+	RelegateCount int           `json:"relegateCount"`
+	List          []Leaderboard `json:"list"`
+
+	league *LeaderboardLeagueConfig
+}
+
+// GetZone 判断分组内某个名次所处的区域
+func (s *LeagueStanding) GetZone(rank, memberCount int) string {
+	return s.league.GetZone(s.Tier, rank, memberCount)
+}
+
+// parseLeagueConfig 解析联赛分组配置，未配置时返回nil
+func parseLeagueConfig(leagueConfig string) (*LeaderboardLeagueConfig, error) {
+	if strings.TrimSpace(leagueConfig) == "" {
+		return nil, nil
+	}
+
+	var parsed LeaderboardLeagueConfig
+	if err := json.Unmarshal([]byte(leagueConfig), &parsed); err != nil {
+		return nil, fmt.Errorf("联赛配置格式错误: %v", err)
+	}
+	if parsed.BucketSize <= 0 || parsed.TierCount <= 0 {
+		return nil, fmt.Errorf("联赛配置bucketSize和tierCount必须大于0")
+	}
+
+	return &parsed, nil
+}
+
+// GetTierName 获取段位名称，未配置名称时返回空字符串
+func (l *LeaderboardLeagueConfig) GetTierName(tier int) string {
+	if tier < 1 || tier > len(l.TierNames) {
+		return ""
+	}
+	return l.TierNames[tier-1]
+}
+
+// GetZone 根据分组内名次和分组人数判断玩家所处区域（最高段位不晋级，最低段位不降级）
+func (l *LeaderboardLeagueConfig) GetZone(tier, rank, memberCount int) string {
+	if rank <= 0 {
+		return LeagueZoneStay
+	}
+	if tier < l.TierCount && rank <= l.PromoteCount {
+		return LeagueZonePromote
+	}
+	if tier > 1 && rank > memberCount-l.RelegateCount {
+		return LeagueZoneRelegate
+	}
+	return LeagueZoneStay
+}
+
+// getLeaguePartition 获取联赛分组对应的排行榜分区键
+func getLeaguePartition(tier, bucket int) string {
+	return fmt.Sprintf("league_%d_%d", tier, bucket)
+}
+
+// getLeagueMember 查询玩家当前段位和本赛季分组，未参加过联赛时返回段位1、分组0
+func getLeagueMember(o orm.QueryExecutor, appId, userId, leaderboardName string) (int, int, error) {
+	var rows []orm.Params
+	_, err := o.Raw(fmt.Sprintf(`SELECT tier, bucket FROM %s WHERE type = ? AND player_id = ?`, getLeaderboardLeagueTableName(appId)),
+		leaderboardName, userId).Values(&rows)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(rows) == 0 {
+		return 1, 0, nil
+	}
+
+	return int(paramToInt64(rows[0]["tier"])), int(paramToInt64(rows[0]["bucket"])), nil
+}
+
+// assignLeagueBucket 为玩家分配本赛季的联赛分组并返回对应分区键，已分配时直接返回
+func assignLeagueBucket(appId, userId, leaderboardName string, config *LeaderboardConfig, league *LeaderboardLeagueConfig) (string, error) {
+	o := orm.NewOrm()
+
+	tier, bucket, err := getLeagueMember(o, appId, userId, leaderboardName)
+	if err != nil {
+		return "", err
+	}
+	if bucket > 0 {
+		return getLeaguePartition(tier, bucket), nil
+	}
+
+	tx, err := o.Begin()
+	if err != nil {
+		return "", err
+	}
+
+	// 锁定排行榜配置行，串行化同一排行榜的分组分配，避免分组超员
+	var lock []orm.Params
+	if _, err = tx.Raw(`SELECT id FROM leaderboard_config WHERE id = ? FOR UPDATE`, config.Id).Values(&lock); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+
+	// 加锁后重新读取，可能已被并发请求分配
+	tier, bucket, err = getLeagueMember(tx, appId, userId, leaderboardName)
+	if err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if bucket > 0 {
+		tx.Rollback()
+		return getLeaguePartition(tier, bucket), nil
+	}
+	if tier > league.TierCount {
+		tier = league.TierCount
+	}
+
+	// 当前段位最新的分组未满则加入，否则新开一组
+	tableName := getLeaderboardLeagueTableName(appId)
+	var latest []orm.Params
+	_, err = tx.Raw(fmt.Sprintf(`
+		SELECT bucket, COUNT(*) as member_count
+		FROM %s
+		WHERE type = ? AND tier = ? AND bucket > 0
+		GROUP BY bucket
+		ORDER BY bucket DESC
+		LIMIT 1
+	`, tableName), leaderboardName, tier).Values(&latest)
+	if err != nil {
+		tx.Rollback()
+		return "", err
+	}
+
+	bucket = 1
+	if len(latest) > 0 {
+		bucket = int(paramToInt64(latest[0]["bucket"]))
+		if int(paramToInt64(latest[0]["member_count"])) >= league.BucketSize {
+			bucket++
+		}
+	}
+
+	_, err = tx.Raw(fmt.Sprintf(`
+		INSERT INTO %s (type, player_id, tier, bucket, created_at, updated_at)
+		VALUES (?, ?, ?, ?, NOW(), NOW())
+		ON DUPLICATE KEY UPDATE tier = VALUES(tier), bucket = VALUES(bucket), updated_at = NOW()
+	`, tableName), leaderboardName, userId, tier, bucket).Exec()
+	if err != nil {
+		tx.Rollback()
+		return "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return getLeaguePartition(tier, bucket), nil
+}
+
+// settleLeagueSeason 赛季结束时按分组内名次执行晋级/降级，并清空所有玩家的分组（需在事务中调用）
+// rows 为按分区和最终排名排序的排行榜数据，ranking字段为分组内名次
+func settleLeagueSeason(tx orm.TxOrmer, appId, leaderboardName string, config *LeaderboardConfig, rows []orm.Params) error {
+	league, err := parseLeagueConfig(config.LeagueConfig)
+	if err != nil {
+		// 配置错误不阻塞排行榜重置，只记录日志
+		logs.Error("联赛晋降级未执行: appId=%s, type=%s, err=%v", appId, leaderboardName, err)
+		return nil
+	}
+	if league == nil {
+		return nil
+	}
+
+	tableName := getLeaderboardLeagueTableName(appId)
+
+	// 读取本赛季有分组的玩家段位
+	var members []orm.Params
+	_, err = tx.Raw(fmt.Sprintf(`SELECT player_id, tier FROM %s WHERE type = ? AND bucket > 0 FOR UPDATE`, tableName),
+		leaderboardName).Values(&members)
+	if err != nil {
+		return err
+	}
+	tierMap := make(map[string]int, len(members))
+	for _, member := range members {
+		if playerId, ok := member["player_id"].(string); ok {
+			tierMap[playerId] = int(paramToInt64(member["tier"]))
+		}
+	}
+
+	// 统计各分组上榜人数
+	memberCount := make(map[string]int)
+	for _, row := range rows {
+		partition, _ := row["partition_key"].(string)
+		memberCount[partition]++
+	}
+
+	var promoted, relegated []interface{}
+	for _, row := range rows {
+		playerId, _ := row["player_id"].(string)
+		tier, ok := tierMap[playerId]
+		if !ok {
+			continue
+		}
+		partition, _ := row["partition_key"].(string)
+		switch league.GetZone(tier, int(paramToInt64(row["ranking"])), memberCount[partition]) {
+		case LeagueZonePromote:
+			promoted = append(promoted, playerId)
+		case LeagueZoneRelegate:
+			relegated = append(relegated, playerId)
+		}
+	}
+
+	if err = updateLeagueTier(tx, tableName, leaderboardName, "LEAST(tier + 1, ?)", league.TierCount, promoted); err != nil {
+		return err
+	}
+	if err = updateLeagueTier(tx, tableName, leaderboardName, "GREATEST(tier - 1, ?)", 1, relegated); err != nil {
+		return err
+	}
+
+	// 新赛季重新分组
+	if _, err = tx.Raw(fmt.Sprintf(`UPDATE %s SET bucket = 0, updated_at = NOW() WHERE type = ? AND bucket > 0`, tableName),
+		leaderboardName).Exec(); err != nil {
+		return err
+	}
+
+	logs.Info("联赛晋降级完成: appId=%s, type=%s, 晋级=%d, 降级=%d", appId, leaderboardName, len(promoted), len(relegated))
+	return nil
+}
+
+// updateLeagueTier 分批更新玩家段位
+func updateLeagueTier(tx orm.TxOrmer, tableName, leaderboardName, tierExpr string, bound int, playerIds []interface{}) error {
+	for start := 0; start < len(playerIds); start += seasonArchiveBatchSize {
+		end := start + seasonArchiveBatchSize
+		if end > len(playerIds) {
+			end = len(playerIds)
+		}
+
+		args := make([]interface{}, 0, end-start+2)
+		args = append(args, bound, leaderboardName)
+		args = append(args, playerIds[start:end]...)
+		_, err := tx.Raw(fmt.Sprintf(`UPDATE %s SET tier = %s, updated_at = NOW() WHERE type = ? AND player_id IN (%s)`,
+			tableName, tierExpr, utils.BuildPlaceholders(end-start)), args...).Exec()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetLeagueStanding 获取玩家所在联赛分组的排名情况（优先从Redis读取）
+func GetLeagueStanding(appId, userId, leaderboardName string) (*LeagueStanding, error) {
+	// 1. 获取排行榜配置并检查重置
+	config, err := getLeaderboardConfig(appId, leaderboardName)
+	if err != nil {
+		return nil, fmt.Errorf("获取排行榜配置失败: %v", err)
+	}
+
+	league, err := parseLeagueConfig(config.LeagueConfig)
+	if err != nil {
+		return nil, err
+	}
+	if league == nil {
+		return nil, fmt.Errorf("排行榜未开启联赛模式")
+	}
+
+	checkAndResetLeaderboard(appId, leaderboardName, config)
+
+	// 2. 查询玩家所在分组
+	tier, bucket, err := getLeagueMember(orm.NewOrm(), appId, userId, leaderboardName)
+	if err != nil {
+		return nil, err
+	}
+
+	standing := &LeagueStanding{
+		Tier:          tier,
+		TierName:      league.GetTierName(tier),
+		Bucket:        bucket,
+		PromoteCount:  league.PromoteCount,
+		RelegateCount: league.RelegateCount,
+		List:          []Leaderboard{},
+		league:        league,
+	}
+	if bucket == 0 {
+		return standing, nil // 本赛季尚未提交分数
+	}
+
+	// 3. 获取整个分组的排名（尝试从Redis获取，失败时从数据库获取）
+	partition := getLeaguePartition(tier, bucket)
+	ascending := isScoreAscending(config)
+
+	var list []Leaderboard
+	if RedisClient != nil {
+		list, err = getLeaderboardFromRedis(appId, leaderboardName, partition, league.BucketSize, ascending)
+		if err == nil && len(list) > 0 {
+			fillLeaderboardUserInfo(appId, list)
+		}
+	}
+	if len(list) == 0 {
+		list, err = getLeaderboardRangeFromDB(appId, leaderboardName, partition, 0, league.BucketSize, ascending)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, item := range list {
+		if item.UserId == userId {
+			standing.Rank = item.Rank
+			standing.Score = item.Score
+			break
+		}
+	}
+	if len(list) > 0 {
+		standing.List = list
+	}
+
+	return standing, nil
+}
@@ -0,0 +1,287 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrInvalidCursor 分页游标无效
+var ErrInvalidCursor = errors.New("无效的分页游标")
+
+// LeaderboardPage 排行榜分页查询结果
+type LeaderboardPage struct {
+	Total      int64         `json:"total"`
+	Offset     int           `json:"offset"` // 本页第一条之前的名次数
+	HasMore    bool          `json:"hasMore"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+	List       []Leaderboard `json:"list"`
+}
+
+// leaderboardCursor 分页游标，记录上一页最后一名的位置
+// 按分数和达成时间定位而不是按名次，翻页期间其他玩家分数变化也不会出现重复或遗漏
+type leaderboardCursor struct {
+	Rank       int     `json:"r"`
+	Score      int64   `json:"s"`
+	ReachedAt  int64   `json:"t"`
+	PlayerId   string  `json:"p"`
+	RedisScore float64 `json:"c,omitempty"`
+}
+
+// encodeLeaderboardCursor 根据本页最后一条数据生成下一页游标
+func encodeLeaderboardCursor(last Leaderboard) string {
+	data, _ := json.Marshal(leaderboardCursor{
+		Rank:       last.Rank,
+		Score:      last.Score,
+		ReachedAt:  last.reachedAt.Unix(),
+		PlayerId:   last.UserId,
+		RedisScore: last.redisScore,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeLeaderboardCursor 解析分页游标
+func decodeLeaderboardCursor(cursor string) (*leaderboardCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var parsed leaderboardCursor
+	if err := json.Unmarshal(data, &parsed); err != nil || parsed.PlayerId == "" || parsed.Rank < 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &parsed, nil
+}
+
+// GetLeaderboardPage 分页获取排行榜（优先从Redis读取）
+// cursor不为空时从游标位置继续翻页，否则从offset开始
+func GetLeaderboardPage(appId, leaderboardName, partition string, offset, limit int, cursor string) (*LeaderboardPage, error) {
+	var after *leaderboardCursor
+	if cursor != "" {
+		var err error
+		if after, err = decodeLeaderboardCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// 1. 获取排行榜配置并检查重置
+	config, err := getLeaderboardConfig(appId, leaderboardName)
+	if err != nil {
+		return nil, fmt.Errorf("获取排行榜配置失败: %v", err)
+	}
+
+	checkAndResetLeaderboard(appId, leaderboardName, config)
+
+	ascending := isScoreAscending(config)
+
+	// 2. 尝试从Redis获取
+	var page *LeaderboardPage
+	if RedisClient != nil {
+		page, err = getLeaderboardPageFromRedis(appId, leaderboardName, partition, offset, limit, after, ascending)
+		if err == nil && page.Total > 0 {
+			fillLeaderboardUserInfo(appId, page.List)
+		} else {
+			page = nil // Redis失败或没有数据，继续从数据库读取
+		}
+	}
+
+	// 3. 从数据库获取
+	if page == nil {
+		page, err = getLeaderboardPageFromDB(appId, leaderboardName, partition, offset, limit, after, ascending)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if page.List == nil {
+		page.List = []Leaderboard{}
+	}
+	page.HasMore = int64(page.Offset+len(page.List)) < page.Total && len(page.List) > 0
+	if page.HasMore {
+		page.NextCursor = encodeLeaderboardCursor(page.List[len(page.List)-1])
+	}
+
+	return page, nil
+}
+
+// getLeaderboardPageFromRedis 从Redis分页获取排行榜
+func getLeaderboardPageFromRedis(appId, leaderboardName, partition string, offset, limit int, after *leaderboardCursor, ascending bool) (*LeaderboardPage, error) {
+	ctx := RedisClient.Context()
+	scoreKey := getLeaderboardRedisKey(appId, leaderboardName, partition)
+	detailKey := scoreKey + ":details"
+
+	total, err := RedisClient.ZCard(ctx, scoreKey).Result()
+	if err != nil || total == 0 {
+		return &LeaderboardPage{}, err
+	}
+
+	page := &LeaderboardPage{Total: total}
+	if after == nil {
+		page.Offset = offset
+		page.List, err = getLeaderboardRangeFromRedis(appId, leaderboardName, partition, int64(offset), int64(offset+limit-1), ascending)
+		return page, err
+	}
+
+	pivot := after.RedisScore
+	if pivot == 0 {
+		pivot = encodeRedisScore(after.Score, time.Unix(after.ReachedAt, 0), ascending)
+	}
+	results, err := getRedisMembersAfterCursor(ctx, scoreKey, pivot, after.PlayerId, limit, ascending)
+	if err != nil {
+		return nil, err
+	}
+
+	page.Offset = after.Rank
+	page.List = redisMembersToLeaderboards(ctx, leaderboardName, detailKey, results, after.Rank)
+	return page, nil
+}
+
+// getRedisMembersAfterCursor 获取排在游标之后的limit个成员
+// 游标玩家仍在榜且复合分数未变时直接按排名读取；否则分批读取同分成员（Redis同分按成员名排序，降序时为逆序），
+// 跳过排在游标之前的，不足limit时再读取分数更靠后的成员。分数超过maxTiebreakScore时同分成员可能很多，每次最多读取limit个
+func getRedisMembersAfterCursor(ctx context.Context, scoreKey string, pivot float64, cursorPlayerId string, limit int, ascending bool) ([]redis.Z, error) {
+	pipe := RedisClient.TxPipeline()
+	scoreCmd := pipe.ZScore(ctx, scoreKey, cursorPlayerId)
+	var rankCmd *redis.IntCmd
+	if ascending {
+		rankCmd = pipe.ZRank(ctx, scoreKey, cursorPlayerId)
+	} else {
+		rankCmd = pipe.ZRevRank(ctx, scoreKey, cursorPlayerId)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	if scoreCmd.Err() == nil && rankCmd.Err() == nil && scoreCmd.Val() == pivot {
+		start, stop := rankCmd.Val()+1, rankCmd.Val()+int64(limit)
+		if ascending {
+			return RedisClient.ZRangeWithScores(ctx, scoreKey, start, stop).Result()
+		}
+		return RedisClient.ZRevRangeWithScores(ctx, scoreKey, start, stop).Result()
+	}
+
+	pivotStr := strconv.FormatFloat(pivot, 'f', -1, 64)
+	results := make([]redis.Z, 0, limit)
+	for offset := int64(0); ; offset += int64(limit) {
+		tieRange := &redis.ZRangeBy{Min: pivotStr, Max: pivotStr, Offset: offset, Count: int64(limit)}
+		var ties []redis.Z
+		var err error
+		if ascending {
+			ties, err = RedisClient.ZRangeByScoreWithScores(ctx, scoreKey, tieRange).Result()
+		} else {
+			ties, err = RedisClient.ZRevRangeByScoreWithScores(ctx, scoreKey, tieRange).Result()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, member := range ties {
+			playerId, _ := member.Member.(string)
+			if (ascending && playerId > cursorPlayerId) || (!ascending && playerId < cursorPlayerId) {
+				results = append(results, member)
+				if len(results) == limit {
+					return results, nil
+				}
+			}
+		}
+		if len(ties) < limit {
+			break
+		}
+	}
+
+	restRange := &redis.ZRangeBy{Count: int64(limit - len(results))}
+	var rest []redis.Z
+	var err error
+	if ascending {
+		restRange.Min, restRange.Max = "("+pivotStr, "+inf"
+		rest, err = RedisClient.ZRangeByScoreWithScores(ctx, scoreKey, restRange).Result()
+	} else {
+		restRange.Min, restRange.Max = "-inf", "("+pivotStr
+		rest, err = RedisClient.ZRevRangeByScoreWithScores(ctx, scoreKey, restRange).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(results, rest...), nil
+}
+
+// getLeaderboardPageFromDB 从数据库分页获取排行榜（同分时先达成者靠前）
+func getLeaderboardPageFromDB(appId, leaderboardName, partition string, offset, limit int, after *leaderboardCursor, ascending bool) (*LeaderboardPage, error) {
+	o := orm.NewOrm()
+	tableName := getLeaderboardTableName(appId)
+
+	var countResult []orm.Params
+	_, err := o.Raw(fmt.Sprintf(`SELECT COUNT(*) as total FROM %s WHERE type = ? AND partition_key = ?`, tableName),
+		leaderboardName, partition).Values(&countResult)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &LeaderboardPage{}
+	if len(countResult) > 0 {
+		page.Total = paramToInt64(countResult[0]["total"])
+	}
+	if page.Total == 0 {
+		return page, nil
+	}
+
+	if after == nil {
+		page.Offset = offset
+		page.List, err = getLeaderboardRangeFromDB(appId, leaderboardName, partition, offset, limit, ascending)
+		return page, err
+	}
+
+	// 游标玩家的记录ID用于同分同时间的排序，记录已不存在时跳过所有同分同时间的玩家
+	var anchorId int64 = math.MaxInt64
+	var anchor []orm.Params
+	_, err = o.Raw(fmt.Sprintf(`SELECT id FROM %s WHERE type = ? AND partition_key = ? AND player_id = ?`, tableName),
+		leaderboardName, partition, after.PlayerId).Values(&anchor)
+	if err != nil {
+		return nil, err
+	}
+	if len(anchor) > 0 {
+		anchorId = paramToInt64(anchor[0]["id"])
+	}
+
+	worseOp := "<"
+	if ascending {
+		worseOp = ">"
+	}
+	reachedAt := time.Unix(after.ReachedAt, 0)
+
+	sql := fmt.Sprintf(`
+		SELECT
+			id,
+			type,
+			player_id as user_id,
+			score,
+			extra_data,
+			created_at as create_time,
+			updated_at as update_time
+		FROM %s
+		WHERE type = ? AND partition_key = ? AND (
+			score %s ?
+			OR (score = ? AND (updated_at > ? OR (updated_at = ? AND id > ?)))
+		)
+		ORDER BY score %s, updated_at ASC, id ASC
+		LIMIT ?
+	`, tableName, worseOp, getScoreOrder(ascending))
+
+	var results []orm.Params
+	_, err = o.Raw(sql, leaderboardName, partition, after.Score, after.Score, reachedAt, reachedAt, anchorId, limit).Values(&results)
+	if err != nil {
+		return nil, err
+	}
+
+	page.Offset = after.Rank
+	page.List = convertLeaderboardRows(appId, results, after.Rank)
+	return page, nil
+}
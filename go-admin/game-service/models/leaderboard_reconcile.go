@@ -0,0 +1,361 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/config"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/go-redis/redis/v8"
+)
+
+// 排行榜对账相关Redis键（与管理后台约定一致）
+const (
+	leaderboardReconcileQueueKey  = "leaderboard_reconcile:queue"   // 后台触发的对账请求队列
+	leaderboardReconcileReportKey = "leaderboard_reconcile:report:" // 对账报告，后接请求ID
+	leaderboardReconcileLastKey   = "leaderboard_reconcile:last"    // 最近一次定时对账报告
+	leaderboardReconcileLockKey   = "leaderboard_reconcile:lock"    // 多实例部署时的定时对账锁
+)
+
+const (
+	// reconcileBatchSize 修复Redis数据时每批写入的成员数
+	reconcileBatchSize = 500
+	// reconcileReportTTL 对账报告保留时间
+	reconcileReportTTL = 24 * time.Hour
+)
+
+// LeaderboardReconcileRequest 管理后台提交的对账请求
+type LeaderboardReconcileRequest struct {
+	RequestId string `json:"requestId"`
+	AppId     string `json:"appId"` // 为空表示所有应用
+	Operator  string `json:"operator"`
+}
+
+// LeaderboardDrift 单个排行榜分区的对账差异
+type LeaderboardDrift struct {
+	AppId      string `json:"appId"`
+	Type       string `json:"type"`
+	Partition  string `json:"partition,omitempty"`
+	DBCount    int    `json:"dbCount"`
+	RedisCount int    `json:"redisCount"`
+	Missing    int    `json:"missing"` // 数据库中存在但Redis缺失的成员
+	Stale      int    `json:"stale"`   // Redis分数与数据库不一致的成员
+	Extra      int    `json:"extra"`   // Redis中存在但数据库已删除的成员
+	Rebuilt    bool   `json:"rebuilt"` // Redis键不存在，已整体重建
+}
+
+// LeaderboardReconcileReport 对账报告
+type LeaderboardReconcileReport struct {
+	RequestId  string             `json:"requestId,omitempty"`
+	AppId      string             `json:"appId,omitempty"`
+	Operator   string             `json:"operator,omitempty"`
+	StartedAt  string             `json:"startedAt"`
+	FinishedAt string             `json:"finishedAt"`
+	Checked    int                `json:"checked"`  // 检查的排行榜分区数
+	Repaired   int                `json:"repaired"` // 存在差异并已修复的分区数
+	Drifts     []LeaderboardDrift `json:"drifts"`
+	Errors     []string           `json:"errors"`
+}
+
+// StartLeaderboardReconciler 启动排行榜对账后台任务
+// 按leaderboard_reconcile_interval（秒，默认600，0表示关闭）定时对账，同时处理管理后台触发的对账请求
+func StartLeaderboardReconciler() {
+	if RedisClient == nil {
+		return
+	}
+
+	appconf, _ := config.NewConfig("ini", "conf/app.conf")
+	interval := 600
+	if appconf != nil {
+		interval = appconf.DefaultInt("leaderboard_reconcile_interval", 600)
+	}
+
+	if interval > 0 {
+		go runScheduledReconcile(time.Duration(interval) * time.Second)
+	}
+	go consumeReconcileRequests()
+
+	logs.Info("排行榜对账任务已启动: interval=%ds", interval)
+}
+
+// runScheduledReconcile 定时对账所有应用（多实例部署时通过Redis锁保证同一周期只执行一次）
+func runScheduledReconcile(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx := context.Background()
+		locked, err := RedisClient.SetNX(ctx, leaderboardReconcileLockKey, time.Now().Unix(), interval/2).Result()
+		if err != nil || !locked {
+			continue
+		}
+
+		report := ReconcileLeaderboards("")
+		saveReconcileReport(leaderboardReconcileLastKey, report)
+		if report.Repaired > 0 || len(report.Errors) > 0 {
+			logs.Warn("排行榜定时对账完成: checked=%d, repaired=%d, errors=%d", report.Checked, report.Repaired, len(report.Errors))
+		}
+	}
+}
+
+// consumeReconcileRequests 处理管理后台触发的对账请求
+func consumeReconcileRequests() {
+	ctx := context.Background()
+	for {
+		result, err := RedisClient.BRPop(ctx, 30*time.Second, leaderboardReconcileQueueKey).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			logs.Warn("读取排行榜对账请求失败: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		var req LeaderboardReconcileRequest
+		if err := json.Unmarshal([]byte(result[1]), &req); err != nil || req.RequestId == "" {
+			logs.Warn("排行榜对账请求格式错误: %s", result[1])
+			continue
+		}
+
+		logs.Info("开始排行榜对账: requestId=%s, appId=%s, operator=%s", req.RequestId, req.AppId, req.Operator)
+		report := ReconcileLeaderboards(req.AppId)
+		report.RequestId = req.RequestId
+		report.Operator = req.Operator
+		saveReconcileReport(leaderboardReconcileReportKey+req.RequestId, report)
+	}
+}
+
+// saveReconcileReport 保存对账报告到Redis
+func saveReconcileReport(key string, report *LeaderboardReconcileReport) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return
+	}
+	if err := RedisClient.Set(context.Background(), key, string(data), reconcileReportTTL).Err(); err != nil {
+		logs.Warn("保存排行榜对账报告失败: %v", err)
+	}
+}
+
+// ReconcileLeaderboards 对比数据库与Redis中的排行榜数据并修复差异，appId为空时检查所有启用的应用
+func ReconcileLeaderboards(appId string) *LeaderboardReconcileReport {
+	report := &LeaderboardReconcileReport{
+		AppId:     appId,
+		StartedAt: time.Now().Format("2006-01-02 15:04:05"),
+		Drifts:    []LeaderboardDrift{},
+		Errors:    []string{},
+	}
+	defer func() {
+		report.FinishedAt = time.Now().Format("2006-01-02 15:04:05")
+	}()
+
+	if RedisClient == nil {
+		report.Errors = append(report.Errors, "Redis未启用")
+		return report
+	}
+
+	o := orm.NewOrm()
+
+	appIds := []string{appId}
+	if appId == "" {
+		var apps []orm.Params
+		if _, err := o.Raw(`SELECT app_id FROM apps WHERE status = 'active'`).Values(&apps); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("查询应用列表失败: %v", err))
+			return report
+		}
+		appIds = appIds[:0]
+		for _, app := range apps {
+			if id, ok := app["app_id"].(string); ok {
+				appIds = append(appIds, id)
+			}
+		}
+	}
+
+	for _, id := range appIds {
+		var types []orm.Params
+		if _, err := o.Raw(`SELECT leaderboard_type FROM leaderboard_config WHERE app_id = ? AND enabled = 1`, id).Values(&types); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: 查询排行榜配置失败: %v", id, err))
+			continue
+		}
+
+		for _, row := range types {
+			leaderboardName, _ := row["leaderboard_type"].(string)
+			if leaderboardName == "" {
+				continue
+			}
+			if err := reconcileLeaderboard(id, leaderboardName, report); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s/%s: %v", id, leaderboardName, err))
+			}
+		}
+	}
+
+	return report
+}
+
+// reconcileLeaderboard 对账单个排行榜的所有分区（包括只存在于Redis中的分区）
+func reconcileLeaderboard(appId, leaderboardName string, report *LeaderboardReconcileReport) error {
+	config, err := getLeaderboardConfig(appId, leaderboardName)
+	if err != nil {
+		return err
+	}
+
+	var rows []orm.Params
+	_, err = orm.NewOrm().Raw(fmt.Sprintf(`SELECT DISTINCT partition_key FROM %s WHERE type = ?`, getLeaderboardTableName(appId)),
+		leaderboardName).Values(&rows)
+	if err != nil {
+		return err
+	}
+
+	partitions := map[string]bool{"": true}
+	for _, row := range rows {
+		partition, _ := row["partition_key"].(string)
+		partitions[partition] = true
+	}
+
+	// 扫描Redis中残留的分区键
+	ctx := RedisClient.Context()
+	prefix := getLeaderboardRedisKey(appId, leaderboardName, "") + ":p:"
+	iter := RedisClient.Scan(ctx, 0, prefix+"*", 200).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if strings.HasSuffix(key, ":details") {
+			continue
+		}
+		partitions[strings.TrimPrefix(key, prefix)] = true
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	for partition := range partitions {
+		drift, err := reconcileLeaderboardPartition(appId, leaderboardName, partition, config)
+		report.Checked++
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s/%s/%s: %v", appId, leaderboardName, partition, err))
+			continue
+		}
+		if drift != nil {
+			report.Repaired++
+			report.Drifts = append(report.Drifts, *drift)
+		}
+	}
+
+	return nil
+}
+
+// reconcileLeaderboardPartition 对账单个分区，存在差异时修复Redis并返回差异，无差异返回nil
+func reconcileLeaderboardPartition(appId, leaderboardName, partition string, config *LeaderboardConfig) (*LeaderboardDrift, error) {
+	ctx := RedisClient.Context()
+	scoreKey := getLeaderboardRedisKey(appId, leaderboardName, partition)
+	detailKey := scoreKey + ":details"
+	ascending := isScoreAscending(config)
+
+	// 1. 先读取Redis中的分数（再读数据库，避免把期间新提交的成员误判为多余成员）
+	members, err := RedisClient.ZRangeWithScores(ctx, scoreKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	redisScores := make(map[string]float64, len(members))
+	for _, member := range members {
+		if playerId, ok := member.Member.(string); ok {
+			redisScores[playerId] = member.Score
+		}
+	}
+
+	// 2. 读取数据库中的分数
+	var rows []orm.Params
+	_, err = orm.NewOrm().Raw(fmt.Sprintf(`SELECT player_id, score, extra_data, updated_at FROM %s WHERE type = ? AND partition_key = ?`,
+		getLeaderboardTableName(appId)), leaderboardName, partition).Values(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	drift := &LeaderboardDrift{
+		AppId:      appId,
+		Type:       leaderboardName,
+		Partition:  partition,
+		DBCount:    len(rows),
+		RedisCount: len(members),
+		Rebuilt:    len(members) == 0 && len(rows) > 0,
+	}
+
+	// 3. 找出缺失和分数不一致的成员
+	var repairs []orm.Params
+	var expected []float64
+	for _, row := range rows {
+		playerId, _ := row["player_id"].(string)
+		encoded := encodeRedisScore(paramToInt64(row["score"]), paramToTime(row["updated_at"]), ascending)
+		current, exists := redisScores[playerId]
+		delete(redisScores, playerId)
+
+		if !exists {
+			drift.Missing++
+		} else if current != encoded {
+			drift.Stale++
+		} else {
+			continue
+		}
+		repairs = append(repairs, row)
+		expected = append(expected, encoded)
+	}
+
+	// 剩余的是数据库中已不存在的成员
+	extras := make([]string, 0, len(redisScores))
+	for playerId := range redisScores {
+		extras = append(extras, playerId)
+	}
+	drift.Extra = len(extras)
+
+	if len(repairs) == 0 && len(extras) == 0 {
+		return nil, nil
+	}
+
+	// 4. 修复Redis数据
+	for start := 0; start < len(repairs); start += reconcileBatchSize {
+		end := start + reconcileBatchSize
+		if end > len(repairs) {
+			end = len(repairs)
+		}
+
+		pipe := RedisClient.Pipeline()
+		for i := start; i < end; i++ {
+			playerId, _ := repairs[i]["player_id"].(string)
+			extraData, _ := repairs[i]["extra_data"].(string)
+			details, _ := json.Marshal(map[string]interface{}{
+				"extra_data":  extraData,
+				"update_time": paramToTime(repairs[i]["updated_at"]).Unix(),
+			})
+			pipe.ZAdd(ctx, scoreKey, &redis.Z{Score: expected[i], Member: playerId})
+			pipe.HSet(ctx, detailKey, playerId, string(details))
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(extras) > 0 {
+		members := make([]interface{}, len(extras))
+		for i, playerId := range extras {
+			members[i] = playerId
+		}
+		pipe := RedisClient.Pipeline()
+		pipe.ZRem(ctx, scoreKey, members...)
+		pipe.HDel(ctx, detailKey, extras...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(rows) > 0 {
+		setRedisExpiry(ctx, scoreKey, config)
+		setRedisExpiry(ctx, detailKey, config)
+	}
+
+	logs.Info("排行榜对账已修复: appId=%s, type=%s, partition=%s, missing=%d, stale=%d, extra=%d",
+		appId, leaderboardName, partition, drift.Missing, drift.Stale, drift.Extra)
+	return drift, nil
+}
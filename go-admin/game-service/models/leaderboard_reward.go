@@ -0,0 +1,132 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"game-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// rewardMailExpireDays 赛季奖励邮件有效天数
+const rewardMailExpireDays = 30
+
+// LeaderboardRewardTier 排行榜赛季奖励档位
+// 标题和内容支持占位符: {name} 排行榜名称, {season} 赛季号, {rank} 名次
+type LeaderboardRewardTier struct {
+	MinRank int             `json:"minRank"`
+	MaxRank int             `json:"maxRank"`
+	Rewards json.RawMessage `json:"rewards"` // 奖励列表（JSON数组），原样写入邮件
+	Title   string          `json:"title,omitempty"`
+	Content string          `json:"content,omitempty"`
+}
+
+// parseRewardTiers 解析排行榜配置中的奖励档位
+func parseRewardTiers(rewardTiers string) ([]LeaderboardRewardTier, error) {
+	if strings.TrimSpace(rewardTiers) == "" {
+		return nil, nil
+	}
+
+	var tiers []LeaderboardRewardTier
+	if err := json.Unmarshal([]byte(rewardTiers), &tiers); err != nil {
+		return nil, fmt.Errorf("奖励档位配置格式错误: %v", err)
+	}
+
+	return tiers, nil
+}
+
+// findRewardTier 查找名次对应的奖励档位
+func findRewardTier(tiers []LeaderboardRewardTier, rank int) *LeaderboardRewardTier {
+	for i := range tiers {
+		if rank >= tiers[i].MinRank && rank <= tiers[i].MaxRank {
+			return &tiers[i]
+		}
+	}
+	return nil
+}
+
+// sendSeasonRewardMails 按奖励档位给本赛季上榜玩家发送个人奖励邮件（需在事务中调用）
+// rows 为本批需要结算的排行榜数据，ranking字段为分区内名次
+func sendSeasonRewardMails(tx orm.TxOrmer, appId string, config *LeaderboardConfig, season int, rows []orm.Params) error {
+	tiers, err := parseRewardTiers(config.RewardTiers)
+	if err != nil {
+		// 配置错误不阻塞排行榜重置，只记录日志
+		logs.Error("赛季奖励未发放: appId=%s, type=%s, season=%d, err=%v", appId, config.LeaderboardType, season, err)
+		return nil
+	}
+	if len(tiers) == 0 {
+		return nil
+	}
+
+	mailTableName := utils.GetMailTableName(appId)
+	expireTime := time.Now().AddDate(0, 0, rewardMailExpireDays)
+
+	values := make([]string, 0, seasonArchiveBatchSize)
+	args := make([]interface{}, 0, seasonArchiveBatchSize*5)
+	sent := 0
+
+	flush := func() error {
+		if len(values) == 0 {
+			return nil
+		}
+		insertSQL := fmt.Sprintf(`
+			INSERT INTO %s (title, content, type, sender, targets, target_type, rewards, status, send_time, expire_time, total_count, created_by)
+			VALUES %s
+		`, mailTableName, strings.Join(values, ", "))
+		if _, err := tx.Raw(insertSQL, args...).Exec(); err != nil {
+			return err
+		}
+		values = values[:0]
+		args = args[:0]
+		return nil
+	}
+
+	for _, row := range rows {
+		rank := int(paramToInt64(row["ranking"]))
+		tier := findRewardTier(tiers, rank)
+		if tier == nil {
+			continue
+		}
+
+		playerId, _ := row["player_id"].(string)
+		if playerId == "" {
+			continue
+		}
+		targets, _ := json.Marshal([]string{playerId})
+
+		title := tier.Title
+		if title == "" {
+			title = "{name}第{season}赛季排名奖励"
+		}
+		content := tier.Content
+		if content == "" {
+			content = "恭喜你在{name}第{season}赛季中获得第{rank}名，请领取赛季奖励！"
+		}
+		replacer := strings.NewReplacer("{name}", config.Name, "{season}", strconv.Itoa(season), "{rank}", strconv.Itoa(rank))
+
+		values = append(values, "(?, ?, 'reward', 'system', ?, 'specific', ?, 'sent', NOW(), ?, 1, 'system')")
+		args = append(args, replacer.Replace(title), replacer.Replace(content), string(targets), string(tier.Rewards), expireTime)
+		sent++
+
+		if len(values) >= seasonArchiveBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if sent > 0 {
+		logs.Info("赛季奖励邮件已发放: appId=%s, type=%s, season=%d, count=%d", appId, config.LeaderboardType, season, sent)
+	}
+
+	return nil
+}
@@ -0,0 +1,361 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"game-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+// seasonArchiveBatchSize 归档赛季排名时每批插入的行数
+const seasonArchiveBatchSize = 500
+
+// getLeaderboardHistoryTableName 获取排行榜赛季历史表名
+func getLeaderboardHistoryTableName(appId string) string {
+	return utils.GetLeaderboardHistoryTableName(appId)
+}
+
+// LeaderboardSeasonEntry 赛季历史排名条目 - 对应leaderboard_history_[appid]表
+type LeaderboardSeasonEntry struct {
+	Season    int                    `json:"season"`
+	Type      string                 `json:"type"`
+	Partition string                 `json:"partition,omitempty"`
+	UserId    string                 `json:"playerId"`
+	Score     int64                  `json:"score"`
+	Rank      int                    `json:"rank"`
+	ExtraData string                 `json:"extraData,omitempty"`
+	UserInfo  map[string]interface{} `json:"userInfo,omitempty"`
+}
+
+// LeaderboardSeason 赛季信息
+type LeaderboardSeason struct {
+	Season      int                      `json:"season"`
+	SeasonStart string                   `json:"seasonStart"`
+	SeasonEnd   string                   `json:"seasonEnd"`
+	PlayerCount int64                    `json:"playerCount"`
+	TopList     []LeaderboardSeasonEntry `json:"topList,omitempty"`
+}
+
+// archiveLeaderboardSeason 将排行榜当前排名归档为新赛季并发放赛季奖励，返回赛季号、涉及的分区和带名次的排名数据
+// 所有分区共用同一赛季号，名次在各分区内独立计算；每批归档和对应的奖励邮件在同一个短事务中写入，
+// 结算中断后重新执行时沿用同一结束时间的赛季号，并跳过已归档的玩家，不会重复发放奖励
+func archiveLeaderboardSeason(appId, leaderboardName string, config *LeaderboardConfig, seasonEnd time.Time) (int, []string, []orm.Params, error) {
+	o := orm.NewOrm()
+	tableName := getLeaderboardTableName(appId)
+	historyTableName := getLeaderboardHistoryTableName(appId)
+
+	// 1. 计算赛季号，上一赛季结束时间即本赛季开始时间
+	season, archived, err := resumeLeaderboardSeason(o, historyTableName, leaderboardName, seasonEnd)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("查询赛季信息失败: %v", err)
+	}
+
+	var lastInfo []orm.Params
+	_, err = o.Raw(fmt.Sprintf(`SELECT MAX(season) as season, MAX(season_end) as season_end FROM %s WHERE type = ? AND season_end < ?`, historyTableName),
+		leaderboardName, seasonEnd).Values(&lastInfo)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("查询赛季信息失败: %v", err)
+	}
+
+	var seasonStart interface{}
+	if len(lastInfo) > 0 {
+		if season == 0 {
+			season = int(paramToInt64(lastInfo[0]["season"])) + 1
+		}
+		if t := paramToTime(lastInfo[0]["season_end"]); !t.IsZero() {
+			seasonStart = t
+		}
+	}
+	if season == 0 {
+		season = 1
+	}
+
+	// 2. 读取当前排名（结算期间排行榜拒绝提交分数，无需锁表），按分区分组后计算分区内名次
+	var rows []orm.Params
+	_, err = o.Raw(fmt.Sprintf(`
+		SELECT partition_key, player_id, score, extra_data
+		FROM %s
+		WHERE type = ?
+		ORDER BY partition_key ASC, score %s, updated_at ASC, id ASC
+	`, tableName, getScoreOrder(isScoreAscending(config))), leaderboardName).Values(&rows)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("读取排行榜数据失败: %v", err)
+	}
+
+	var partitions []string
+	pending := make([]orm.Params, 0, len(rows))
+	rank := 0
+	for i, row := range rows {
+		partition, _ := row["partition_key"].(string)
+		if i == 0 || partition != partitions[len(partitions)-1] {
+			partitions = append(partitions, partition)
+			rank = 0
+		}
+		rank++
+		row["ranking"] = rank
+
+		playerId, _ := row["player_id"].(string)
+		if !archived[partition+"\x00"+playerId] {
+			pending = append(pending, row)
+		}
+	}
+	if len(partitions) == 0 {
+		partitions = []string{""}
+	}
+
+	// 3. 分批写入历史表并发放赛季奖励邮件
+	for start := 0; start < len(pending); start += seasonArchiveBatchSize {
+		end := start + seasonArchiveBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		if err = archiveSeasonBatch(o, historyTableName, appId, leaderboardName, config, season, seasonStart, seasonEnd, pending[start:end]); err != nil {
+			return 0, nil, nil, err
+		}
+	}
+
+	return season, partitions, rows, nil
+}
+
+// resumeLeaderboardSeason 查找同一结束时间已归档的赛季（上次结算中断），返回赛季号和已归档的玩家，没有时赛季号为0
+func resumeLeaderboardSeason(o orm.Ormer, historyTableName, leaderboardName string, seasonEnd time.Time) (int, map[string]bool, error) {
+	archived := make(map[string]bool)
+
+	var current []orm.Params
+	_, err := o.Raw(fmt.Sprintf(`SELECT MAX(season) as season FROM %s WHERE type = ? AND season_end = ?`, historyTableName),
+		leaderboardName, seasonEnd).Values(&current)
+	if err != nil || len(current) == 0 {
+		return 0, archived, err
+	}
+
+	season := int(paramToInt64(current[0]["season"]))
+	if season == 0 {
+		return 0, archived, nil
+	}
+
+	var done []orm.Params
+	_, err = o.Raw(fmt.Sprintf(`SELECT partition_key, player_id FROM %s WHERE type = ? AND season = ?`, historyTableName),
+		leaderboardName, season).Values(&done)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, row := range done {
+		partition, _ := row["partition_key"].(string)
+		playerId, _ := row["player_id"].(string)
+		archived[partition+"\x00"+playerId] = true
+	}
+
+	return season, archived, nil
+}
+
+// archiveSeasonBatch 在一个事务中归档一批排名并发放对应的赛季奖励邮件
+func archiveSeasonBatch(o orm.Ormer, historyTableName, appId, leaderboardName string, config *LeaderboardConfig, season int, seasonStart interface{}, seasonEnd time.Time, rows []orm.Params) error {
+	values := make([]string, 0, len(rows))
+	args := make([]interface{}, 0, len(rows)*9)
+	for _, row := range rows {
+		values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		args = append(args, leaderboardName, season, row["partition_key"], row["player_id"], paramToInt64(row["score"]),
+			row["ranking"], row["extra_data"], seasonStart, seasonEnd)
+	}
+
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+
+	insertSQL := fmt.Sprintf(`
+		INSERT INTO %s (type, season, partition_key, player_id, score, ranking, extra_data, season_start, season_end)
+		VALUES %s
+	`, historyTableName, strings.Join(values, ", "))
+	if _, err = tx.Raw(insertSQL, args...).Exec(); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("归档赛季排名失败: %v", err)
+	}
+
+	if err = sendSeasonRewardMails(tx, appId, config, season, rows); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("发放赛季奖励失败: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// resolveLeaderboardSeason 解析赛季号，season<=0时返回最近一个已结束的赛季，没有历史赛季返回0
+func resolveLeaderboardSeason(o orm.Ormer, appId, leaderboardName string, season int) (int, error) {
+	if season > 0 {
+		return season, nil
+	}
+
+	var latest []orm.Params
+	_, err := o.Raw(fmt.Sprintf(`SELECT MAX(season) as season FROM %s WHERE type = ?`, getLeaderboardHistoryTableName(appId)),
+		leaderboardName).Values(&latest)
+	if err != nil {
+		return 0, err
+	}
+	if len(latest) == 0 {
+		return 0, nil
+	}
+
+	return int(paramToInt64(latest[0]["season"])), nil
+}
+
+// GetLeaderboardSeasonRank 获取历史赛季指定分区的排名前limit名，season<=0表示上一赛季
+func GetLeaderboardSeasonRank(appId, leaderboardName, partition string, season, limit int) (int, []LeaderboardSeasonEntry, error) {
+	o := orm.NewOrm()
+
+	season, err := resolveLeaderboardSeason(o, appId, leaderboardName, season)
+	if err != nil {
+		return 0, nil, err
+	}
+	if season == 0 {
+		return 0, []LeaderboardSeasonEntry{}, nil
+	}
+
+	var results []orm.Params
+	_, err = o.Raw(fmt.Sprintf(`
+		SELECT season, type, partition_key, player_id as user_id, score, ranking, extra_data
+		FROM %s
+		WHERE type = ? AND season = ? AND partition_key = ?
+		ORDER BY ranking ASC
+		LIMIT ?
+	`, getLeaderboardHistoryTableName(appId)), leaderboardName, season, partition, limit).Values(&results)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return season, convertSeasonRows(appId, results), nil
+}
+
+// GetUserSeasonRank 获取玩家在历史赛季指定分区中的排名，未上榜时返回nil
+func GetUserSeasonRank(appId, userId, leaderboardName, partition string, season int) (*LeaderboardSeasonEntry, error) {
+	o := orm.NewOrm()
+
+	season, err := resolveLeaderboardSeason(o, appId, leaderboardName, season)
+	if err != nil || season == 0 {
+		return nil, err
+	}
+
+	var results []orm.Params
+	_, err = o.Raw(fmt.Sprintf(`
+		SELECT season, type, partition_key, player_id as user_id, score, ranking, extra_data
+		FROM %s
+		WHERE type = ? AND season = ? AND partition_key = ? AND player_id = ?
+	`, getLeaderboardHistoryTableName(appId)), leaderboardName, season, partition, userId).Values(&results)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	entries := convertSeasonRows(appId, results)
+	return &entries[0], nil
+}
+
+// GetLeaderboardHallOfFame 获取指定分区的名人堂：最近seasonCount个赛季各自的前top名
+func GetLeaderboardHallOfFame(appId, leaderboardName, partition string, seasonCount, top int) ([]LeaderboardSeason, error) {
+	o := orm.NewOrm()
+	historyTableName := getLeaderboardHistoryTableName(appId)
+
+	var seasonRows []orm.Params
+	_, err := o.Raw(fmt.Sprintf(`
+		SELECT season, MIN(season_start) as season_start, MAX(season_end) as season_end, COUNT(*) as player_count
+		FROM %s
+		WHERE type = ? AND partition_key = ?
+		GROUP BY season
+		ORDER BY season DESC
+		LIMIT ?
+	`, historyTableName), leaderboardName, partition, seasonCount).Values(&seasonRows)
+	if err != nil {
+		return nil, err
+	}
+
+	seasons := make([]LeaderboardSeason, 0, len(seasonRows))
+	if len(seasonRows) == 0 {
+		return seasons, nil
+	}
+
+	// 一次查询所有赛季的前top名
+	seasonIds := make([]interface{}, 0, len(seasonRows))
+	for _, row := range seasonRows {
+		seasonIds = append(seasonIds, paramToInt64(row["season"]))
+	}
+
+	var topRows []orm.Params
+	args := append([]interface{}{leaderboardName, partition}, seasonIds...)
+	args = append(args, top)
+	_, err = o.Raw(fmt.Sprintf(`
+		SELECT season, type, partition_key, player_id as user_id, score, ranking, extra_data
+		FROM %s
+		WHERE type = ? AND partition_key = ? AND season IN (%s) AND ranking <= ?
+		ORDER BY season DESC, ranking ASC
+	`, historyTableName, utils.BuildPlaceholders(len(seasonIds))), args...).Values(&topRows)
+	if err != nil {
+		return nil, err
+	}
+
+	topMap := make(map[int][]LeaderboardSeasonEntry)
+	for _, entry := range convertSeasonRows(appId, topRows) {
+		topMap[entry.Season] = append(topMap[entry.Season], entry)
+	}
+
+	for _, row := range seasonRows {
+		season := LeaderboardSeason{
+			Season:      int(paramToInt64(row["season"])),
+			PlayerCount: paramToInt64(row["player_count"]),
+		}
+		if t := paramToTime(row["season_start"]); !t.IsZero() {
+			season.SeasonStart = t.Format("2006-01-02 15:04:05")
+		}
+		if t := paramToTime(row["season_end"]); !t.IsZero() {
+			season.SeasonEnd = t.Format("2006-01-02 15:04:05")
+		}
+		season.TopList = topMap[season.Season]
+		if season.TopList == nil {
+			season.TopList = []LeaderboardSeasonEntry{}
+		}
+		seasons = append(seasons, season)
+	}
+
+	return seasons, nil
+}
+
+// convertSeasonRows 将历史表查询结果转换为赛季排名条目，并填充用户信息
+func convertSeasonRows(appId string, results []orm.Params) []LeaderboardSeasonEntry {
+	userInfoMap, err := getUserInfoMapForLeaderboard(appId, results)
+	if err != nil {
+		userInfoMap = make(map[string]map[string]interface{})
+	}
+
+	entries := make([]LeaderboardSeasonEntry, 0, len(results))
+	for _, result := range results {
+		entry := LeaderboardSeasonEntry{
+			Season: int(paramToInt64(result["season"])),
+			Score:  paramToInt64(result["score"]),
+			Rank:   int(paramToInt64(result["ranking"])),
+		}
+		if leaderboardType, ok := result["type"].(string); ok {
+			entry.Type = leaderboardType
+		}
+		if partition, ok := result["partition_key"].(string); ok {
+			entry.Partition = partition
+		}
+		if userId, ok := result["user_id"].(string); ok {
+			entry.UserId = userId
+			if userInfo, exists := userInfoMap[userId]; exists {
+				entry.UserInfo = userInfo
+			} else {
+				entry.UserInfo = map[string]interface{}{}
+			}
+		}
+		if extraData, ok := result["extra_data"].(string); ok {
+			entry.ExtraData = extraData
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
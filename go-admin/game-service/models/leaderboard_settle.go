@@ -0,0 +1,247 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"game-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/config"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/go-redis/redis/v8"
+)
+
+// 排行榜赛季结算相关Redis键
+const (
+	leaderboardSettleQueueKey   = "leaderboard_settle:queue"    // 玩家请求发现排行榜到期后提交的结算请求队列
+	leaderboardSettlePendingKey = "leaderboard_settle:pending:" // 已提交未处理的结算请求，后接appId:type，避免重复入队
+	leaderboardSettleLockKey    = "leaderboard_settle:lock:"    // 单个排行榜的结算锁，后接appId:type
+)
+
+const (
+	// leaderboardSettleLockTTL 结算锁有效期，需大于单个排行榜结算的最长耗时
+	leaderboardSettleLockTTL = 30 * time.Minute
+	// leaderboardSettlePendingTTL 结算请求去重标记有效期，超时后允许重新入队
+	leaderboardSettlePendingTTL = time.Minute
+)
+
+// ErrLeaderboardSettling 排行榜已到重置时间、赛季正在结算
+var ErrLeaderboardSettling = errors.New("排行榜赛季结算中，请稍后再试")
+
+// leaderboardSettleRequest 排行榜赛季结算请求
+type leaderboardSettleRequest struct {
+	AppId string `json:"appId"`
+	Type  string `json:"type"`
+}
+
+// leaderboardSettleUnlockScript 仅释放自己持有的结算锁
+var leaderboardSettleUnlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// StartLeaderboardSettler 启动排行榜赛季结算后台任务
+// 按leaderboard_settle_interval（秒，默认60，0表示关闭）定时扫描到期的排行榜，同时处理玩家请求触发的结算请求
+func StartLeaderboardSettler() {
+	appconf, _ := config.NewConfig("ini", "conf/app.conf")
+	interval := 60
+	if appconf != nil {
+		interval = appconf.DefaultInt("leaderboard_settle_interval", 60)
+	}
+
+	if interval > 0 {
+		go runScheduledSettle(time.Duration(interval) * time.Second)
+	}
+	if RedisClient != nil {
+		go consumeSettleRequests()
+	}
+
+	logs.Info("排行榜赛季结算任务已启动: interval=%ds", interval)
+}
+
+// requestLeaderboardSettle 提交排行榜赛季结算请求（同一排行榜未处理的请求只保留一个）
+// Redis不可用时由定时扫描结算
+func requestLeaderboardSettle(appId, leaderboardName string) {
+	if RedisClient == nil {
+		return
+	}
+	ctx := context.Background()
+
+	id := appId + ":" + leaderboardName
+	added, err := RedisClient.SetNX(ctx, leaderboardSettlePendingKey+id, time.Now().Unix(), leaderboardSettlePendingTTL).Result()
+	if err != nil || !added {
+		return
+	}
+
+	data, _ := json.Marshal(leaderboardSettleRequest{AppId: appId, Type: leaderboardName})
+	if err := RedisClient.LPush(ctx, leaderboardSettleQueueKey, data).Err(); err != nil {
+		logs.Warn("提交排行榜结算请求失败: appId=%s, type=%s, err=%v", appId, leaderboardName, err)
+		RedisClient.Del(ctx, leaderboardSettlePendingKey+id)
+	}
+}
+
+// runScheduledSettle 定时结算所有已到重置时间的排行榜（没有玩家访问的排行榜也能按时发放奖励）
+func runScheduledSettle(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var boards []orm.Params
+		_, err := orm.NewOrm().Raw(`SELECT app_id, leaderboard_type FROM leaderboard_config WHERE reset_type <> 'permanent' AND reset_time IS NOT NULL AND reset_time <= NOW()`).Values(&boards)
+		if err != nil {
+			logs.Warn("查询到期排行榜失败: %v", err)
+			continue
+		}
+
+		for _, board := range boards {
+			appId, _ := board["app_id"].(string)
+			leaderboardName, _ := board["leaderboard_type"].(string)
+			runLeaderboardSettle(appId, leaderboardName)
+		}
+	}
+}
+
+// consumeSettleRequests 处理玩家请求触发的结算请求
+func consumeSettleRequests() {
+	ctx := context.Background()
+	for {
+		result, err := RedisClient.BRPop(ctx, 30*time.Second, leaderboardSettleQueueKey).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			logs.Warn("读取排行榜结算请求失败: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		var req leaderboardSettleRequest
+		if err := json.Unmarshal([]byte(result[1]), &req); err != nil || req.AppId == "" || req.Type == "" {
+			logs.Warn("排行榜结算请求格式错误: %s", result[1])
+			continue
+		}
+
+		runLeaderboardSettle(req.AppId, req.Type)
+		RedisClient.Del(ctx, leaderboardSettlePendingKey+req.AppId+":"+req.Type)
+	}
+}
+
+// runLeaderboardSettle 结算到期的排行榜并记录结果
+func runLeaderboardSettle(appId, leaderboardName string) {
+	season, err := settleLeaderboardSeason(appId, leaderboardName, true)
+	if err != nil {
+		logs.Error("排行榜赛季结算失败: appId=%s, type=%s, err=%v", appId, leaderboardName, err)
+		return
+	}
+	if season > 0 {
+		logs.Info("排行榜重置完成: appId=%s, type=%s, 归档赛季=%d", appId, leaderboardName, season)
+	}
+}
+
+// settleLeaderboardSeason 结算排行榜赛季：分批归档排名并发放奖励，然后执行联赛晋降级并清空数据
+// scheduled为true时只结算已到重置时间的排行榜，并在清空数据的同一事务中更新下次重置时间；
+// 多实例部署时通过每个排行榜独立的Redis锁保证同一时间只有一个实例结算，返回归档的赛季号，未结算时返回0
+func settleLeaderboardSeason(appId, leaderboardName string, scheduled bool) (int, error) {
+	unlock, locked, err := lockLeaderboardSettle(appId, leaderboardName)
+	if err != nil {
+		return 0, err
+	}
+	if !locked {
+		if scheduled {
+			return 0, nil
+		}
+		return 0, ErrLeaderboardSettling
+	}
+	defer unlock()
+
+	config, err := getLeaderboardConfig(appId, leaderboardName)
+	if err != nil {
+		return 0, err
+	}
+
+	seasonEnd := time.Now()
+	if scheduled {
+		if config.ResetType == "permanent" || config.ResetTime.IsZero() || !seasonEnd.After(config.ResetTime) {
+			return 0, nil
+		}
+		seasonEnd = config.ResetTime
+	}
+
+	season, partitions, rows, err := archiveLeaderboardSeason(appId, leaderboardName, config, seasonEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := orm.NewOrm().Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	if scheduled {
+		// 没有下次重置时间时置空，避免重复结算
+		var next interface{}
+		if nextResetTime := calculateNextResetTime(config.ResetType, config.ResetValue); nextResetTime != nil {
+			next = *nextResetTime
+		}
+
+		res, err := tx.Raw(`UPDATE leaderboard_config SET reset_time = ?, last_reset_time = NOW(), updated_at = NOW() WHERE app_id = ? AND leaderboard_type = ? AND reset_time = ?`,
+			next, appId, leaderboardName, config.ResetTime).Exec()
+		if err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("更新重置时间失败: %v", err)
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			tx.Rollback()
+			return 0, nil
+		}
+	}
+
+	// 联赛排行榜按分组名次晋级/降级
+	if err = settleLeagueSeason(tx, appId, leaderboardName, config, rows); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("联赛晋降级失败: %v", err)
+	}
+
+	// 清空本赛季数据
+	if _, err = tx.Raw(fmt.Sprintf(`DELETE FROM %s WHERE type = ?`, getLeaderboardTableName(appId)), leaderboardName).Exec(); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("清空排行榜数据失败: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	clearLeaderboardRedis(appId, leaderboardName, partitions)
+	return season, nil
+}
+
+// lockLeaderboardSettle 获取排行榜结算锁，返回释放函数；Redis不可用时视为单实例部署直接返回成功
+func lockLeaderboardSettle(appId, leaderboardName string) (func(), bool, error) {
+	if RedisClient == nil {
+		return func() {}, true, nil
+	}
+	ctx := context.Background()
+
+	key := leaderboardSettleLockKey + appId + ":" + leaderboardName
+	token := utils.GenerateRandomString(16)
+	locked, err := RedisClient.SetNX(ctx, key, token, leaderboardSettleLockTTL).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("获取排行榜结算锁失败: %v", err)
+	}
+	if !locked {
+		return nil, false, nil
+	}
+
+	return func() {
+		if err := leaderboardSettleUnlockScript.Run(ctx, RedisClient, []string{key}, token).Err(); err != nil && err != redis.Nil {
+			logs.Warn("释放排行榜结算锁失败: key=%s, err=%v", key, err)
+		}
+	}, true, nil
+}
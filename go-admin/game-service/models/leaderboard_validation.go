@@ -0,0 +1,205 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"game-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// LeaderboardValidationRules 排行榜分数校验规则（存储在leaderboard_config.validation_rules）
+type LeaderboardValidationRules struct {
+	MinScore            *int64 `json:"minScore,omitempty"`            // 允许的最小分数
+	MaxScore            *int64 `json:"maxScore,omitempty"`            // 允许的最大分数
+	MaxIncrease         int64  `json:"maxIncrease,omitempty"`         // 单次提交最大提升幅度（累计分排行榜为单次最大增量），0表示不限制
+	MaxSubmitsPerMinute int    `json:"maxSubmitsPerMinute,omitempty"` // 每个玩家每分钟最大提交次数，0表示不限制
+	RequireProof        bool   `json:"requireProof,omitempty"`        // 是否要求extraData中携带签名proof
+	ProofSecret         string `json:"proofSecret,omitempty"`         // proof签名密钥（HMAC-SHA256）
+	ProofMaxAge         int64  `json:"proofMaxAge,omitempty"`         // proof有效期（秒），0表示使用默认值300
+}
+
+// defaultProofMaxAge proof默认有效期（秒）
+const defaultProofMaxAge = 300
+
+// ScoreValidationError 分数未通过校验规则
+type ScoreValidationError struct {
+	Rule   string // 触发的规则
+	Reason string // 具体原因
+}
+
+func (e *ScoreValidationError) Error() string {
+	return fmt.Sprintf("分数校验未通过: %s", e.Reason)
+}
+
+// parseValidationRules 解析排行榜校验规则，未配置时返回nil
+func parseValidationRules(rules string) (*LeaderboardValidationRules, error) {
+	if strings.TrimSpace(rules) == "" {
+		return nil, nil
+	}
+
+	var parsed LeaderboardValidationRules
+	if err := json.Unmarshal([]byte(rules), &parsed); err != nil {
+		return nil, fmt.Errorf("分数校验规则格式错误: %v", err)
+	}
+
+	return &parsed, nil
+}
+
+// GenerateScoreProof 生成分数签名proof
+// 格式: hex(HMAC-SHA256(proofSecret, appId|playerId|type|score|proofTs|nonce))，
+// 客户端需按相同规则生成并与proofTs（秒级时间戳）、nonce（每次提交唯一的随机串）一起放入extraData
+func GenerateScoreProof(appId, playerId, leaderboardName string, score, proofTs int64, nonce, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%s|%s|%s|%d|%d|%s", appId, playerId, leaderboardName, score, proofTs, nonce)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyScoreProof 校验extraData中的分数签名proof，过期或已使用过的proof视为无效
+func verifyScoreProof(appId, userId, leaderboardName string, rules *LeaderboardValidationRules, score int64, extraData string) *ScoreValidationError {
+	var extra map[string]interface{}
+	if json.Unmarshal([]byte(extraData), &extra) != nil {
+		return &ScoreValidationError{Rule: "requireProof", Reason: "缺少分数签名proof"}
+	}
+	proof, _ := extra["proof"].(string)
+	nonce, _ := extra["nonce"].(string)
+	var proofTs int64
+	switch v := extra["proofTs"].(type) {
+	case float64:
+		proofTs = int64(v)
+	case string:
+		proofTs, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if proof == "" || nonce == "" || proofTs <= 0 {
+		return &ScoreValidationError{Rule: "requireProof", Reason: "缺少分数签名proof、proofTs或nonce"}
+	}
+
+	maxAge := rules.ProofMaxAge
+	if maxAge <= 0 {
+		maxAge = defaultProofMaxAge
+	}
+	if age := time.Now().Unix() - proofTs; age > maxAge || age < -maxAge {
+		return &ScoreValidationError{Rule: "requireProof", Reason: "分数签名proof已过期"}
+	}
+
+	expected := GenerateScoreProof(appId, userId, leaderboardName, score, proofTs, nonce, rules.ProofSecret)
+	if !hmac.Equal([]byte(strings.ToLower(proof)), []byte(expected)) {
+		return &ScoreValidationError{Rule: "requireProof", Reason: "分数签名proof无效"}
+	}
+
+	// 有效期内同一nonce只能使用一次（依赖Redis，Redis不可用时只校验有效期）
+	if RedisClient != nil {
+		key := fmt.Sprintf("leaderboard_proof:%s:%s:%s:%s", appId, leaderboardName, userId, nonce)
+		added, err := RedisClient.SetNX(RedisClient.Context(), key, proofTs, time.Duration(maxAge*2)*time.Second).Result()
+		if err != nil {
+			logs.Warn("检查proof重放失败: %v", err)
+		} else if !added {
+			return &ScoreValidationError{Rule: "requireProof", Reason: "分数签名proof已被使用"}
+		}
+	}
+
+	return nil
+}
+
+// validateScoreSubmission 按排行榜配置的规则校验提交的分数
+// 校验失败时记录可疑分数并返回 *ScoreValidationError
+func validateScoreSubmission(appId, userId, leaderboardName string, config *LeaderboardConfig, score, oldScore int64, hasRecord bool, extraData string) error {
+	rules, err := parseValidationRules(config.ValidationRules)
+	if err != nil {
+		// 规则配置错误不影响正常提交
+		logs.Error("排行榜校验规则无效: appId=%s, type=%s, err=%v", appId, leaderboardName, err)
+		return nil
+	}
+	if rules == nil {
+		return nil
+	}
+
+	violation := checkScoreRules(appId, userId, leaderboardName, config, rules, score, oldScore, hasRecord, extraData)
+	if violation == nil {
+		return nil
+	}
+
+	if err := recordSuspiciousScore(appId, userId, leaderboardName, score, oldScore, extraData, violation); err != nil {
+		logs.Error("记录可疑分数失败: %v", err)
+	}
+	logs.Warn("拒绝可疑分数: appId=%s, type=%s, playerId=%s, score=%d, rule=%s, reason=%s",
+		appId, leaderboardName, userId, score, violation.Rule, violation.Reason)
+
+	return violation
+}
+
+// checkScoreRules 逐条检查校验规则，返回第一个违规项
+func checkScoreRules(appId, userId, leaderboardName string, config *LeaderboardConfig, rules *LeaderboardValidationRules, score, oldScore int64, hasRecord bool, extraData string) *ScoreValidationError {
+	// 1. 分数范围
+	if rules.MinScore != nil && score < *rules.MinScore {
+		return &ScoreValidationError{Rule: "minScore", Reason: fmt.Sprintf("分数%d低于最小值%d", score, *rules.MinScore)}
+	}
+	if rules.MaxScore != nil && score > *rules.MaxScore {
+		return &ScoreValidationError{Rule: "maxScore", Reason: fmt.Sprintf("分数%d超过最大值%d", score, *rules.MaxScore)}
+	}
+
+	// 2. 单次提升幅度
+	if rules.MaxIncrease > 0 {
+		var increase int64
+		switch {
+		case config.UpdateStrategy == 2:
+			increase = score // 累计分：提交的分数即为增量
+		case !hasRecord:
+			increase = 0 // 首次提交只受分数范围限制
+		case isScoreAscending(config):
+			increase = oldScore - score
+		default:
+			increase = score - oldScore
+		}
+		if increase > rules.MaxIncrease {
+			return &ScoreValidationError{Rule: "maxIncrease", Reason: fmt.Sprintf("单次提升%d超过上限%d", increase, rules.MaxIncrease)}
+		}
+	}
+
+	// 3. 签名proof
+	if rules.RequireProof {
+		if violation := verifyScoreProof(appId, userId, leaderboardName, rules, score, extraData); violation != nil {
+			return violation
+		}
+	}
+
+	// 4. 提交频率（依赖Redis，Redis不可用时跳过）
+	if rules.MaxSubmitsPerMinute > 0 && RedisClient != nil {
+		ctx := RedisClient.Context()
+		key := fmt.Sprintf("leaderboard_submit:%s:%s:%s:%d", appId, leaderboardName, userId, time.Now().Unix()/60)
+		count, err := RedisClient.Incr(ctx, key).Result()
+		if err != nil {
+			logs.Warn("检查提交频率失败: %v", err)
+		} else {
+			if count == 1 {
+				RedisClient.Expire(ctx, key, 2*time.Minute)
+			}
+			if count > int64(rules.MaxSubmitsPerMinute) {
+				return &ScoreValidationError{Rule: "maxSubmitsPerMinute", Reason: fmt.Sprintf("每分钟提交次数超过上限%d", rules.MaxSubmitsPerMinute)}
+			}
+		}
+	}
+
+	return nil
+}
+
+// recordSuspiciousScore 记录被拒绝的可疑分数，供管理后台审核
+func recordSuspiciousScore(appId, userId, leaderboardName string, score, oldScore int64, extraData string, violation *ScoreValidationError) error {
+	o := orm.NewOrm()
+
+	sql := fmt.Sprintf(`
+		INSERT INTO %s (type, player_id, score, old_score, extra_data, rule, reason, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 0, NOW())
+	`, utils.GetLeaderboardSuspiciousTableName(appId))
+
+	_, err := o.Raw(sql, leaderboardName, userId, score, oldScore, extraData, violation.Rule, violation.Reason).Exec()
+	return err
+}
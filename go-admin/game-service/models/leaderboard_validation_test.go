@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestVerifyScoreProof 分数签名proof校验测试（不依赖Redis，只校验签名和有效期）
+func TestVerifyScoreProof(t *testing.T) {
+	redisClient := RedisClient
+	RedisClient = nil
+	t.Cleanup(func() { RedisClient = redisClient })
+
+	rules := &LeaderboardValidationRules{RequireProof: true, ProofSecret: "secret", ProofMaxAge: 60}
+	now := time.Now().Unix()
+
+	extraData := func(proof string, proofTs interface{}, nonce string) string {
+		data, _ := json.Marshal(map[string]interface{}{"proof": proof, "proofTs": proofTs, "nonce": nonce})
+		return string(data)
+	}
+	validProof := GenerateScoreProof("app", "player", "level", 100, now, "n1", "secret")
+
+	tests := []struct {
+		name      string
+		score     int64
+		extraData string
+		reason    string // 期望的错误原因，为空表示校验通过
+	}{
+		{"签名正确", 100, extraData(validProof, now, "n1"), ""},
+		{"签名大写", 100, extraData(strings.ToUpper(validProof), now, "n1"), ""},
+		{"时间戳为字符串", 100, extraData(validProof, "1", "n1"), "已过期"},
+		{"分数被篡改", 101, extraData(validProof, now, "n1"), "无效"},
+		{"nonce被篡改", 100, extraData(validProof, now, "n2"), "无效"},
+		{"密钥错误", 100, extraData(GenerateScoreProof("app", "player", "level", 100, now, "n1", "other"), now, "n1"), "无效"},
+		{"proof已过期", 100, extraData(GenerateScoreProof("app", "player", "level", 100, now-61, "n1", "secret"), now-61, "n1"), "已过期"},
+		{"时间戳超前", 100, extraData(GenerateScoreProof("app", "player", "level", 100, now+61, "n1", "secret"), now+61, "n1"), "已过期"},
+		{"缺少nonce", 100, extraData(validProof, now, ""), "缺少"},
+		{"缺少proof", 100, `{"level":3}`, "缺少"},
+		{"extraData不是JSON", 100, "not json", "缺少"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violation := verifyScoreProof("app", "player", "level", rules, tt.score, tt.extraData)
+			if tt.reason == "" {
+				if violation != nil {
+					t.Fatalf("应校验通过，实际为: %s", violation.Reason)
+				}
+				return
+			}
+			if violation == nil || !strings.Contains(violation.Reason, tt.reason) {
+				t.Fatalf("应返回包含%q的错误，实际为: %v", tt.reason, violation)
+			}
+			if violation.Rule != "requireProof" {
+				t.Errorf("规则应为requireProof，实际为%s", violation.Rule)
+			}
+		})
+	}
+}
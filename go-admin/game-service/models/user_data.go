@@ -1,8 +1,8 @@
 package models
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,6 +12,9 @@ import (
 	"github.com/beego/beego/v2/core/logs"
 )
 
+// ErrUserDataConflict 保存游戏数据时版本号与服务器不一致（其他设备已保存过）
+var ErrUserDataConflict = errors.New("游戏数据已在其他设备更新")
+
 // User 用户模型 - 按照数据库设计.md的正确结构
 type User struct {
 	ID            int64     `orm:"pk;auto" json:"id"`
@@ -22,6 +25,7 @@ type User struct {
 	Nickname      string    `orm:"size(100)" json:"nickname"`                                             // 昵称
 	Avatar        string    `orm:"size(500)" json:"avatar"`                                               // 头像URL
 	Data          string    `orm:"type(longtext)" json:"data"`                                            // 游戏数据（JSON格式）
+	DataVersion   int64     `orm:"default(0);column(data_version)" json:"dataVersion"`                    // 游戏数据版本号（每次保存加1）
 	Level         int       `orm:"default(1)" json:"level"`                                               // 等级
 	Exp           int64     `orm:"default(0)" json:"exp"`                                                 // 经验值
 	Coin          int64     `orm:"default(0)" json:"coin"`                                                // 金币
@@ -95,8 +99,11 @@ func GetUserByPlayerId(appId, playerId string) (*User, error) {
 
 // CreateUser 创建新用户
 func CreateUser(appId string, user *User) error {
-	o := orm.NewOrm()
+	return createUser(orm.NewOrm(), appId, user)
+}
 
+// createUser 在指定连接（或事务）中创建新用户
+func createUser(o orm.QueryExecutor, appId string, user *User) error {
 	tableName := utils.GetUserTableName(appId)
 	user.AppId = appId
 
@@ -131,7 +138,7 @@ func CreateUser(appId string, user *User) error {
 	return nil
 }
 
-// UpdateUser 更新用户信息
+// UpdateUser 更新用户信息（不包括游戏数据，游戏数据通过SaveUserDataWithVersion保存，避免覆盖其他设备的存档）
 func UpdateUser(appId string, user *User) error {
 	o := orm.NewOrm()
 
@@ -139,14 +146,14 @@ func UpdateUser(appId string, user *User) error {
 
 	sql := fmt.Sprintf(`
 		UPDATE %s SET 
-			token = ?, nickname = ?, avatar = ?, data = ?, level = ?, exp = ?, coin = ?, diamond = ?, 
+			token = ?, nickname = ?, avatar = ?, level = ?, exp = ?, coin = ?, diamond = ?, 
 			vip_level = ?, banned = ?, ban_reason = ?, ban_expire = ?, login_count = ?, last_login_time = ?, 
 			last_login_ip = ?, updated_at = NOW()
 		WHERE player_id = ?
 	`, tableName)
 
 	_, err := o.Raw(sql,
-		user.Token, user.Nickname, user.Avatar, user.Data, user.Level, user.Exp, user.Coin, user.Diamond,
+		user.Token, user.Nickname, user.Avatar, user.Level, user.Exp, user.Coin, user.Diamond,
 		user.VipLevel, user.Banned, user.BanReason, user.BanExpire, user.LoginCount, user.LastLoginTime,
 		user.LastLoginIp, user.PlayerId,
 	).Exec()
@@ -159,45 +166,86 @@ func UpdateUser(appId string, user *User) error {
 	return nil
 }
 
-// SaveUserData 保存用户游戏数据
+// SaveUserData 保存用户游戏数据（不校验版本号）
 func SaveUserData(appId, playerId string, data string) error {
+	_, err := SaveUserDataWithVersion(appId, playerId, data, nil, "")
+	return err
+}
+
+// SaveUserDataWithVersion 保存用户游戏数据并返回新的版本号，同时记录存档历史
+// expectedVersion不为空时只有服务器版本号与之相同才会保存，否则返回ErrUserDataConflict
+func SaveUserDataWithVersion(appId, playerId string, data string, expectedVersion *int64, ip string) (int64, error) {
 	o := orm.NewOrm()
 	tableName := utils.GetUserTableName(appId)
 
-	sql := fmt.Sprintf("UPDATE %s SET data = ?, updated_at = NOW() WHERE player_id = ?", tableName)
-	_, err := o.Raw(sql, data, playerId).Exec()
+	tx, err := o.Begin()
+	if err != nil {
+		return 0, err
+	}
 
+	var rows []orm.Params
+	_, err = tx.Raw(fmt.Sprintf("SELECT data_version FROM %s WHERE player_id = ? FOR UPDATE", tableName), playerId).Values(&rows)
 	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if len(rows) == 0 {
+		tx.Rollback()
+		return 0, fmt.Errorf("用户不存在")
+	}
+
+	version := paramToInt64(rows[0]["data_version"])
+	if expectedVersion != nil && *expectedVersion != version {
+		tx.Rollback()
+		return version, ErrUserDataConflict
+	}
+
+	archiveCurrentUserData(tx, appId, playerId)
+
+	sql := fmt.Sprintf("UPDATE %s SET data = ?, data_version = data_version + 1, updated_at = NOW() WHERE player_id = ?", tableName)
+	if _, err := tx.Raw(sql, data, playerId).Exec(); err != nil {
+		tx.Rollback()
 		logs.Error("保存用户数据失败: %v", err)
-		return err
+		return 0, err
 	}
 
-	return nil
+	recordUserDataHistory(tx, appId, playerId, version+1, data, UserDataSourcePlayer, ip)
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return version + 1, nil
 }
 
 // GetUserData 获取用户游戏数据
 func GetUserData(appId, playerId string) (map[string]interface{}, error) {
+	data, _, err := GetUserDataWithVersion(appId, playerId)
+	return data, err
+}
+
+// GetUserDataWithVersion 获取用户游戏数据及其版本号
+func GetUserDataWithVersion(appId, playerId string) (map[string]interface{}, int64, error) {
 	user, err := GetUserByPlayerId(appId, playerId)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if user == nil {
-		return nil, fmt.Errorf("用户不存在")
+		return nil, 0, fmt.Errorf("用户不存在")
 	}
 
 	if user.Data == "" {
-		return make(map[string]interface{}), nil
+		return make(map[string]interface{}), user.DataVersion, nil
 	}
 
 	var data map[string]interface{}
 	err = json.Unmarshal([]byte(user.Data), &data)
 	if err != nil {
 		logs.Error("反序列化用户数据失败: %v", err)
-		return nil, err
+		return nil, 0, err
 	}
 
-	return data, nil
+	return data, user.DataVersion, nil
 }
 
 // UpdateLoginInfo 更新登录信息
@@ -309,7 +357,7 @@ func ClearUserGameData(appId, playerId string) error {
 	o := orm.NewOrm()
 	tableName := utils.GetUserTableName(appId)
 
-	sql := fmt.Sprintf("UPDATE %s SET data = '{}', updated_at = NOW() WHERE player_id = ?", tableName)
+	sql := fmt.Sprintf("UPDATE %s SET data = '{}', data_version = data_version + 1, updated_at = NOW() WHERE player_id = ?", tableName)
 	_, err := o.Raw(sql, playerId).Exec()
 
 	if err != nil {
@@ -319,15 +367,3 @@ func ClearUserGameData(appId, playerId string) error {
 
 	return nil
 }
-
-// 在redis中保存用户token
-func SaveUserStatusToRedis(appId, playerId, token string) error {
-	RedisClient.Set(context.Background(), fmt.Sprintf("user_token_%s_%s", appId, playerId), token, 0)
-	return nil
-}
-
-// 在redis中获取用户token
-func GetUserStatusFromRedis(appId, playerId string) (string, error) {
-	token, err := RedisClient.Get(context.Background(), fmt.Sprintf("user_token_%s_%s", appId, playerId)).Result()
-	return token, err
-}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"fmt"
+
+	"game-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// 存档历史来源（与管理后台约定一致）
+const (
+	UserDataSourceInitial = "initial" // 开始记录历史前已有的存档
+	UserDataSourcePlayer  = "player"  // 玩家保存
+	UserDataSourcePatch   = "patch"   // 玩家局部更新
+)
+
+// getUserDataHistoryKeep 每个玩家保留的存档历史版本数
+func getUserDataHistoryKeep() int64 {
+	return int64(web.AppConfig.DefaultInt("user_data_history_keep", 20))
+}
+
+// archiveCurrentUserData 保存前把玩家当前存档写入历史（已记录过的版本忽略）
+// 保证开始记录历史之前的存档也能恢复，需要在锁定玩家记录的事务中调用
+// 历史记录失败不影响保存，只记录日志
+func archiveCurrentUserData(o orm.QueryExecutor, appId, playerId string) {
+	if getUserDataHistoryKeep() <= 0 {
+		return
+	}
+
+	sql := fmt.Sprintf(`
+		INSERT IGNORE INTO %s (player_id, version, data, source, ip, created_at)
+		SELECT player_id, data_version, data, ?, '', updated_at FROM %s WHERE player_id = ? AND data IS NOT NULL AND data <> '' AND data <> '{}'
+	`, utils.GetUserDataHistoryTableName(appId), utils.GetUserTableName(appId))
+
+	if _, err := o.Raw(sql, UserDataSourceInitial, playerId).Exec(); err != nil {
+		logs.Warn("记录存档历史失败: appId=%s, playerId=%s, err=%v", appId, playerId, err)
+	}
+}
+
+// recordUserDataHistory 记录新保存的存档版本，并清理超出保留数量的旧版本（失败只记录日志）
+func recordUserDataHistory(o orm.QueryExecutor, appId, playerId string, version int64, data, source, ip string) {
+	keep := getUserDataHistoryKeep()
+	if keep <= 0 {
+		return
+	}
+
+	tableName := utils.GetUserDataHistoryTableName(appId)
+	sql := fmt.Sprintf(`INSERT IGNORE INTO %s (player_id, version, data, source, ip, created_at) VALUES (?, ?, ?, ?, ?, NOW())`, tableName)
+	if _, err := o.Raw(sql, playerId, version, data, source, ip).Exec(); err != nil {
+		logs.Warn("记录存档历史失败: appId=%s, playerId=%s, err=%v", appId, playerId, err)
+		return
+	}
+
+	// 版本号连续递增，只保留最近keep个版本
+	sql = fmt.Sprintf(`DELETE FROM %s WHERE player_id = ? AND version <= ?`, tableName)
+	if _, err := o.Raw(sql, playerId, version-keep).Exec(); err != nil {
+		logs.Warn("清理存档历史失败: appId=%s, playerId=%s, err=%v", appId, playerId, err)
+	}
+}
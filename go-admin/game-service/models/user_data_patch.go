@@ -0,0 +1,336 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"game-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 游戏数据局部更新操作类型
+const (
+	UserDataPatchSet       = "set"
+	UserDataPatchIncrement = "increment"
+	UserDataPatchRemove    = "remove"
+)
+
+// UserDataPatchMaxOps 单次局部更新的最大操作数
+const UserDataPatchMaxOps = 100
+
+// ErrUserDataPatchInvalid 局部更新参数错误
+var ErrUserDataPatchInvalid = errors.New("局部更新参数错误")
+
+// UserDataPatchOp 按路径更新游戏数据的操作，路径以.分隔（如bag.items.0.count），数组使用下标
+type UserDataPatchOp struct {
+	Op    string      `json:"op"` // set/increment/remove
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// PatchUserData 在事务中局部更新用户游戏数据，返回新的版本号和各操作路径更新后的值
+// mergePatch按JSON Merge Patch（RFC 7386）合并，ops在合并后依次执行
+// expectedVersion不为空时只有服务器版本号与之相同才会更新，否则返回ErrUserDataConflict
+func PatchUserData(appId, playerId string, mergePatch map[string]interface{}, ops []UserDataPatchOp, expectedVersion *int64, ip string) (int64, map[string]interface{}, error) {
+	if len(mergePatch) == 0 && len(ops) == 0 {
+		return 0, nil, fmt.Errorf("%w: 没有需要更新的内容", ErrUserDataPatchInvalid)
+	}
+	if len(ops) > UserDataPatchMaxOps {
+		return 0, nil, fmt.Errorf("%w: 操作数量不能超过%d", ErrUserDataPatchInvalid, UserDataPatchMaxOps)
+	}
+
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// 1. 锁定玩家记录，读取当前数据
+	tableName := utils.GetUserTableName(appId)
+	var rows []orm.Params
+	_, err = tx.Raw(fmt.Sprintf("SELECT data, data_version FROM %s WHERE player_id = ? FOR UPDATE", tableName), playerId).Values(&rows)
+	if err != nil {
+		tx.Rollback()
+		return 0, nil, err
+	}
+	if len(rows) == 0 {
+		tx.Rollback()
+		return 0, nil, fmt.Errorf("用户不存在")
+	}
+
+	version := paramToInt64(rows[0]["data_version"])
+	if expectedVersion != nil && *expectedVersion != version {
+		tx.Rollback()
+		return version, nil, ErrUserDataConflict
+	}
+
+	raw, _ := rows[0]["data"].(string)
+	data, err := decodeUserData(raw)
+	if err != nil {
+		tx.Rollback()
+		return 0, nil, err
+	}
+
+	// 2. 应用合并补丁和路径操作
+	if len(mergePatch) > 0 {
+		data = applyJSONMergePatch(data, mergePatch).(map[string]interface{})
+	}
+	values := make(map[string]interface{}, len(ops))
+	for i, op := range ops {
+		value, err := applyUserDataPatchOp(data, op)
+		if err != nil {
+			tx.Rollback()
+			return 0, nil, fmt.Errorf("%w: 第%d个操作%v", ErrUserDataPatchInvalid, i+1, err)
+		}
+		if op.Op != UserDataPatchRemove {
+			values[op.Path] = value
+		}
+	}
+
+	// 3. 校验更新后的数据，写回数据并增加版本号
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		tx.Rollback()
+		return 0, nil, err
+	}
+	if err := ValidateUserData(appId, string(encoded)); err != nil {
+		tx.Rollback()
+		return 0, nil, err
+	}
+	archiveCurrentUserData(tx, appId, playerId)
+	_, err = tx.Raw(fmt.Sprintf("UPDATE %s SET data = ?, data_version = data_version + 1, updated_at = NOW() WHERE player_id = ?", tableName),
+		string(encoded), playerId).Exec()
+	if err != nil {
+		tx.Rollback()
+		logs.Error("局部更新用户数据失败: %v", err)
+		return 0, nil, err
+	}
+	recordUserDataHistory(tx, appId, playerId, version+1, string(encoded), UserDataSourcePatch, ip)
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+
+	return version + 1, values, nil
+}
+
+// GetUserDataPaths 获取用户游戏数据中指定路径的值，不存在的路径不返回
+func GetUserDataPaths(appId, playerId string, paths []string) (map[string]interface{}, int64, error) {
+	user, err := GetUserByPlayerId(appId, playerId)
+	if err != nil {
+		return nil, 0, err
+	}
+	if user == nil {
+		return nil, 0, fmt.Errorf("用户不存在")
+	}
+
+	data, err := decodeUserData(user.Data)
+	if err != nil {
+		logs.Error("反序列化用户数据失败: %v", err)
+		return nil, 0, err
+	}
+
+	result := make(map[string]interface{}, len(paths))
+	for _, path := range paths {
+		if value, ok := lookupUserDataPath(data, splitUserDataPath(path)); ok {
+			result[path] = value
+		}
+	}
+	return result, user.DataVersion, nil
+}
+
+// decodeUserData 解析游戏数据，数字保留原始精度
+func decodeUserData(raw string) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	if raw == "" {
+		return data, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	return data, nil
+}
+
+// applyJSONMergePatch 按RFC 7386合并补丁：null删除字段，对象递归合并，其他值直接替换
+func applyJSONMergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = applyJSONMergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// splitUserDataPath 拆分数据路径
+func splitUserDataPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// lookupUserDataPath 按路径查找值
+func lookupUserDataPath(node interface{}, segments []string) (interface{}, bool) {
+	for _, segment := range segments {
+		switch current := node.(type) {
+		case map[string]interface{}:
+			value, ok := current[segment]
+			if !ok {
+				return nil, false
+			}
+			node = value
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, false
+			}
+			node = current[index]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// applyUserDataPatchOp 执行单个路径操作，返回路径更新后的值
+// set和increment会自动创建不存在的中间对象，数组只能修改已有下标
+func applyUserDataPatchOp(data map[string]interface{}, op UserDataPatchOp) (interface{}, error) {
+	segments := splitUserDataPath(op.Path)
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("路径不能为空")
+	}
+
+	// 找到最后一级的父节点
+	var parent interface{} = data
+	for i, segment := range segments[:len(segments)-1] {
+		switch current := parent.(type) {
+		case map[string]interface{}:
+			child, ok := current[segment]
+			if !ok || child == nil {
+				if op.Op == UserDataPatchRemove {
+					return nil, nil
+				}
+				child = make(map[string]interface{})
+				current[segment] = child
+			}
+			parent = child
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, fmt.Errorf("路径[%s]数组下标无效", strings.Join(segments[:i+1], "."))
+			}
+			parent = current[index]
+		default:
+			return nil, fmt.Errorf("路径[%s]不是对象或数组", strings.Join(segments[:i+1], "."))
+		}
+	}
+
+	last := segments[len(segments)-1]
+	get := func() (interface{}, bool) { return lookupUserDataPath(parent, []string{last}) }
+	var set func(value interface{})
+	switch current := parent.(type) {
+	case map[string]interface{}:
+		set = func(value interface{}) { current[last] = value }
+		if op.Op == UserDataPatchRemove {
+			delete(current, last)
+			return nil, nil
+		}
+	case []interface{}:
+		index, err := strconv.Atoi(last)
+		if err != nil || index < 0 || index >= len(current) {
+			return nil, fmt.Errorf("路径[%s]数组下标无效", op.Path)
+		}
+		set = func(value interface{}) { current[index] = value }
+		if op.Op == UserDataPatchRemove {
+			return nil, fmt.Errorf("不能删除数组元素，请使用set设置整个数组")
+		}
+	default:
+		return nil, fmt.Errorf("路径[%s]的上级不是对象或数组", op.Path)
+	}
+
+	switch op.Op {
+	case UserDataPatchSet:
+		set(op.Value)
+		return op.Value, nil
+	case UserDataPatchIncrement:
+		current, _ := get()
+		value, err := addUserDataNumbers(current, op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("路径[%s]%v", op.Path, err)
+		}
+		set(value)
+		return value, nil
+	default:
+		return nil, fmt.Errorf("不支持的操作类型[%s]", op.Op)
+	}
+}
+
+// addUserDataNumbers 数值相加，两个值都是整数时按整数计算，不存在的值视为0
+func addUserDataNumbers(current, delta interface{}) (json.Number, error) {
+	deltaNumber, ok := toJSONNumber(delta)
+	if !ok {
+		return "", fmt.Errorf("增量必须是数字")
+	}
+	if current == nil {
+		return deltaNumber, nil
+	}
+	currentNumber, ok := toJSONNumber(current)
+	if !ok {
+		return "", fmt.Errorf("当前值不是数字")
+	}
+
+	a, errA := currentNumber.Int64()
+	b, errB := deltaNumber.Int64()
+	if errA == nil && errB == nil {
+		if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+			return "", fmt.Errorf("相加结果超出整数范围")
+		}
+		return json.Number(strconv.FormatInt(a+b, 10)), nil
+	}
+
+	x, _ := currentNumber.Float64()
+	y, _ := deltaNumber.Float64()
+	sum := x + y
+	if math.IsInf(sum, 0) || math.IsNaN(sum) {
+		return "", fmt.Errorf("相加结果超出数值范围")
+	}
+	return json.Number(strconv.FormatFloat(sum, 'f', -1, 64)), nil
+}
+
+// toJSONNumber 将数字转换为json.Number
+func toJSONNumber(value interface{}) (json.Number, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		return v, true
+	case float64:
+		return json.Number(strconv.FormatFloat(v, 'f', -1, 64)), true
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10)), true
+	case int:
+		return json.Number(strconv.Itoa(v)), true
+	}
+	return "", false
+}
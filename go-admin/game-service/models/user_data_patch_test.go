@@ -0,0 +1,173 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testUserData 局部更新测试使用的游戏数据
+const testUserData = `{"coins":10,"name":"hero","big":9223372036854775800,"bag":{"items":[{"id":"a","count":1},{"id":"b","count":2}]}}`
+
+// TestApplyUserDataPatchOp 按路径执行set/increment/remove操作
+func TestApplyUserDataPatchOp(t *testing.T) {
+	tests := []struct {
+		name   string
+		op     UserDataPatchOp
+		want   interface{} // 操作后路径上的值，为nil表示路径不存在
+		errMsg string      // 期望的错误信息，为空表示操作成功
+	}{
+		{"set新建中间对象", UserDataPatchOp{Op: "set", Path: "profile.level", Value: 3}, 3, ""},
+		{"set覆盖已有值", UserDataPatchOp{Op: "set", Path: "name", Value: "mage"}, "mage", ""},
+		{"set数组已有下标", UserDataPatchOp{Op: "set", Path: "bag.items.1.count", Value: 5}, 5, ""},
+		{"set数组最后一个下标", UserDataPatchOp{Op: "set", Path: "bag.items.1", Value: "x"}, "x", ""},
+		{"set数组下标越界", UserDataPatchOp{Op: "set", Path: "bag.items.2.count", Value: 5}, nil, "数组下标无效"},
+		{"set数组末级下标越界", UserDataPatchOp{Op: "set", Path: "bag.items.2", Value: 5}, nil, "数组下标无效"},
+		{"set数组负数下标", UserDataPatchOp{Op: "set", Path: "bag.items.-1.count", Value: 5}, nil, "数组下标无效"},
+		{"set数组下标不是数字", UserDataPatchOp{Op: "set", Path: "bag.items.first.count", Value: 5}, nil, "数组下标无效"},
+		{"set穿过非对象", UserDataPatchOp{Op: "set", Path: "name.first", Value: "a"}, nil, "不是对象或数组"},
+		{"increment整数", UserDataPatchOp{Op: "increment", Path: "coins", Value: 5}, json.Number("15"), ""},
+		{"increment负数", UserDataPatchOp{Op: "increment", Path: "coins", Value: -15}, json.Number("-5"), ""},
+		{"increment小数", UserDataPatchOp{Op: "increment", Path: "coins", Value: 0.5}, json.Number("10.5"), ""},
+		{"increment不存在的路径", UserDataPatchOp{Op: "increment", Path: "stats.wins", Value: 1}, json.Number("1"), ""},
+		{"increment数组元素", UserDataPatchOp{Op: "increment", Path: "bag.items.0.count", Value: 2}, json.Number("3"), ""},
+		{"increment整数溢出", UserDataPatchOp{Op: "increment", Path: "big", Value: 10}, nil, "超出整数范围"},
+		{"increment当前值不是数字", UserDataPatchOp{Op: "increment", Path: "name", Value: 1}, nil, "当前值不是数字"},
+		{"increment增量不是数字", UserDataPatchOp{Op: "increment", Path: "coins", Value: "1"}, nil, "增量必须是数字"},
+		{"remove已有字段", UserDataPatchOp{Op: "remove", Path: "coins"}, nil, ""},
+		{"remove不存在的字段", UserDataPatchOp{Op: "remove", Path: "bag.none"}, nil, ""},
+		{"remove上级不存在", UserDataPatchOp{Op: "remove", Path: "missing.child"}, nil, ""},
+		{"remove数组元素", UserDataPatchOp{Op: "remove", Path: "bag.items.0"}, nil, "不能删除数组元素"},
+		{"空路径", UserDataPatchOp{Op: "set", Path: "", Value: 1}, nil, "路径不能为空"},
+		{"不支持的操作", UserDataPatchOp{Op: "append", Path: "coins", Value: 1}, nil, "不支持的操作类型"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := decodeUserData(testUserData)
+			if err != nil {
+				t.Fatalf("解析测试数据失败: %v", err)
+			}
+
+			_, err = applyUserDataPatchOp(data, tt.op)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("应返回包含%q的错误，实际为: %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("操作失败: %v", err)
+			}
+
+			value, ok := lookupUserDataPath(data, splitUserDataPath(tt.op.Path))
+			if tt.want == nil {
+				if ok {
+					t.Errorf("路径%s应不存在，实际为%v", tt.op.Path, value)
+				}
+				return
+			}
+			if !ok || value != tt.want {
+				t.Errorf("路径%s的值应为%v，实际为%v", tt.op.Path, tt.want, value)
+			}
+		})
+	}
+
+	t.Run("remove上级不存在时不创建中间对象", func(t *testing.T) {
+		data, _ := decodeUserData(testUserData)
+		if _, err := applyUserDataPatchOp(data, UserDataPatchOp{Op: "remove", Path: "missing.child"}); err != nil {
+			t.Fatalf("操作失败: %v", err)
+		}
+		if _, ok := data["missing"]; ok {
+			t.Error("删除不存在的路径不应创建中间对象")
+		}
+	})
+}
+
+// TestApplyJSONMergePatch JSON Merge Patch合并（用例来自RFC 7386附录A）
+func TestApplyJSONMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		patch  string
+		want   string
+	}{
+		{"替换字段", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"新增字段", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null删除字段", `{"a":"b"}`, `{"a":null}`, `{}`},
+		{"删除不存在的字段", `{"a":"b"}`, `{"c":null}`, `{"a":"b"}`},
+		{"保留其他字段", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"数组整体替换", `{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"值替换为数组", `{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{"对象递归合并", `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{"数组中的对象不合并", `{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{"目标中的null保留", `{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{"非对象替换为对象", `{"a":"foo"}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{"补丁不是对象", `{"a":"foo"}`, `"bar"`, `"bar"`},
+		{"目标不是对象", `["a","b"]`, `{"a":"b"}`, `{"a":"b"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var target, patch interface{}
+			if err := json.Unmarshal([]byte(tt.target), &target); err != nil {
+				t.Fatalf("解析目标失败: %v", err)
+			}
+			if err := json.Unmarshal([]byte(tt.patch), &patch); err != nil {
+				t.Fatalf("解析补丁失败: %v", err)
+			}
+
+			got, _ := json.Marshal(applyJSONMergePatch(target, patch))
+			if string(got) != tt.want {
+				t.Errorf("合并结果应为%s，实际为%s", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestAddUserDataNumbers 数值相加及溢出检查
+func TestAddUserDataNumbers(t *testing.T) {
+	maxInt := json.Number(strconv.FormatInt(math.MaxInt64, 10))
+	minInt := json.Number(strconv.FormatInt(math.MinInt64, 10))
+
+	tests := []struct {
+		name    string
+		current interface{}
+		delta   interface{}
+		want    json.Number
+		errMsg  string
+	}{
+		{"当前值不存在", nil, 5, "5", ""},
+		{"整数相加", json.Number("1"), int64(2), "3", ""},
+		{"整数与小数相加", json.Number("1"), 0.25, "1.25", ""},
+		{"小数相加", json.Number("1.5"), json.Number("2.25"), "3.75", ""},
+		{"加到最大整数", maxInt, -1, json.Number(strconv.FormatInt(math.MaxInt64-1, 10)), ""},
+		{"最大整数加负数后回到范围内", json.Number("9223372036854775800"), 7, maxInt, ""},
+		{"正向溢出", maxInt, 1, "", "超出整数范围"},
+		{"负向溢出", minInt, -1, "", "超出整数范围"},
+		{"大数正向溢出", json.Number("9223372036854775800"), json.Number("9223372036854775800"), "", "超出整数范围"},
+		{"小数溢出", json.Number("1e308"), json.Number("1e308"), "", "超出数值范围"},
+		{"增量不是数字", json.Number("1"), "2", "", "增量必须是数字"},
+		{"当前值不是数字", "abc", 1, "", "当前值不是数字"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := addUserDataNumbers(tt.current, tt.delta)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("应返回包含%q的错误，实际为: %v, %v", tt.errMsg, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("相加失败: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("结果应为%s，实际为%s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"game-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// ErrUserDataInvalid 存档数据不符合应用的校验规则
+var ErrUserDataInvalid = errors.New("存档数据校验失败")
+
+// appDataRulesCacheTTL 应用存档校验规则在进程内的缓存时间
+const appDataRulesCacheTTL = 30 * time.Second
+
+// appDataRulesCache 应用存档校验规则缓存，键为appId
+var appDataRulesCache sync.Map
+
+// appDataRules 应用存档校验规则
+type appDataRules struct {
+	schema   map[string]interface{}   // JSON Schema，为空时不校验结构
+	patterns utils.JSONSchemaPatterns // schema中预编译的pattern正则
+	maxSize  int                      // 最大字节数
+	maxDepth int                      // 最大嵌套层数
+	expireAt time.Time
+}
+
+// getAppDataRules 获取应用的存档校验规则，应用未设置的限制使用app.conf中的默认值
+func getAppDataRules(appId string) *appDataRules {
+	if cached, ok := appDataRulesCache.Load(appId); ok {
+		rules := cached.(*appDataRules)
+		if time.Now().Before(rules.expireAt) {
+			return rules
+		}
+	}
+
+	rules := &appDataRules{
+		maxSize:  web.AppConfig.DefaultInt("user_data_max_size", 1048576),
+		maxDepth: web.AppConfig.DefaultInt("user_data_max_depth", 32),
+		expireAt: time.Now().Add(appDataRulesCacheTTL),
+	}
+
+	var rows []orm.Params
+	_, err := orm.NewOrm().Raw(`SELECT data_schema, data_max_size, data_max_depth FROM apps WHERE app_id = ?`, appId).Values(&rows)
+	if err != nil {
+		logs.Warn("获取应用存档校验规则失败: appId=%s, err=%v", appId, err)
+	} else if len(rows) > 0 {
+		if size := int(paramToInt64(rows[0]["data_max_size"])); size > 0 {
+			rules.maxSize = size
+		}
+		if depth := int(paramToInt64(rows[0]["data_max_depth"])); depth > 0 {
+			rules.maxDepth = depth
+		}
+		if raw, _ := rows[0]["data_schema"].(string); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &rules.schema); err != nil {
+				logs.Warn("应用存档JSON Schema无效: appId=%s, err=%v", appId, err)
+			} else if rules.patterns, err = utils.CompileJSONSchemaPatterns(rules.schema); err != nil {
+				logs.Warn("应用存档JSON Schema的pattern无效: appId=%s, err=%v", appId, err)
+			}
+		}
+	}
+
+	appDataRulesCache.Store(appId, rules)
+	return rules
+}
+
+// ValidateUserData 按应用规则校验存档数据：必须是JSON对象，并满足大小、层数限制和JSON Schema
+func ValidateUserData(appId, data string) error {
+	return validateUserDataPayload(appId, data, true)
+}
+
+// ValidateUserDataLimits 只校验数据是合法JSON并满足大小、层数限制（用于用户信息、命名存档等不适用存档Schema的数据）
+func ValidateUserDataLimits(appId, data string) error {
+	return validateUserDataPayload(appId, data, false)
+}
+
+// validateUserDataPayload 校验数据
+func validateUserDataPayload(appId, data string, checkSchema bool) error {
+	rules := getAppDataRules(appId)
+
+	if rules.maxSize > 0 && len(data) > rules.maxSize {
+		return fmt.Errorf("%w: 数据大小%d字节超过上限%d字节", ErrUserDataInvalid, len(data), rules.maxSize)
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return fmt.Errorf("%w: 数据不是合法的JSON", ErrUserDataInvalid)
+	}
+
+	if rules.maxDepth > 0 {
+		if depth := utils.JSONDepth(value); depth > rules.maxDepth {
+			return fmt.Errorf("%w: 数据嵌套%d层超过上限%d层", ErrUserDataInvalid, depth, rules.maxDepth)
+		}
+	}
+
+	if !checkSchema {
+		return nil
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		return fmt.Errorf("%w: 数据必须是JSON对象", ErrUserDataInvalid)
+	}
+	if rules.schema != nil {
+		if err := utils.ValidateJSONSchemaWithPatterns(rules.schema, rules.patterns, value); err != nil {
+			return fmt.Errorf("%w: %v", ErrUserDataInvalid, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"game-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 登录渠道（与管理后台约定一致）
+const (
+	IdentityProviderCommon = "common" // 通用登录（code直接作为openId）
+	IdentityProviderWechat = "wx"     // 微信小程序
+	IdentityProviderQQ     = "qq"     // QQ小程序
+	IdentityProviderAlipay = "alipay" // 支付宝小程序
+	IdentityProviderDouyin = "douyin" // 抖音小程序
+	IdentityProviderYalla  = "yalla"  // Yalla SDK
+	IdentityProviderGuest  = "guest"  // 游客（客户端生成的设备ID）
+	IdentityProviderLegacy = "legacy" // 合并账号时迁移过来的旧账号，渠道未知，首次登录时改为实际渠道
+)
+
+var (
+	// ErrIdentityLinkedToOther 登录身份已绑定到其他玩家
+	ErrIdentityLinkedToOther = errors.New("该账号已绑定其他玩家")
+	// ErrIdentityProviderLinked 玩家已绑定过该渠道的其他账号
+	ErrIdentityProviderLinked = errors.New("已绑定该渠道的其他账号，请先解绑")
+	// ErrIdentityNotFound 玩家未绑定该渠道
+	ErrIdentityNotFound = errors.New("未绑定该渠道的账号")
+	// ErrIdentityLastOne 玩家只剩一个登录身份，解绑后将无法登录
+	ErrIdentityLastOne = errors.New("至少需要保留一个登录方式")
+	// ErrGuestIdentityLinked 游客账号已绑定平台账号，不能再用设备ID登录
+	ErrGuestIdentityLinked = errors.New("该游客账号已绑定平台账号，请使用平台账号登录")
+)
+
+// UserIdentity 玩家登录身份，一个玩家可以绑定多个渠道的账号
+type UserIdentity struct {
+	Provider  string `json:"provider"`
+	OpenId    string `json:"openId"`
+	UnionId   string `json:"unionId,omitempty"`
+	PlayerId  string `json:"playerId"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// FindOrCreateUserByIdentity 根据登录身份查找玩家，不存在时创建新玩家，返回玩家及是否为新玩家
+// 查找顺序：渠道+openId -> unionId（同一开放平台下的其他渠道） -> 旧数据中open_id相同且尚未建立身份的玩家
+func FindOrCreateUserByIdentity(appId, provider, openId, unionId string) (*User, bool, error) {
+	o := orm.NewOrm()
+
+	identity, err := getUserIdentity(o, appId, provider, openId)
+	if err != nil {
+		return nil, false, err
+	}
+	if identity == nil && provider != IdentityProviderGuest {
+		if identity, err = claimLegacyIdentity(o, appId, provider, openId); err != nil {
+			return nil, false, err
+		}
+	}
+	if identity != nil {
+		user, err := GetUserByPlayerId(appId, identity.PlayerId)
+		if err != nil {
+			return nil, false, err
+		}
+		if user != nil {
+			// 设备ID不是可靠的凭证，绑定平台账号后只能通过平台账号登录
+			if provider == IdentityProviderGuest {
+				identities, err := ListUserIdentities(appId, user.PlayerId)
+				if err != nil {
+					return nil, false, err
+				}
+				if hasPlatformIdentity(identities) {
+					return nil, false, ErrGuestIdentityLinked
+				}
+			}
+			if unionId != "" && identity.UnionId == "" {
+				o.Raw(fmt.Sprintf("UPDATE %s SET union_id = ? WHERE provider = ? AND open_id = ?", utils.GetUserIdentityTableName(appId)), unionId, provider, openId).Exec()
+			}
+			return user, false, nil
+		}
+		// 玩家已被删除，清理残留的身份后按新玩家处理
+		o.Raw(fmt.Sprintf("DELETE FROM %s WHERE player_id = ?", utils.GetUserIdentityTableName(appId)), identity.PlayerId).Exec()
+	}
+
+	if unionId != "" {
+		user, err := findUserByUnionId(o, appId, unionId)
+		if err != nil {
+			return nil, false, err
+		}
+		if user != nil {
+			if err := insertUserIdentity(o, appId, user.PlayerId, provider, openId, unionId); err != nil {
+				return nil, false, err
+			}
+			return user, false, nil
+		}
+	}
+
+	// 游客的设备ID由客户端生成，不能用来认领旧账号，open_id加上前缀避免与渠道账号冲突
+	if provider == IdentityProviderGuest {
+		return createOrReuseUserWithIdentity(o, appId, provider, openId, unionId, provider+":"+openId)
+	}
+
+	// 兼容建立身份表之前创建的玩家：open_id相同且该open_id还没有归属到任何渠道时，认领为当前渠道
+	openIdColumn := openId
+	legacyUser, err := GetUserByOpenId(appId, openId)
+	if err != nil {
+		return nil, false, err
+	}
+	if legacyUser != nil {
+		claimed, err := isOpenIdClaimed(o, appId, legacyUser.PlayerId, openId)
+		if err != nil {
+			return nil, false, err
+		}
+		if !claimed {
+			if err := insertUserIdentity(o, appId, legacyUser.PlayerId, provider, openId, unionId); err != nil {
+				return nil, false, err
+			}
+			return legacyUser, false, nil
+		}
+		// open_id已被其他渠道的同名账号占用，新玩家的open_id加上渠道前缀
+		openIdColumn = provider + ":" + openId
+	}
+
+	return createOrReuseUserWithIdentity(o, appId, provider, openId, unionId, openIdColumn)
+}
+
+// createOrReuseUserWithIdentity 创建新玩家，并发登录导致身份已被另一个请求创建时返回已创建的玩家
+func createOrReuseUserWithIdentity(o orm.QueryExecutor, appId, provider, openId, unionId, openIdColumn string) (*User, bool, error) {
+	user, err := createUserWithIdentity(appId, provider, openId, unionId, openIdColumn)
+	if err != nil {
+		if identity, _ := getUserIdentity(o, appId, provider, openId); identity != nil {
+			if user, _ := GetUserByPlayerId(appId, identity.PlayerId); user != nil {
+				return user, false, nil
+			}
+		}
+		return nil, false, err
+	}
+	return user, true, nil
+}
+
+// ListUserIdentities 获取玩家绑定的所有登录身份
+func ListUserIdentities(appId, playerId string) ([]UserIdentity, error) {
+	o := orm.NewOrm()
+
+	var rows []orm.Params
+	sql := fmt.Sprintf("SELECT provider, open_id, union_id, player_id, created_at FROM %s WHERE player_id = ? ORDER BY id ASC", utils.GetUserIdentityTableName(appId))
+	if _, err := o.Raw(sql, playerId).Values(&rows); err != nil {
+		return nil, err
+	}
+
+	identities := make([]UserIdentity, 0, len(rows))
+	for _, row := range rows {
+		identities = append(identities, convertUserIdentity(row))
+	}
+	return identities, nil
+}
+
+// LinkUserIdentity 为玩家绑定新的登录身份，每个渠道只能绑定一个账号
+func LinkUserIdentity(appId, playerId, provider, openId, unionId string) (*UserIdentity, error) {
+	o := orm.NewOrm()
+
+	identity, err := getUserIdentity(o, appId, provider, openId)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		if identity.PlayerId != playerId {
+			return nil, ErrIdentityLinkedToOther
+		}
+		return identity, nil
+	}
+	if legacy, err := getUserIdentity(o, appId, IdentityProviderLegacy, openId); err != nil {
+		return nil, err
+	} else if legacy != nil && legacy.PlayerId != playerId {
+		return nil, ErrIdentityLinkedToOther
+	}
+
+	// 旧数据中以该openId创建的其他玩家也视为已绑定
+	if legacyUser, err := GetUserByOpenId(appId, openId); err != nil {
+		return nil, err
+	} else if legacyUser != nil && legacyUser.PlayerId != playerId {
+		if claimed, err := isOpenIdClaimed(o, appId, legacyUser.PlayerId, openId); err != nil {
+			return nil, err
+		} else if !claimed {
+			return nil, ErrIdentityLinkedToOther
+		}
+	}
+
+	var rows []orm.Params
+	sql := fmt.Sprintf("SELECT id FROM %s WHERE player_id = ? AND provider = ? LIMIT 1", utils.GetUserIdentityTableName(appId))
+	if _, err := o.Raw(sql, playerId, provider).Values(&rows); err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 {
+		return nil, ErrIdentityProviderLinked
+	}
+
+	if err := insertUserIdentity(o, appId, playerId, provider, openId, unionId); err != nil {
+		// 唯一键冲突说明同时被其他玩家绑定
+		if identity, _ := getUserIdentity(o, appId, provider, openId); identity != nil && identity.PlayerId != playerId {
+			return nil, ErrIdentityLinkedToOther
+		}
+		return nil, err
+	}
+
+	logs.Info("绑定登录身份成功: appId=%s, playerId=%s, provider=%s, openId=%s", appId, playerId, provider, openId)
+	return getUserIdentity(o, appId, provider, openId)
+}
+
+// UnlinkUserIdentity 解绑玩家某个渠道的登录身份，不能解绑最后一个
+func UnlinkUserIdentity(appId, playerId, provider string) error {
+	o := orm.NewOrm()
+	tableName := utils.GetUserIdentityTableName(appId)
+
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+
+	var rows []orm.Params
+	_, err = tx.Raw(fmt.Sprintf("SELECT provider, open_id FROM %s WHERE player_id = ? FOR UPDATE", tableName), playerId).Values(&rows)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	openId := ""
+	found := false
+	for _, row := range rows {
+		if fmt.Sprint(row["provider"]) == provider {
+			openId = fmt.Sprint(row["open_id"])
+			found = true
+		}
+	}
+	if !found {
+		tx.Rollback()
+		return ErrIdentityNotFound
+	}
+	if len(rows) <= 1 {
+		tx.Rollback()
+		return ErrIdentityLastOne
+	}
+
+	if _, err := tx.Raw(fmt.Sprintf("DELETE FROM %s WHERE player_id = ? AND provider = ?", tableName), playerId, provider).Exec(); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 解绑的账号如果是玩家的open_id，改为不会被登录匹配的值，避免再次登录时被当作旧数据认领回来
+	sql := fmt.Sprintf("UPDATE %s SET open_id = CONCAT('unlinked:', player_id) WHERE player_id = ? AND open_id = ?", utils.GetUserTableName(appId))
+	if _, err := tx.Raw(sql, playerId, openId).Exec(); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logs.Info("解绑登录身份成功: appId=%s, playerId=%s, provider=%s", appId, playerId, provider)
+	return nil
+}
+
+// createUserWithIdentity 在事务中创建新玩家及其登录身份
+func createUserWithIdentity(appId, provider, openId, unionId, openIdColumn string) (*User, error) {
+	tx, err := orm.NewOrm().Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	user := &User{
+		AppId:    appId,
+		PlayerId: utils.GeneratePlayerId(),
+		OpenId:   openIdColumn,
+		Data:     "{}",
+	}
+	if err := createUser(tx, appId, user); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("创建用户失败: %v", err)
+	}
+	if err := insertUserIdentity(tx, appId, user.PlayerId, provider, openId, unionId); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("创建登录身份失败: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logs.Info("创建新用户成功: appId=%s, playerId=%s, provider=%s, openId=%s", appId, user.PlayerId, provider, openId)
+	return user, nil
+}
+
+// getUserIdentity 根据渠道和openId获取登录身份，不存在时返回nil
+func getUserIdentity(o orm.QueryExecutor, appId, provider, openId string) (*UserIdentity, error) {
+	var rows []orm.Params
+	sql := fmt.Sprintf("SELECT provider, open_id, union_id, player_id, created_at FROM %s WHERE provider = ? AND open_id = ?", utils.GetUserIdentityTableName(appId))
+	if _, err := o.Raw(sql, provider, openId).Values(&rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	identity := convertUserIdentity(rows[0])
+	return &identity, nil
+}
+
+// claimLegacyIdentity 把渠道未知的旧账号身份改为当前登录渠道，不存在时返回nil
+func claimLegacyIdentity(o orm.QueryExecutor, appId, provider, openId string) (*UserIdentity, error) {
+	identity, err := getUserIdentity(o, appId, IdentityProviderLegacy, openId)
+	if err != nil || identity == nil {
+		return nil, err
+	}
+
+	sql := fmt.Sprintf("UPDATE %s SET provider = ? WHERE provider = ? AND open_id = ?", utils.GetUserIdentityTableName(appId))
+	if _, err := o.Raw(sql, provider, IdentityProviderLegacy, openId).Exec(); err != nil {
+		return nil, err
+	}
+	identity.Provider = provider
+	return identity, nil
+}
+
+// findUserByUnionId 根据unionId查找已绑定的玩家
+func findUserByUnionId(o orm.QueryExecutor, appId, unionId string) (*User, error) {
+	var rows []orm.Params
+	sql := fmt.Sprintf("SELECT player_id FROM %s WHERE union_id = ? ORDER BY id ASC LIMIT 1", utils.GetUserIdentityTableName(appId))
+	if _, err := o.Raw(sql, unionId).Values(&rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return GetUserByPlayerId(appId, fmt.Sprint(rows[0]["player_id"]))
+}
+
+// isOpenIdClaimed 玩家的open_id是否已归属到某个渠道的登录身份
+func isOpenIdClaimed(o orm.QueryExecutor, appId, playerId, openId string) (bool, error) {
+	var rows []orm.Params
+	sql := fmt.Sprintf("SELECT id FROM %s WHERE player_id = ? AND open_id = ? LIMIT 1", utils.GetUserIdentityTableName(appId))
+	if _, err := o.Raw(sql, playerId, openId).Values(&rows); err != nil {
+		return false, err
+	}
+	return len(rows) > 0, nil
+}
+
+// insertUserIdentity 写入登录身份
+func insertUserIdentity(o orm.QueryExecutor, appId, playerId, provider, openId, unionId string) error {
+	sql := fmt.Sprintf("INSERT INTO %s (player_id, provider, open_id, union_id, created_at, updated_at) VALUES (?, ?, ?, ?, NOW(), NOW())", utils.GetUserIdentityTableName(appId))
+	_, err := o.Raw(sql, playerId, provider, openId, unionId).Exec()
+	return err
+}
+
+// hasPlatformIdentity 是否绑定了游客以外的登录身份
+func hasPlatformIdentity(identities []UserIdentity) bool {
+	for _, identity := range identities {
+		if identity.Provider != IdentityProviderGuest {
+			return true
+		}
+	}
+	return false
+}
+
+// convertUserIdentity 转换查询结果
+func convertUserIdentity(row orm.Params) UserIdentity {
+	identity := UserIdentity{
+		Provider: fmt.Sprint(row["provider"]),
+		OpenId:   fmt.Sprint(row["open_id"]),
+		PlayerId: fmt.Sprint(row["player_id"]),
+	}
+	if unionId, ok := row["union_id"].(string); ok {
+		identity.UnionId = unionId
+	}
+	if createdAt, ok := row["created_at"].(string); ok {
+		identity.CreatedAt = createdAt
+	}
+	return identity
+}
@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+// TestHasPlatformIdentity 游客绑定平台账号后不能再用设备ID登录
+func TestHasPlatformIdentity(t *testing.T) {
+	guest := UserIdentity{Provider: IdentityProviderGuest, OpenId: "device-0001", PlayerId: "p1"}
+
+	tests := []struct {
+		name       string
+		identities []UserIdentity
+		linked     bool
+	}{
+		{"只有游客身份", []UserIdentity{guest}, false},
+		{"游客绑定微信后", []UserIdentity{guest, {Provider: IdentityProviderWechat, OpenId: "wx_openid", PlayerId: "p1"}}, true},
+		{"游客合并到旧账号后", []UserIdentity{guest, {Provider: IdentityProviderLegacy, OpenId: "old_openid", PlayerId: "p1"}}, true},
+		{"没有登录身份", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if linked := hasPlatformIdentity(tt.identities); linked != tt.linked {
+				t.Errorf("应为%v，实际为%v", tt.linked, linked)
+			}
+		})
+	}
+}
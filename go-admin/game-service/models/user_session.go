@@ -0,0 +1,368 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/server/web"
+	"github.com/go-redis/redis/v8"
+)
+
+// 登录会话保存在redis中，每台设备登录后生成一个会话：
+//   user_session_{appId}_{playerId}_{sessionId} 会话详情（hash），过期时间与refreshToken一致
+//   user_sessions_{appId}_{playerId}            玩家当前有效的会话ID集合
+// token和refreshToken的格式为"{sessionId}.{随机串}"，校验时直接定位到会话。
+// 会话被踢下线（其他设备登录、管理员操作、封禁）后保留到token过期，期间该设备的请求返回被踢下线。
+
+var (
+	// ErrSessionInvalid token不存在、已过期或已被撤销
+	ErrSessionInvalid = errors.New("登录已失效，请重新登录")
+	// ErrRefreshTokenInvalid refreshToken不存在、已过期或已被使用
+	ErrRefreshTokenInvalid = errors.New("refreshToken无效或已过期")
+	// ErrUserBanned 玩家已被封禁
+	ErrUserBanned = errors.New("账号已被封禁")
+)
+
+// 会话被踢下线的原因
+const (
+	SessionKickOtherDevice = "other_device" // 应用限制单设备登录，其他设备登录
+	SessionKickLimit       = "limit"        // 登录设备数超过上限，最早登录的设备被踢下线
+	SessionKickAdmin       = "admin"        // 管理员踢下线
+	SessionKickBanned      = "banned"       // 玩家被封禁
+)
+
+const (
+	defaultUserTokenExpire        = 86400   // token默认有效期（秒）
+	defaultUserRefreshTokenExpire = 2592000 // refreshToken默认有效期（秒）
+	defaultUserSessionMax         = 10      // 每个玩家默认最多同时登录的设备数
+)
+
+// SessionKickedError 会话已被踢下线
+type SessionKickedError struct {
+	Reason string
+}
+
+func (e *SessionKickedError) Error() string {
+	switch e.Reason {
+	case SessionKickOtherDevice:
+		return "账号已在其他设备登录"
+	case SessionKickLimit:
+		return "登录设备过多，已被踢下线"
+	case SessionKickBanned:
+		return "账号已被封禁"
+	default:
+		return "已被管理员踢下线"
+	}
+}
+
+// UserSession 登录会话
+type UserSession struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"` // token有效期（秒）
+}
+
+// sessionKickLua 把会话标记为被踢下线并移出有效会话集合，会话保留到token过期
+const sessionKickLua = `
+local function kick(index, key, id, reason, now)
+	local expireAt = tonumber(redis.call('HGET', key, 'token_expire_at') or '0') or 0
+	if expireAt > now then
+		redis.call('HSET', key, 'kicked', reason)
+		redis.call('EXPIRE', key, expireAt - now)
+	else
+		redis.call('DEL', key)
+	end
+	redis.call('SREM', index, id)
+end
+`
+
+// sessionCreateScript 创建会话：单设备登录时踢掉其他会话，否则超过设备上限时踢掉最早登录的会话
+// KEYS: 会话集合、新会话
+// ARGV: sessionId、token、refreshToken、token过期时间、设备、IP、当前时间、会话过期秒数、是否单设备、设备上限、会话键前缀
+var sessionCreateScript = redis.NewScript(sessionKickLua + `
+local now = tonumber(ARGV[7])
+local active = {}
+for _, id in ipairs(redis.call('SMEMBERS', KEYS[1])) do
+	local key = ARGV[11] .. id
+	local loginTime = redis.call('HGET', key, 'login_time')
+	if not loginTime then
+		redis.call('SREM', KEYS[1], id)
+	elseif ARGV[9] == '1' then
+		kick(KEYS[1], key, id, 'other_device', now)
+	else
+		table.insert(active, {id, tonumber(loginTime)})
+	end
+end
+
+local overflow = #active - tonumber(ARGV[10]) + 1
+if overflow > 0 then
+	table.sort(active, function(a, b) return a[2] < b[2] end)
+	for i = 1, overflow do
+		kick(KEYS[1], ARGV[11] .. active[i][1], active[i][1], 'limit', now)
+	end
+end
+
+redis.call('HSET', KEYS[2], 'token', ARGV[2], 'refresh_token', ARGV[3], 'token_expire_at', ARGV[4],
+	'device', ARGV[5], 'ip', ARGV[6], 'login_time', ARGV[7], 'last_heartbeat', ARGV[7], 'kicked', '')
+redis.call('EXPIRE', KEYS[2], ARGV[8])
+redis.call('SADD', KEYS[1], ARGV[1])
+redis.call('EXPIRE', KEYS[1], ARGV[8])
+return 1
+`)
+
+// sessionRefreshScript refreshToken一致时原子替换token和refreshToken，保证同一个refreshToken只能使用一次
+// 返回ok表示成功，invalid表示refreshToken无效，其他为会话被踢下线的原因
+// KEYS: 会话、会话集合
+// ARGV: refreshToken、新token、新refreshToken、token过期时间、会话过期秒数
+var sessionRefreshScript = redis.NewScript(`
+local session = redis.call('HMGET', KEYS[1], 'refresh_token', 'kicked')
+if not session[1] or session[1] ~= ARGV[1] then
+	return 'invalid'
+end
+if session[2] and session[2] ~= '' then
+	return session[2]
+end
+redis.call('HSET', KEYS[1], 'token', ARGV[2], 'refresh_token', ARGV[3], 'token_expire_at', ARGV[4])
+redis.call('EXPIRE', KEYS[1], ARGV[5])
+redis.call('EXPIRE', KEYS[2], ARGV[5])
+return 'ok'
+`)
+
+// sessionTouchScript 更新会话最后心跳时间（会话已删除时不重新创建）
+var sessionTouchScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'last_heartbeat', ARGV[1], 'ip', ARGV[2])
+return 1
+`)
+
+// sessionKickScript 踢下线玩家的指定会话，sessionId为空时踢下线所有会话
+// KEYS: 会话集合
+// ARGV: 会话键前缀、原因、当前时间、sessionId
+var sessionKickScript = redis.NewScript(sessionKickLua + `
+local now = tonumber(ARGV[3])
+local count = 0
+for _, id in ipairs(redis.call('SMEMBERS', KEYS[1])) do
+	if ARGV[4] == '' or ARGV[4] == id then
+		kick(KEYS[1], ARGV[1] .. id, id, ARGV[2], now)
+		count = count + 1
+	end
+end
+return count
+`)
+
+// IsBanned 玩家当前是否处于封禁状态（临时封禁到期后视为未封禁）
+func (u *User) IsBanned() bool {
+	return u.Banned && (u.BanExpire.IsZero() || u.BanExpire.After(time.Now()))
+}
+
+// CreateUserSession 玩家登录成功后为当前设备创建新会话
+// 应用限制单设备登录时，玩家在其他设备上的会话全部被踢下线
+func CreateUserSession(appId, playerId, device, ip string) (*UserSession, error) {
+	sessionId, err := randomSessionString(8)
+	if err != nil {
+		return nil, err
+	}
+	session, err := newUserSession(sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	singleDevice := "0"
+	if isSingleDeviceLogin(appId) {
+		singleDevice = "1"
+	}
+
+	now := time.Now().Unix()
+	keys := []string{userSessionsKey(appId, playerId), userSessionKey(appId, playerId, sessionId)}
+	err = sessionCreateScript.Run(context.Background(), RedisClient, keys,
+		sessionId, session.Token, session.RefreshToken, now+session.ExpiresIn, device, ip, now,
+		userRefreshTokenExpire(), singleDevice, userSessionMax(), userSessionKey(appId, playerId, "")).Err()
+	if err != nil {
+		return nil, fmt.Errorf("保存登录状态失败: %v", err)
+	}
+
+	return session, nil
+}
+
+// ValidateUserSession 校验玩家token，返回token所属的会话ID
+// token不存在、已过期或不一致时返回ErrSessionInvalid，会话被踢下线时返回*SessionKickedError
+func ValidateUserSession(appId, playerId, token string) (string, error) {
+	sessionId := parseSessionId(token)
+	if playerId == "" || sessionId == "" {
+		return "", ErrSessionInvalid
+	}
+
+	values, err := RedisClient.HMGet(context.Background(), userSessionKey(appId, playerId, sessionId),
+		"token", "token_expire_at", "kicked").Result()
+	if err != nil {
+		return "", fmt.Errorf("查询登录状态失败: %v", err)
+	}
+
+	userToken, _ := values[0].(string)
+	if userToken == "" || subtle.ConstantTimeCompare([]byte(userToken), []byte(token)) != 1 {
+		return "", ErrSessionInvalid
+	}
+	if kicked, _ := values[2].(string); kicked != "" {
+		return "", &SessionKickedError{Reason: kicked}
+	}
+	expireAt, _ := values[1].(string)
+	if ts, _ := strconv.ParseInt(expireAt, 10, 64); ts <= time.Now().Unix() {
+		return "", ErrSessionInvalid
+	}
+
+	return sessionId, nil
+}
+
+// RefreshUserSession 使用refreshToken换取新的token，refreshToken同时轮换
+func RefreshUserSession(appId, playerId, refreshToken string) (*UserSession, error) {
+	sessionId := parseSessionId(refreshToken)
+	if playerId == "" || sessionId == "" {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	user, err := GetUserByPlayerId(appId, playerId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if user.IsBanned() {
+		KickUserSessions(appId, playerId, SessionKickBanned)
+		return nil, ErrUserBanned
+	}
+
+	session, err := newUserSession(sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{userSessionKey(appId, playerId, sessionId), userSessionsKey(appId, playerId)}
+	result, err := sessionRefreshScript.Run(context.Background(), RedisClient, keys,
+		refreshToken, session.Token, session.RefreshToken, time.Now().Unix()+session.ExpiresIn, userRefreshTokenExpire()).Text()
+	if err != nil {
+		return nil, fmt.Errorf("刷新登录状态失败: %v", err)
+	}
+	switch result {
+	case "ok":
+		return session, nil
+	case "invalid":
+		return nil, ErrRefreshTokenInvalid
+	default:
+		return nil, &SessionKickedError{Reason: result}
+	}
+}
+
+// TouchUserSession 记录会话心跳时间和IP
+func TouchUserSession(appId, playerId, sessionId, ip string) error {
+	return sessionTouchScript.Run(context.Background(), RedisClient,
+		[]string{userSessionKey(appId, playerId, sessionId)}, time.Now().Unix(), ip).Err()
+}
+
+// RevokeUserSession 删除玩家的指定会话（登出）
+func RevokeUserSession(appId, playerId, sessionId string) error {
+	ctx := context.Background()
+	pipe := RedisClient.TxPipeline()
+	pipe.Del(ctx, userSessionKey(appId, playerId, sessionId))
+	pipe.SRem(ctx, userSessionsKey(appId, playerId), sessionId)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// KickUserSessions 踢下线玩家的所有会话
+func KickUserSessions(appId, playerId, reason string) error {
+	return sessionKickScript.Run(context.Background(), RedisClient, []string{userSessionsKey(appId, playerId)},
+		userSessionKey(appId, playerId, ""), reason, time.Now().Unix(), "").Err()
+}
+
+// isSingleDeviceLogin 应用是否限制玩家只能在一台设备登录
+func isSingleDeviceLogin(appId string) bool {
+	var singleDevice bool
+	err := orm.NewOrm().Raw("SELECT single_device_login FROM apps WHERE app_id = ?", appId).QueryRow(&singleDevice)
+	return err == nil && singleDevice
+}
+
+// newUserSession 为会话生成新的token和refreshToken
+func newUserSession(sessionId string) (*UserSession, error) {
+	token, err := randomSessionString(32)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomSessionString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserSession{
+		Token:        sessionId + "." + token,
+		RefreshToken: sessionId + "." + refreshToken,
+		ExpiresIn:    int64(userTokenExpire()),
+	}, nil
+}
+
+// parseSessionId 从token或refreshToken中解析会话ID，格式错误时返回空字符串
+func parseSessionId(token string) string {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 || len(parts[0]) != 16 || parts[1] == "" {
+		return ""
+	}
+	sessionId := parts[0]
+	if _, err := hex.DecodeString(sessionId); err != nil {
+		return ""
+	}
+	return sessionId
+}
+
+// randomSessionString 生成n字节随机数的十六进制字符串
+func randomSessionString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成token失败: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// userTokenExpire token有效期（秒）
+func userTokenExpire() int {
+	if expire := web.AppConfig.DefaultInt("user_token_expire", defaultUserTokenExpire); expire > 0 {
+		return expire
+	}
+	return defaultUserTokenExpire
+}
+
+// userRefreshTokenExpire refreshToken有效期（秒），不会短于token有效期
+func userRefreshTokenExpire() int {
+	expire := web.AppConfig.DefaultInt("user_refresh_token_expire", defaultUserRefreshTokenExpire)
+	if expire < userTokenExpire() {
+		return userTokenExpire()
+	}
+	return expire
+}
+
+// userSessionMax 每个玩家最多同时登录的设备数
+func userSessionMax() int {
+	if max := web.AppConfig.DefaultInt("user_session_max", defaultUserSessionMax); max > 0 {
+		return max
+	}
+	return defaultUserSessionMax
+}
+
+// userSessionKey 玩家会话的redis键，sessionId为空时返回键前缀
+func userSessionKey(appId, playerId, sessionId string) string {
+	return fmt.Sprintf("user_session_%s_%s_%s", appId, playerId, sessionId)
+}
+
+// userSessionsKey 玩家有效会话集合的redis键
+func userSessionsKey(appId, playerId string) string {
+	return fmt.Sprintf("user_sessions_%s_%s", appId, playerId)
+}
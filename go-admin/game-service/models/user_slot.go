@@ -0,0 +1,181 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"game-service/utils"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+var (
+	// ErrUserDataSlotNotFound 存档槽位不存在
+	ErrUserDataSlotNotFound = errors.New("存档槽位不存在")
+	// ErrUserDataSlotLimit 存档槽位数量已达上限
+	ErrUserDataSlotLimit = errors.New("存档槽位数量已达上限")
+	// ErrUserDataSlotInvalid 存档槽位名称不合法
+	ErrUserDataSlotInvalid = errors.New("存档槽位名称只能包含字母、数字、下划线和中划线，长度1-50")
+)
+
+// userDataSlotPattern 存档槽位名称规则
+var userDataSlotPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,50}$`)
+
+// UserDataSlot 玩家命名存档（如slot1、settings），对应user_data_[appid]表
+type UserDataSlot struct {
+	Slot      string    `json:"slot"`
+	Data      string    `json:"data,omitempty"`
+	Version   int64     `json:"version"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ValidateUserDataSlot 校验存档槽位名称
+func ValidateUserDataSlot(slot string) error {
+	if !userDataSlotPattern.MatchString(slot) {
+		return ErrUserDataSlotInvalid
+	}
+	return nil
+}
+
+// getUserDataMaxSlots 每个玩家最多可以拥有的存档槽位数
+func getUserDataMaxSlots() int {
+	return web.AppConfig.DefaultInt("user_data_max_slots", 10)
+}
+
+// GetUserDataSlot 获取玩家指定槽位的存档，不存在时返回ErrUserDataSlotNotFound
+func GetUserDataSlot(appId, playerId, slot string) (*UserDataSlot, error) {
+	o := orm.NewOrm()
+	sql := fmt.Sprintf(`SELECT slot, data, version, LENGTH(data) as size, created_at, updated_at FROM %s WHERE player_id = ? AND slot = ?`,
+		utils.GetUserDataTableName(appId))
+
+	var rows []orm.Params
+	if _, err := o.Raw(sql, playerId, slot).Values(&rows); err != nil {
+		logs.Error("获取存档失败: %v", err)
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrUserDataSlotNotFound
+	}
+
+	return convertUserDataSlot(rows[0]), nil
+}
+
+// ListUserDataSlots 获取玩家所有槽位的存档信息（不含存档内容）
+func ListUserDataSlots(appId, playerId string) ([]UserDataSlot, error) {
+	o := orm.NewOrm()
+	sql := fmt.Sprintf(`SELECT slot, version, LENGTH(data) as size, created_at, updated_at FROM %s WHERE player_id = ? ORDER BY slot ASC`,
+		utils.GetUserDataTableName(appId))
+
+	var rows []orm.Params
+	if _, err := o.Raw(sql, playerId).Values(&rows); err != nil {
+		logs.Error("获取存档列表失败: %v", err)
+		return nil, err
+	}
+
+	slots := make([]UserDataSlot, 0, len(rows))
+	for _, row := range rows {
+		slots = append(slots, *convertUserDataSlot(row))
+	}
+	return slots, nil
+}
+
+// SaveUserDataSlot 保存玩家指定槽位的存档并返回新的版本号，槽位不存在时自动创建
+// expectedVersion不为空时只有服务器版本号与之相同才会保存（槽位不存在时版本号为0），否则返回ErrUserDataConflict
+func SaveUserDataSlot(appId, playerId, slot, data string, expectedVersion *int64) (int64, error) {
+	tableName := utils.GetUserDataTableName(appId)
+
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	// 先锁定玩家记录，同一玩家并发新建不同槽位时串行执行数量检查（槽位不存在时FOR UPDATE只加间隙锁，不互斥）
+	var users []orm.Params
+	_, err = tx.Raw(fmt.Sprintf(`SELECT player_id FROM %s WHERE player_id = ? FOR UPDATE`, utils.GetUserTableName(appId)), playerId).Values(&users)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	var rows []orm.Params
+	_, err = tx.Raw(fmt.Sprintf(`SELECT version FROM %s WHERE player_id = ? AND slot = ? FOR UPDATE`, tableName), playerId, slot).Values(&rows)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	var version int64
+	if len(rows) > 0 {
+		version = paramToInt64(rows[0]["version"])
+	}
+	if expectedVersion != nil && *expectedVersion != version {
+		tx.Rollback()
+		return version, ErrUserDataConflict
+	}
+
+	if len(rows) == 0 {
+		// 新建槽位前检查数量上限
+		var countRows []orm.Params
+		_, err = tx.Raw(fmt.Sprintf(`SELECT COUNT(*) as total FROM %s WHERE player_id = ?`, tableName), playerId).Values(&countRows)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		if len(countRows) > 0 && paramToInt64(countRows[0]["total"]) >= int64(getUserDataMaxSlots()) {
+			tx.Rollback()
+			return 0, ErrUserDataSlotLimit
+		}
+
+		_, err = tx.Raw(fmt.Sprintf(`INSERT INTO %s (player_id, slot, data, version, created_at, updated_at) VALUES (?, ?, ?, 1, NOW(), NOW())`, tableName),
+			playerId, slot, data).Exec()
+	} else {
+		_, err = tx.Raw(fmt.Sprintf(`UPDATE %s SET data = ?, version = version + 1, updated_at = NOW() WHERE player_id = ? AND slot = ?`, tableName),
+			data, playerId, slot).Exec()
+	}
+	if err != nil {
+		tx.Rollback()
+		logs.Error("保存存档失败: %v", err)
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return version + 1, nil
+}
+
+// DeleteUserDataSlot 删除玩家指定槽位的存档
+func DeleteUserDataSlot(appId, playerId, slot string) error {
+	o := orm.NewOrm()
+	sql := fmt.Sprintf(`DELETE FROM %s WHERE player_id = ? AND slot = ?`, utils.GetUserDataTableName(appId))
+
+	result, err := o.Raw(sql, playerId, slot).Exec()
+	if err != nil {
+		logs.Error("删除存档失败: %v", err)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrUserDataSlotNotFound
+	}
+	return nil
+}
+
+// convertUserDataSlot 将查询结果转换为存档结构
+func convertUserDataSlot(row orm.Params) *UserDataSlot {
+	slot := &UserDataSlot{
+		Version:   paramToInt64(row["version"]),
+		Size:      paramToInt64(row["size"]),
+		CreatedAt: paramToTime(row["created_at"]),
+		UpdatedAt: paramToTime(row["updated_at"]),
+	}
+	slot.Slot, _ = row["slot"].(string)
+	slot.Data, _ = row["data"].(string)
+	return slot
+}
@@ -35,19 +35,40 @@ func init() {
 	web.Router("/user/login/douyin", &login.DouyinLoginController{}, "post:DouyinLogin")
 	web.Router("/user/login/qq", &login.QQLoginController{}, "post:QQLogin")
 	web.Router("/user/login/yalla", &login.YallaLoginController{}, "post:YallaLogin")
+	web.Router("/user/login/guest", &login.GuestLoginController{}, "post:GuestLogin")
+
+	// 登录会话（刷新token、登出）
+	web.Router("/user/refreshToken", &login.SessionController{}, "post:RefreshToken")
+	web.Router("/user/logout", &login.SessionController{}, "post:Logout")
+
+	// 登录身份绑定（同一玩家绑定多个渠道的账号）
+	web.Router("/user/identity/link", &login.IdentityController{}, "post:LinkIdentity")
+	web.Router("/user/identity/unlink", &login.IdentityController{}, "post:UnlinkIdentity")
+	web.Router("/user/identity/list", &login.IdentityController{}, "post:ListIdentities")
 
 	// 用户数据接口（对齐zy-sdk/user.ts）
 	web.Router("/user/getData", &controllers.UserController{}, "post:GetData")
 	web.Router("/user/saveData", &controllers.UserController{}, "post:SaveData")
+	web.Router("/user/patchData", &controllers.UserController{}, "post:PatchData")
+	web.Router("/user/slot/get", &controllers.UserController{}, "post:GetSlot")
+	web.Router("/user/slot/save", &controllers.UserController{}, "post:SaveSlot")
+	web.Router("/user/slot/list", &controllers.UserController{}, "post:ListSlots")
+	web.Router("/user/slot/delete", &controllers.UserController{}, "post:DeleteSlot")
 	web.Router("/user/saveUserInfo", &controllers.UserController{}, "post:SaveUserInfo")
 
 	// 排行榜接口（对齐zy-sdk/leaderboard.ts）
 	web.Router("/leaderboard/commit", &controllers.LeaderboardController{}, "post:CommitScore")
 	web.Router("/leaderboard/queryTopRank", &controllers.LeaderboardController{}, "post:QueryTopRank")
+	web.Router("/leaderboard/queryAroundRank", &controllers.LeaderboardController{}, "post:QueryAroundRank")
+	web.Router("/leaderboard/queryFriendRank", &controllers.LeaderboardController{}, "post:QueryFriendRank")
+	web.Router("/leaderboard/querySeasonRank", &controllers.LeaderboardController{}, "post:QuerySeasonRank")
+	web.Router("/leaderboard/queryHallOfFame", &controllers.LeaderboardController{}, "post:QueryHallOfFame")
+	web.Router("/leaderboard/queryLeague", &controllers.LeaderboardController{}, "post:QueryLeague")
 
 	// 计数器接口（对齐zy-sdk/counter.ts）
 	web.Router("/counter/increment", &controllers.CounterController{}, "post:IncrementCounter")
 	web.Router("/counter/get", &controllers.CounterController{}, "post:GetCounter")
+	web.Router("/counter/batch", &controllers.CounterController{}, "post:BatchCounter")
 
 	// 邮件接口（对齐zy-sdk/mail.ts）
 	web.Router("/mail/getUserMails", &controllers.MailController{}, "post:GetUserMails")
@@ -26,7 +26,11 @@ var (
 )
 
 func init() {
-	appconf, _ := config.NewConfig("ini", "conf/app.conf")
+	// 配置文件不存在时（如在包目录下运行单元测试）使用默认值
+	appconf, err := config.NewConfig("ini", "conf/app.conf")
+	if err != nil {
+		appconf = nil
+	}
 	apiSecret = getConfigString(appconf, "api_secret", "default_api_secret")
 	md5Salt = getConfigString(appconf, "md5_salt", "default_md5_salt")
 	adminServiceURL = getConfigString(appconf, "admin_service_url", "")
@@ -0,0 +1,332 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// JSONSchemaError JSON数据校验失败，Path为出错位置（以.分隔，数组使用下标，根节点为空）
+type JSONSchemaError struct {
+	Path string
+	Msg  string
+}
+
+func (e *JSONSchemaError) Error() string {
+	if e.Path == "" {
+		return e.Msg
+	}
+	return e.Path + ": " + e.Msg
+}
+
+// JSONSchemaPatterns 预编译的pattern正则，键为pattern原文，值为nil表示该pattern无效
+type JSONSchemaPatterns map[string]*regexp.Regexp
+
+// ValidateJSONSchema 按JSON Schema校验数据，数据需使用json.Decoder.UseNumber解析
+// 支持type、enum、const、properties、required、additionalProperties、maxProperties、
+// items、minItems、maxItems、minLength、maxLength、pattern、minimum、maximum关键字
+func ValidateJSONSchema(schema map[string]interface{}, value interface{}) error {
+	return validateJSONSchema(schema, nil, value, "")
+}
+
+// ValidateJSONSchemaWithPatterns 使用预编译的pattern正则校验数据，patterns中没有的pattern按需编译
+func ValidateJSONSchemaWithPatterns(schema map[string]interface{}, patterns JSONSchemaPatterns, value interface{}) error {
+	return validateJSONSchema(schema, patterns, value, "")
+}
+
+// CompileJSONSchemaPatterns 预编译schema中所有pattern关键字，返回遇到的第一个无效pattern的错误
+// 无效的pattern同样记录在结果中（值为nil），校验时报告为规则错误
+func CompileJSONSchemaPatterns(schema map[string]interface{}) (JSONSchemaPatterns, error) {
+	patterns := JSONSchemaPatterns{}
+	err := compileJSONSchemaPatterns(schema, patterns, "")
+	return patterns, err
+}
+
+// compileJSONSchemaPatterns 递归编译pattern（只处理校验时会用到的子模式）
+func compileJSONSchemaPatterns(schema map[string]interface{}, patterns JSONSchemaPatterns, path string) error {
+	var firstErr error
+	if pattern, ok := schema["pattern"].(string); ok {
+		if _, exists := patterns[pattern]; !exists {
+			re, err := regexp.Compile(pattern)
+			patterns[pattern] = re
+			if err != nil {
+				firstErr = &JSONSchemaError{Path: path, Msg: "校验规则pattern无效: " + err.Error()}
+			}
+		}
+	}
+
+	children := map[string]map[string]interface{}{}
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		for key, child := range properties {
+			if childSchema, ok := child.(map[string]interface{}); ok {
+				children[joinJSONPath(path, key)] = childSchema
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		children[joinJSONPath(path, "items")] = items
+	}
+	if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+		children[joinJSONPath(path, "additionalProperties")] = additional
+	}
+
+	// 按路径顺序编译，保证同一份规则每次报告相同的错误
+	childPaths := make([]string, 0, len(children))
+	for childPath := range children {
+		childPaths = append(childPaths, childPath)
+	}
+	sort.Strings(childPaths)
+	for _, childPath := range childPaths {
+		if err := compileJSONSchemaPatterns(children[childPath], patterns, childPath); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// JSONDepth 计算JSON数据的嵌套层数，标量为0
+func JSONDepth(value interface{}) int {
+	depth := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			if d := JSONDepth(child); d > depth {
+				depth = d
+			}
+		}
+		return depth + 1
+	case []interface{}:
+		for _, child := range v {
+			if d := JSONDepth(child); d > depth {
+				depth = d
+			}
+		}
+		return depth + 1
+	}
+	return 0
+}
+
+// validateJSONSchema 递归校验
+func validateJSONSchema(schema map[string]interface{}, patterns JSONSchemaPatterns, value interface{}, path string) error {
+	if schemaType, ok := schema["type"]; ok && !matchJSONSchemaType(schemaType, value) {
+		return &JSONSchemaError{Path: path, Msg: fmt.Sprintf("类型应为%v，实际为%s", schemaType, jsonTypeName(value))}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		matched := false
+		for _, candidate := range enum {
+			if jsonEqual(candidate, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return &JSONSchemaError{Path: path, Msg: fmt.Sprintf("取值应为%v之一", enum)}
+		}
+	}
+	if constValue, ok := schema["const"]; ok && !jsonEqual(constValue, value) {
+		return &JSONSchemaError{Path: path, Msg: fmt.Sprintf("取值应为%v", constValue)}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateJSONSchemaObject(schema, patterns, v, path)
+	case []interface{}:
+		if min, ok := schemaInt(schema, "minItems"); ok && len(v) < min {
+			return &JSONSchemaError{Path: path, Msg: fmt.Sprintf("元素数量不能少于%d", min)}
+		}
+		if max, ok := schemaInt(schema, "maxItems"); ok && len(v) > max {
+			return &JSONSchemaError{Path: path, Msg: fmt.Sprintf("元素数量不能超过%d", max)}
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateJSONSchema(items, patterns, item, joinJSONPath(path, strconv.Itoa(i))); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if min, ok := schemaInt(schema, "minLength"); ok && length < min {
+			return &JSONSchemaError{Path: path, Msg: fmt.Sprintf("长度不能少于%d", min)}
+		}
+		if max, ok := schemaInt(schema, "maxLength"); ok && length > max {
+			return &JSONSchemaError{Path: path, Msg: fmt.Sprintf("长度不能超过%d", max)}
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, compiled := patterns[pattern]
+			if !compiled {
+				var err error
+				if re, err = regexp.Compile(pattern); err != nil {
+					return &JSONSchemaError{Path: path, Msg: "校验规则pattern无效: " + err.Error()}
+				}
+			}
+			if re == nil {
+				return &JSONSchemaError{Path: path, Msg: "校验规则pattern无效"}
+			}
+			if !re.MatchString(v) {
+				return &JSONSchemaError{Path: path, Msg: "格式不符合" + pattern}
+			}
+		}
+	case json.Number:
+		number, _ := v.Float64()
+		if min, ok := schemaFloat(schema, "minimum"); ok && number < min {
+			return &JSONSchemaError{Path: path, Msg: fmt.Sprintf("不能小于%v", min)}
+		}
+		if max, ok := schemaFloat(schema, "maximum"); ok && number > max {
+			return &JSONSchemaError{Path: path, Msg: fmt.Sprintf("不能大于%v", max)}
+		}
+	}
+
+	return nil
+}
+
+// validateJSONSchemaObject 校验对象
+func validateJSONSchemaObject(schema map[string]interface{}, patterns JSONSchemaPatterns, object map[string]interface{}, path string) error {
+	if max, ok := schemaInt(schema, "maxProperties"); ok && len(object) > max {
+		return &JSONSchemaError{Path: path, Msg: fmt.Sprintf("字段数量不能超过%d", max)}
+	}
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			key, _ := name.(string)
+			if _, exists := object[key]; !exists {
+				return &JSONSchemaError{Path: joinJSONPath(path, key), Msg: "缺少必填字段"}
+			}
+		}
+	}
+
+	// 按字段名顺序校验，保证同一份数据每次报告相同的错误
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	for _, key := range keys {
+		child := object[key]
+		childPath := joinJSONPath(path, key)
+		if propertySchema, ok := properties[key].(map[string]interface{}); ok {
+			if err := validateJSONSchema(propertySchema, patterns, child, childPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return &JSONSchemaError{Path: childPath, Msg: "不允许的字段"}
+			}
+		case map[string]interface{}:
+			if err := validateJSONSchema(additional, patterns, child, childPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchJSONSchemaType 检查类型，schemaType可以是字符串或字符串数组
+func matchJSONSchemaType(schemaType interface{}, value interface{}) bool {
+	switch t := schemaType.(type) {
+	case string:
+		return matchJSONType(t, value)
+	case []interface{}:
+		for _, item := range t {
+			if name, ok := item.(string); ok && matchJSONType(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// matchJSONType 检查单个类型
+func matchJSONType(name string, value interface{}) bool {
+	actual := jsonTypeName(value)
+	switch name {
+	case "number":
+		return actual == "integer" || actual == "number"
+	default:
+		return actual == name
+	}
+}
+
+// jsonTypeName 获取JSON值的类型名称
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		if f, err := v.Float64(); err == nil && f == float64(int64(f)) {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "unknown"
+}
+
+// jsonEqual 比较两个JSON值，数字按数值比较
+func jsonEqual(a, b interface{}) bool {
+	an, aIsNumber := toJSONFloat(a)
+	bn, bIsNumber := toJSONFloat(b)
+	if aIsNumber || bIsNumber {
+		return aIsNumber && bIsNumber && an == bn
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// toJSONFloat 将JSON数字转换为float64
+func toJSONFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// schemaInt 读取整数类型的关键字
+func schemaInt(schema map[string]interface{}, key string) (int, bool) {
+	value, ok := schemaFloat(schema, key)
+	return int(value), ok
+}
+
+// schemaFloat 读取数字类型的关键字
+func schemaFloat(schema map[string]interface{}, key string) (float64, bool) {
+	return toJSONFloat(schema[key])
+}
+
+// joinJSONPath 拼接数据路径
+func joinJSONPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
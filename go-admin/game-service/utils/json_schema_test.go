@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// decodeTestJSON 按ValidateJSONSchema的要求解析JSON（数字使用json.Number）
+func decodeTestJSON(t *testing.T, raw string) interface{} {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatalf("解析JSON失败: %s, %v", raw, err)
+	}
+	return value
+}
+
+// TestValidateJSONSchema 逐个关键字校验通过和不通过的情况
+func TestValidateJSONSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		data   string
+		path   string // 期望的出错位置
+		errMsg string // 期望的错误信息，为空表示校验通过
+	}{
+		{"type字符串", `{"type":"string"}`, `"a"`, "", ""},
+		{"type不匹配", `{"type":"string"}`, `1`, "", "类型应为string"},
+		{"type整数", `{"type":"integer"}`, `3`, "", ""},
+		{"type整数拒绝小数", `{"type":"integer"}`, `3.5`, "", "实际为number"},
+		{"type数字接受整数", `{"type":"number"}`, `3`, "", ""},
+		{"type布尔", `{"type":"boolean"}`, `true`, "", ""},
+		{"type null", `{"type":"null"}`, `null`, "", ""},
+		{"type对象", `{"type":"object"}`, `[]`, "", "实际为array"},
+		{"type多个类型", `{"type":["string","null"]}`, `null`, "", ""},
+		{"type多个类型不匹配", `{"type":["string","null"]}`, `1`, "", "类型应为"},
+		{"enum匹配", `{"enum":["a","b",1]}`, `"b"`, "", ""},
+		{"enum数字按数值比较", `{"enum":[1,2]}`, `2.0`, "", ""},
+		{"enum不匹配", `{"enum":["a","b"]}`, `"c"`, "", "取值应为"},
+		{"const匹配", `{"const":{"a":1}}`, `{"a":1}`, "", ""},
+		{"const不匹配", `{"const":"v1"}`, `"v2"`, "", "取值应为v1"},
+		{"properties校验子字段", `{"properties":{"level":{"type":"integer"}}}`, `{"level":"x"}`, "level", "类型应为integer"},
+		{"required缺少字段", `{"required":["name","level"]}`, `{"name":"a"}`, "level", "缺少必填字段"},
+		{"required字段齐全", `{"required":["name"]}`, `{"name":"a"}`, "", ""},
+		{"additionalProperties禁止", `{"properties":{"a":{}},"additionalProperties":false}`, `{"a":1,"b":2}`, "b", "不允许的字段"},
+		{"additionalProperties允许", `{"properties":{"a":{}},"additionalProperties":true}`, `{"a":1,"b":2}`, "", ""},
+		{"additionalProperties子模式", `{"additionalProperties":{"type":"integer"}}`, `{"a":1,"b":"x"}`, "b", "类型应为integer"},
+		{"maxProperties超出", `{"maxProperties":1}`, `{"a":1,"b":2}`, "", "字段数量不能超过1"},
+		{"maxProperties未超出", `{"maxProperties":2}`, `{"a":1,"b":2}`, "", ""},
+		{"items校验元素", `{"items":{"type":"string"}}`, `["a",1]`, "1", "类型应为string"},
+		{"minItems不足", `{"minItems":2}`, `[1]`, "", "元素数量不能少于2"},
+		{"maxItems超出", `{"maxItems":1}`, `[1,2]`, "", "元素数量不能超过1"},
+		{"minItems和maxItems之间", `{"minItems":1,"maxItems":2}`, `[1,2]`, "", ""},
+		{"minLength按字符计算", `{"minLength":2}`, `"中文"`, "", ""},
+		{"minLength不足", `{"minLength":3}`, `"ab"`, "", "长度不能少于3"},
+		{"maxLength超出", `{"maxLength":2}`, `"abc"`, "", "长度不能超过2"},
+		{"pattern匹配", `{"pattern":"^[a-z]+$"}`, `"abc"`, "", ""},
+		{"pattern不匹配", `{"pattern":"^[a-z]+$"}`, `"ABC"`, "", "格式不符合"},
+		{"pattern无效", `{"pattern":"("}`, `"a"`, "", "pattern无效"},
+		{"minimum不足", `{"minimum":1}`, `0`, "", "不能小于1"},
+		{"minimum边界", `{"minimum":1}`, `1`, "", ""},
+		{"maximum超出", `{"maximum":10}`, `10.5`, "", "不能大于10"},
+		{"maximum边界", `{"maximum":10}`, `10`, "", ""},
+		{"嵌套路径", `{"properties":{"bag":{"properties":{"items":{"items":{"required":["id"]}}}}}}`, `{"bag":{"items":[{"id":1},{}]}}`, "bag.items.1.id", "缺少必填字段"},
+		{"未知关键字忽略", `{"format":"email"}`, `"x"`, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, ok := decodeTestJSON(t, tt.schema).(map[string]interface{})
+			if !ok {
+				t.Fatalf("schema必须是对象: %s", tt.schema)
+			}
+
+			err := ValidateJSONSchema(schema, decodeTestJSON(t, tt.data))
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Fatalf("应校验通过，实际为: %v", err)
+				}
+				return
+			}
+
+			var schemaErr *JSONSchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("应返回JSONSchemaError，实际为: %v", err)
+			}
+			if schemaErr.Path != tt.path || !strings.Contains(schemaErr.Msg, tt.errMsg) {
+				t.Errorf("应返回%s: %s，实际为%s: %s", tt.path, tt.errMsg, schemaErr.Path, schemaErr.Msg)
+			}
+		})
+	}
+}
+
+// TestJSONDepth JSON嵌套层数
+func TestJSONDepth(t *testing.T) {
+	tests := []struct {
+		data  string
+		depth int
+	}{
+		{`1`, 0},
+		{`{}`, 1},
+		{`{"a":[1,2]}`, 2},
+		{`{"a":{"b":{"c":[{}]}}}`, 5},
+		{`[[],[[[]]]]`, 4},
+	}
+
+	for _, tt := range tests {
+		if depth := JSONDepth(decodeTestJSON(t, tt.data)); depth != tt.depth {
+			t.Errorf("%s 层数应为%d，实际为%d", tt.data, tt.depth, depth)
+		}
+	}
+}
+
+// TestCompileJSONSchemaPatterns 预编译各层子模式中的pattern，无效的pattern报告位置
+func TestCompileJSONSchemaPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		patterns []string // 期望编译的pattern
+		path     string   // 期望的出错位置
+		errMsg   string   // 期望的错误信息，为空表示全部有效
+	}{
+		{"没有pattern", `{"type":"object"}`, nil, "", ""},
+		{"各层子模式", `{"pattern":"^a","properties":{"name":{"pattern":"^b"}},"items":{"pattern":"^c"},"additionalProperties":{"pattern":"^d"}}`, []string{"^a", "^b", "^c", "^d"}, "", ""},
+		{"同一pattern只编译一次", `{"properties":{"a":{"pattern":"^x$"},"b":{"pattern":"^x$"}}}`, []string{"^x$"}, "", ""},
+		{"嵌套的无效pattern", `{"properties":{"bag":{"items":{"pattern":"("}}}}`, []string{"("}, "bag.items", "pattern无效"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := decodeTestJSON(t, tt.schema).(map[string]interface{})
+			patterns, err := CompileJSONSchemaPatterns(schema)
+			if len(patterns) != len(tt.patterns) {
+				t.Errorf("应编译%d个pattern，实际为%d个", len(tt.patterns), len(patterns))
+			}
+			for _, pattern := range tt.patterns {
+				if _, ok := patterns[pattern]; !ok {
+					t.Errorf("缺少pattern: %s", pattern)
+				}
+			}
+
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Fatalf("应全部有效，实际为: %v", err)
+				}
+				return
+			}
+			var schemaErr *JSONSchemaError
+			if !errors.As(err, &schemaErr) || schemaErr.Path != tt.path || !strings.Contains(schemaErr.Msg, tt.errMsg) {
+				t.Errorf("应返回%s: %s，实际为: %v", tt.path, tt.errMsg, err)
+			}
+		})
+	}
+}
+
+// TestValidateJSONSchemaWithPatterns 使用预编译的pattern校验，无效的pattern报告为规则错误
+func TestValidateJSONSchemaWithPatterns(t *testing.T) {
+	schema := decodeTestJSON(t, `{"properties":{"code":{"pattern":"^[a-z]+$"},"bad":{"pattern":"("}}}`).(map[string]interface{})
+	patterns, _ := CompileJSONSchemaPatterns(schema)
+
+	tests := []struct {
+		data   string
+		errMsg string
+	}{
+		{`{"code":"abc"}`, ""},
+		{`{"code":"ABC"}`, "格式不符合"},
+		{`{"bad":"x"}`, "pattern无效"},
+	}
+	for _, tt := range tests {
+		err := ValidateJSONSchemaWithPatterns(schema, patterns, decodeTestJSON(t, tt.data))
+		if tt.errMsg == "" {
+			if err != nil {
+				t.Errorf("%s 应校验通过，实际为: %v", tt.data, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("%s 应返回包含%q的错误，实际为: %v", tt.data, tt.errMsg, err)
+		}
+	}
+}
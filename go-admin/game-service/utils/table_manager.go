@@ -32,13 +32,47 @@ func GetUserTableName(appId string) string {
 	return fmt.Sprintf("user_%s", CleanAppId(appId))
 }
 
-// GetUserDataTableName 获取用户数据表名（兼容旧版本）
-// Deprecated: 请使用 GetUserTableName
+// GetUserDataTableName 获取玩家命名存档表名
 func GetUserDataTableName(appId string) string {
-	return GetUserTableName(appId)
+	return fmt.Sprintf("user_data_%s", CleanAppId(appId))
+}
+
+// GetUserDataHistoryTableName 获取玩家存档历史表名
+func GetUserDataHistoryTableName(appId string) string {
+	return fmt.Sprintf("user_data_history_%s", CleanAppId(appId))
 }
 
 // GetCounterTableName 获取计数器表名
 func GetCounterTableName(appId string) string {
 	return fmt.Sprintf("counter_%s", CleanAppId(appId))
 }
+
+// GetCounterHistoryTableName 获取计数器历史表名
+func GetCounterHistoryTableName(appId string) string {
+	return fmt.Sprintf("counter_history_%s", CleanAppId(appId))
+}
+
+// GetCounterThresholdLogTableName 获取计数器阈值触发记录表名
+func GetCounterThresholdLogTableName(appId string) string {
+	return fmt.Sprintf("counter_threshold_log_%s", CleanAppId(appId))
+}
+
+// GetLeaderboardHistoryTableName 获取排行榜赛季历史表名
+func GetLeaderboardHistoryTableName(appId string) string {
+	return fmt.Sprintf("leaderboard_history_%s", CleanAppId(appId))
+}
+
+// GetLeaderboardSuspiciousTableName 获取排行榜可疑分数记录表名
+func GetLeaderboardSuspiciousTableName(appId string) string {
+	return fmt.Sprintf("leaderboard_suspicious_%s", CleanAppId(appId))
+}
+
+// GetLeaderboardLeagueTableName 获取排行榜联赛分组表名
+func GetLeaderboardLeagueTableName(appId string) string {
+	return fmt.Sprintf("leaderboard_league_%s", CleanAppId(appId))
+}
+
+// GetUserIdentityTableName 获取玩家登录身份表名
+func GetUserIdentityTableName(appId string) string {
+	return fmt.Sprintf("user_identity_%s", CleanAppId(appId))
+}
@@ -14,6 +14,8 @@ export class User {
     public login(code: string): Promise<ResponseCommon & {
         data: {
             token: string,
+            refreshToken: string,
+            expiresIn: number,
             playerId: string,
             isNew: boolean,
             openId: string,
@@ -24,6 +26,8 @@ export class User {
         let url = '/user/login';
         if(Env.platform === EPlatform.WeChat) {
             url = '/user/login/wx';
+        } else if(Env.platform === EPlatform.ByteDance) {
+            url = '/user/login/douyin';
         }
 
         return Http.inst.post(url, {
@@ -32,6 +36,91 @@ export class User {
         }, false) as any;
     }    
 
+    /**
+     * 游客登录，使用客户端生成的设备ID，之后可以通过bind绑定平台账号并保留进度
+     * @param deviceId 设备ID（8-64位字母、数字、下划线或中划线），不传时自动生成并保存在本地
+     * @returns 
+     */
+    public loginGuest(deviceId?: string): Promise<ResponseCommon & {
+        data: {
+            token: string,
+            refreshToken: string,
+            expiresIn: number,
+            playerId: string,
+            isNew: boolean,
+            openId: string,
+            data: any,
+        }
+    }> {
+        return Http.inst.post('/user/login/guest', {
+            ...Env.getCommonParams(),
+            deviceId: deviceId || this.getDeviceId(),
+        }, false) as any;
+    }
+
+    /**
+     * 为当前玩家绑定平台账号（如游客绑定微信），绑定后使用该平台登录会进入同一个玩家
+     * @param provider 渠道：wx/qq/douyin/yalla/common
+     * @param code 渠道授权码
+     * @returns 
+     */
+    public bind(provider: string, code: string): Promise<ResponseCommon> {
+        return Http.inst.post('/user/identity/link', {
+            ...Env.getCommonParams(),
+            provider,
+            code,
+        }) as any;
+    }
+
+    /**
+     * 使用登录时返回的refreshToken换取新的token（token过期时接口返回code 401，
+     * 被其他设备登录或管理员踢下线时返回code 4010，需要重新登录）
+     * @param refreshToken 刷新token，每次刷新后都会更换，需要保存新的refreshToken
+     * @returns 
+     */
+    public refreshToken(refreshToken: string): Promise<ResponseCommon & {
+        data: {
+            token: string,
+            refreshToken: string,
+            expiresIn: number,
+        }
+    }> {
+        return Http.inst.post('/user/refreshToken', {
+            ...Env.getCommonParams(),
+            refreshToken,
+        }) as any;
+    }
+
+    /**
+     * 登出，当前token和refreshToken立即失效
+     * @returns 
+     */
+    public logout(): Promise<ResponseCommon> {
+        return Http.inst.post('/user/logout', {
+            ...Env.getCommonParams(),
+        }) as any;
+    }
+
+    /**
+     * 获取本地保存的设备ID，不存在时生成一个新的
+     */
+    private getDeviceId(): string {
+        const key = 'zy_sdk_device_id';
+        let deviceId = '';
+        try {
+            deviceId = localStorage.getItem(key) || '';
+        } catch (e) {
+        }
+        if (!deviceId) {
+            deviceId = Date.now().toString(36) + Math.random().toString(36).substring(2, 12);
+            try {
+                localStorage.setItem(key, deviceId);
+            } catch (e) {
+            }
+        }
+        return deviceId;
+    }
+
     public getData(): Promise<ResponseCommon & {
         data: any
     }> {